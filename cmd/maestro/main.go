@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -20,6 +21,7 @@ import (
 	httpworkflow "maestro/internal/http"
 	"maestro/internal/progress"
 	"maestro/internal/ratelimit"
+	"maestro/internal/template"
 )
 
 const (
@@ -28,6 +30,12 @@ const (
 	ExitError           = 2
 )
 
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
 func main() {
 	configPath := flag.String("config", "", "path to YAML config file (required)")
 	actors := flag.Int("actors", 5, "number of initial actors to spawn")
@@ -37,6 +45,13 @@ func main() {
 	verbose := flag.Bool("verbose", false, "enable debug output (request/response logging)")
 	maxIterations := flag.Int("max-iterations", 0, "max iterations per actor (0 = unlimited)")
 	warmup := flag.Int("warmup", 0, "warmup iterations before collecting metrics (per-actor)")
+	timeout := flag.Duration("timeout", 30*time.Second, "default request timeout when the config sets none")
+	baselinePath := flag.String("baseline", "", "JSON result of a previous run to check for regressions against")
+	seed := flag.Int64("seed", 0, "seed for random and faker template functions, for reproducible payloads")
+	baseURL := flag.String("base-url", "", "base URL for relative step URLs, overrides defaults.baseURL")
+	var varFlags, varFiles stringList
+	flag.Var(&varFlags, "var", "set a workflow variable as key=value (repeatable, overrides --var-file)")
+	flag.Var(&varFiles, "var-file", "YAML file of workflow variables (repeatable, overrides config)")
 	flag.Parse()
 
 	if *configPath == "" {
@@ -56,6 +71,49 @@ func main() {
 		os.Exit(ExitError)
 	}
 
+	// Load the baseline up front so a bad path fails before the test runs
+	var baseline *collector.Metrics
+	if *baselinePath != "" {
+		baseline, err = collector.LoadBaseline(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(ExitError)
+		}
+	}
+
+	// Resolve workflow variables: config < --var-file < --var
+	layers := []config.VariableLayer{{Source: config.SourceConfig, Values: cfg.Workflow.Variables}}
+	for _, path := range varFiles {
+		fileVars, err := config.LoadVarFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(ExitError)
+		}
+		layers = append(layers, config.VariableLayer{Source: config.SourceVarFile, Values: fileVars})
+	}
+	cliVars := make(map[string]any, len(varFlags))
+	for _, v := range varFlags {
+		key, value, err := config.ParseVar(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(ExitError)
+		}
+		cliVars[key] = value
+	}
+	layers = append(layers, config.VariableLayer{Source: config.SourceCLI, Values: cliVars})
+	resolvedVars := config.ResolveVariables(layers...)
+	cfg.Workflow.Variables = config.VariableMap(resolvedVars)
+
+	// Seed random template functions: --seed overrides execution.seed
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			cfg.Execution.Seed = seed
+		}
+	})
+	if cfg.Execution.Seed != nil {
+		template.SetSeed(*cfg.Execution.Seed)
+	}
+
 	// Load data sources (relative paths resolved against config file directory)
 	configDir := filepath.Dir(*configPath)
 	var dataSources data.Sources
@@ -77,13 +135,29 @@ func main() {
 	var debugLogger *httpworkflow.DebugLogger
 	if *verbose {
 		debugLogger = httpworkflow.NewDebugLogger(os.Stderr)
+		debugLogger.LogVariables(resolvedVars)
+	}
+
+	if *baseURL != "" {
+		cfg.Workflow.Defaults.BaseURL = *baseURL
+	}
+
+	// Timeouts are applied per request (step > defaults > --timeout), so the
+	// shared client itself has none.
+	if cfg.Workflow.Defaults.Timeout == 0 {
+		cfg.Workflow.Defaults.Timeout = *timeout
+	}
+
+	clients, err := httpworkflow.NewClientFactory(cfg.HTTP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(ExitError)
 	}
 
 	workflow := &httpworkflow.Workflow{
-		Config: cfg.Workflow,
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		Config:      cfg.Workflow,
+		Client:      &http.Client{Transport: clients.Transport()},
+		Clients:     clients,
 		Debug:       debugLogger,
 		DataSources: dataSources,
 	}
@@ -125,14 +199,26 @@ func main() {
 	prog.Stop()
 
 	metrics := collector.ComputeMetrics(coll.Events(), coll.Duration())
+	metrics.DroppedEvents = coll.DroppedEvents()
 
 	var thresholdResults *collector.ThresholdResults
 	if cfg.Thresholds != nil {
 		thresholdResults = cfg.Thresholds.Check(metrics)
 	}
 
-	if dropped := coll.DroppedEvents(); dropped > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: %d events dropped (buffer full)\n", dropped)
+	if baseline != nil {
+		rules := collector.DefaultRegressionRules
+		if cfg.Thresholds != nil && len(cfg.Thresholds.Regressions) > 0 {
+			rules = cfg.Thresholds.Regressions
+		}
+		if thresholdResults == nil {
+			thresholdResults = &collector.ThresholdResults{Passed: true}
+		}
+		thresholdResults.AddComparison(collector.CompareBaseline(*baselinePath, baseline, metrics, rules))
+	}
+
+	if metrics.DroppedEvents > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d events dropped (buffer full)\n", metrics.DroppedEvents)
 	}
 
 	if *output == "json" {
@@ -147,7 +233,12 @@ func main() {
 
 	if thresholdResults != nil && !thresholdResults.Passed {
 		if *output == "text" {
-			fmt.Fprintln(os.Stderr, "\nThreshold check failed!")
+			if thresholdResults.Baseline != nil && !thresholdResults.Baseline.Passed {
+				fmt.Fprintln(os.Stderr, "\nBaseline regression check failed!")
+			}
+			if len(thresholdResults.Violations()) > 0 {
+				fmt.Fprintln(os.Stderr, "\nThreshold check failed!")
+			}
 		}
 		os.Exit(ExitThresholdFailed)
 	}
@@ -0,0 +1,321 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RegressionRule limits how much a metric may change relative to a
+// baseline run. A positive Limit is the maximum increase and a negative
+// Limit the maximum decrease, in percent of the baseline value. Rates
+// such as http_req_failed are compared in percentage points instead.
+type RegressionRule struct {
+	Metric string // metric and optional stat, e.g. http_req_duration.p95
+	Limit  float64
+	Source string // limit as written, e.g. "+10%"
+
+	expr Expression
+}
+
+// DefaultRegressionRules apply when --baseline is given and the config
+// defines no regressions: p95 latency may grow by 10% and throughput may
+// drop by 5%.
+var DefaultRegressionRules = []RegressionRule{
+	mustRegressionRule("http_req_duration.p95", "+10%"),
+	mustRegressionRule("requests_per_sec", "-5%"),
+}
+
+// stepMetricNames are the metrics that are also compared per step.
+var stepMetricNames = map[string]bool{
+	"http_req_duration": true,
+	"http_req_failed":   true,
+	"http_reqs":         true,
+	"requests_per_sec":  true,
+	"checks":            true,
+	"schema_violations": true,
+}
+
+// ParseRegressionRule parses a rule such as `http_req_duration.p95: +10%`.
+func ParseRegressionRule(metric, limit string) (RegressionRule, error) {
+	expr, err := ParseExpression(metric + " > 0")
+	if err != nil {
+		return RegressionRule{}, fmt.Errorf("regression %q: %w", metric, err)
+	}
+	if len(expr.Scope) > 0 {
+		return RegressionRule{}, fmt.Errorf("regression %q: scopes are not supported (steps are compared automatically)", metric)
+	}
+	if expr.kind == kindDuration && expr.Stat != "" {
+		if _, ok := durationStats[expr.Stat]; !ok {
+			return RegressionRule{}, fmt.Errorf("regression %q: baselines only record avg, min, max, med, p50, p90, p95 and p99", metric)
+		}
+	}
+
+	s := strings.TrimSpace(limit)
+	if !strings.HasSuffix(s, "%") {
+		return RegressionRule{}, fmt.Errorf("regression %q: limit %q must be a percentage such as +10%% or -5%%", metric, limit)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return RegressionRule{}, fmt.Errorf("regression %q: invalid limit %q", metric, limit)
+	}
+	return RegressionRule{Metric: metric, Limit: value, Source: s, expr: expr}, nil
+}
+
+func mustRegressionRule(metric, limit string) RegressionRule {
+	rule, err := ParseRegressionRule(metric, limit)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+func (t *Thresholds) decodeRegressions(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: regressions must be a mapping of metric to limit", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		rule, err := ParseRegressionRule(node.Content[i].Value, node.Content[i+1].Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Content[i].Line, err)
+		}
+		t.Regressions = append(t.Regressions, rule)
+	}
+	return nil
+}
+
+// Comparison is the outcome of comparing a run against a baseline.
+type Comparison struct {
+	Baseline string          `json:"baseline"` // path of the baseline file
+	Passed   bool            `json:"passed"`
+	Rows     []ComparisonRow `json:"rows"`
+}
+
+// ComparisonRow compares one metric of the whole run (empty Step) or of
+// one step.
+type ComparisonRow struct {
+	Step     string  `json:"step,omitempty"`
+	Metric   string  `json:"metric"`
+	Baseline string  `json:"baseline"`
+	Current  string  `json:"current"`
+	Delta    string  `json:"delta"` // e.g. "+8.3%", or "+1.20pp" for rates
+	Change   float64 `json:"change"`
+	Limit    string  `json:"limit"`
+	Passed   bool    `json:"passed"`
+}
+
+// LoadBaseline reads metrics from a file written by FormatJSON.
+func LoadBaseline(path string) (*Metrics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline: %w", err)
+	}
+	var doc jsonMetrics
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	m, err := doc.metrics()
+	if err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// metrics converts a JSON result back into Metrics.
+func (j jsonMetrics) metrics() (*Metrics, error) {
+	m := &Metrics{
+		TotalRequests:  j.TotalRequests,
+		SuccessCount:   j.SuccessCount,
+		FailureCount:   j.FailureCount,
+		SuccessRate:    j.SuccessRate,
+		RequestsPerSec: j.RequestsPerSec,
+		Iterations:     j.Iterations,
+		BytesSent:      j.BytesSent,
+		BytesReceived:  j.BytesReceived,
+		DroppedEvents:  j.DroppedEvents,
+		Errors:         j.Errors,
+		StatusCodes:    j.StatusCodes,
+		Steps:          make(map[string]*StepMetrics, len(j.Steps)),
+	}
+
+	var err error
+	if m.TestDuration, err = time.ParseDuration(j.Duration); err != nil {
+		return nil, fmt.Errorf("duration: %w", err)
+	}
+	if m.Duration, err = j.Durations.metrics(); err != nil {
+		return nil, fmt.Errorf("durations: %w", err)
+	}
+	if m.IterationDuration, err = j.IterationDur.metrics(); err != nil {
+		return nil, fmt.Errorf("iterationDurations: %w", err)
+	}
+	if j.Checks != nil {
+		m.Checks = CheckMetrics{Passes: j.Checks.Passes, Fails: j.Checks.Fails}
+	}
+	if j.Schema != nil {
+		m.Schema = *j.Schema
+	}
+
+	for name, js := range j.Steps {
+		sm := &StepMetrics{
+			Count:       js.Count,
+			Success:     js.Success,
+			Failed:      js.Failed,
+			Errors:      js.Errors,
+			StatusCodes: js.StatusCodes,
+		}
+		if sm.Duration, err = js.Durations.metrics(); err != nil {
+			return nil, fmt.Errorf("step %q durations: %w", name, err)
+		}
+		if len(js.Checks) > 0 {
+			sm.Checks = make(map[string]*CheckMetrics, len(js.Checks))
+			for check, jc := range js.Checks {
+				sm.Checks[check] = &CheckMetrics{Passes: jc.Passes, Fails: jc.Fails}
+			}
+		}
+		if js.Schema != nil {
+			sm.Schema = *js.Schema
+		}
+		m.Steps[name] = sm
+	}
+	return m, nil
+}
+
+// metrics reads the exact values, or parses the display strings of
+// baselines written before they were recorded.
+func (j jsonDurationMetrics) metrics() (DurationMetrics, error) {
+	if n := j.Nanoseconds; n != nil {
+		return DurationMetrics{
+			Min: time.Duration(n.Min),
+			Max: time.Duration(n.Max),
+			Avg: time.Duration(n.Avg),
+			P50: time.Duration(n.P50),
+			P90: time.Duration(n.P90),
+			P95: time.Duration(n.P95),
+			P99: time.Duration(n.P99),
+		}, nil
+	}
+
+	var d DurationMetrics
+	fields := []struct {
+		s   string
+		dst *time.Duration
+	}{
+		{j.Min, &d.Min}, {j.Max, &d.Max}, {j.Avg, &d.Avg},
+		{j.P50, &d.P50}, {j.P90, &d.P90}, {j.P95, &d.P95}, {j.P99, &d.P99},
+	}
+	for _, f := range fields {
+		if f.s == "" {
+			continue
+		}
+		v, err := time.ParseDuration(f.s)
+		if err != nil {
+			return DurationMetrics{}, err
+		}
+		*f.dst = v
+	}
+	return d, nil
+}
+
+// CompareBaseline checks current against base using rules, for the whole
+// run and for every step present in both runs.
+func CompareBaseline(path string, base, current *Metrics, rules []RegressionRule) *Comparison {
+	c := &Comparison{Baseline: path, Passed: true}
+	for _, rule := range rules {
+		c.compare("", rule, base, current)
+	}
+	for _, step := range sortedStepNames(current.Steps) {
+		bs, ok := base.Steps[step]
+		if !ok {
+			continue
+		}
+		for _, rule := range rules {
+			if stepMetricNames[rule.expr.Metric] {
+				c.compare(step, rule, stepAsMetrics(bs, base.TestDuration), stepAsMetrics(current.Steps[step], current.TestDuration))
+			}
+		}
+	}
+	return c
+}
+
+func (c *Comparison) compare(step string, rule RegressionRule, base, current *Metrics) {
+	baseValue, kind, _ := rule.expr.value(base) // validated by ParseRegressionRule
+	curValue, _, _ := rule.expr.value(current)
+
+	row := ComparisonRow{
+		Step:     step,
+		Metric:   rule.Metric,
+		Baseline: formatValue(baseValue, kind),
+		Current:  formatValue(curValue, kind),
+		Limit:    rule.Source,
+	}
+
+	if kind == kindRate {
+		// Relative changes of small rates are noise; compare points instead
+		row.Change = curValue - baseValue
+		row.Delta = fmt.Sprintf("%+.2fpp", row.Change)
+	} else {
+		row.Change = relativeChange(baseValue, curValue)
+		row.Delta = formatChange(row.Change)
+	}
+
+	if rule.Limit >= 0 {
+		row.Passed = row.Change <= rule.Limit
+	} else {
+		row.Passed = row.Change >= rule.Limit
+	}
+	if !row.Passed {
+		c.Passed = false
+	}
+	c.Rows = append(c.Rows, row)
+}
+
+// relativeChange returns the change from base to current in percent.
+// Growth from zero is reported as +Inf.
+func relativeChange(base, current float64) float64 {
+	if base == 0 {
+		if current == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (current - base) / base * 100
+}
+
+func formatChange(change float64) string {
+	if math.IsInf(change, 1) {
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", change)
+}
+
+// stepAsMetrics presents one step's metrics as a whole run so rules can be
+// evaluated against it.
+func stepAsMetrics(sm *StepMetrics, testDuration time.Duration) *Metrics {
+	m := &Metrics{
+		TotalRequests: sm.Count,
+		SuccessCount:  sm.Success,
+		FailureCount:  sm.Failed,
+		TestDuration:  testDuration,
+		Duration:      sm.Duration,
+		Errors:        sm.Errors,
+		Schema:        sm.Schema,
+		StatusCodes:   sm.StatusCodes,
+	}
+	if sm.Count > 0 {
+		m.SuccessRate = float64(sm.Success) / float64(sm.Count) * 100
+	}
+	if testDuration > 0 {
+		m.RequestsPerSec = float64(sm.Count) / testDuration.Seconds()
+	}
+	for _, cm := range sm.Checks {
+		m.Checks.Passes += cm.Passes
+		m.Checks.Fails += cm.Fails
+	}
+	return m
+}
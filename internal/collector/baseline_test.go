@@ -0,0 +1,258 @@
+package collector
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/core"
+
+	"gopkg.in/yaml.v3"
+)
+
+// baselineRun builds metrics for two steps with the given latencies.
+func baselineRun(login, orders time.Duration, requests int, duration time.Duration) *Metrics {
+	var events []core.Event
+	for i := 0; i < requests; i++ {
+		events = append(events,
+			core.Event{Step: "login", Success: true, StatusCode: 200, Duration: login},
+			core.Event{Step: "orders", Success: true, StatusCode: 200, Duration: orders})
+	}
+	return ComputeMetrics(events, duration)
+}
+
+func writeBaseline(t *testing.T, m *Metrics) string {
+	t.Helper()
+	var buf bytes.Buffer
+	FormatJSON(&buf, m, nil)
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBaseline_RoundTrip(t *testing.T) {
+	original := baselineRun(20*time.Millisecond, 150*time.Millisecond, 50, 10*time.Second)
+	loaded, err := LoadBaseline(writeBaseline(t, original))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if loaded.TotalRequests != 100 || loaded.TestDuration != 10*time.Second {
+		t.Errorf("unexpected totals: %d requests over %v", loaded.TotalRequests, loaded.TestDuration)
+	}
+	if loaded.RequestsPerSec != original.RequestsPerSec {
+		t.Errorf("expected %.1f req/s, got %.1f", original.RequestsPerSec, loaded.RequestsPerSec)
+	}
+	if loaded.Duration.P95 != original.Duration.P95 {
+		t.Errorf("expected p95 %v, got %v", original.Duration.P95, loaded.Duration.P95)
+	}
+	orders := loaded.Steps["orders"]
+	if orders == nil || orders.Count != 50 || orders.Duration.Avg != 150*time.Millisecond {
+		t.Errorf("unexpected orders step: %+v", orders)
+	}
+}
+
+func TestCompareBaseline_ExactDurations(t *testing.T) {
+	// 1.049s is displayed as 1.0s; comparing against that would turn a
+	// +4.9% change into +10% and fail the default rule
+	base := baselineRun(20*time.Millisecond, 1049*time.Millisecond, 50, 10*time.Second)
+	loaded, err := LoadBaseline(writeBaseline(t, base))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Steps["orders"].Duration.P95 != 1049*time.Millisecond {
+		t.Fatalf("expected exact p95 1.049s, got %v", loaded.Steps["orders"].Duration.P95)
+	}
+
+	current := baselineRun(20*time.Millisecond, 1100*time.Millisecond, 50, 10*time.Second)
+	c := CompareBaseline("base.json", loaded, current, DefaultRegressionRules)
+	for _, row := range c.Rows {
+		if row.Step == "orders" && row.Metric == "http_req_duration.p95" {
+			if !row.Passed || row.Delta != "+4.9%" {
+				t.Errorf("expected a passing +4.9%% change, got %s (passed=%v)", row.Delta, row.Passed)
+			}
+			return
+		}
+	}
+	t.Fatalf("no orders p95 row in %+v", c.Rows)
+}
+
+func TestLoadBaseline_DisplayStrings(t *testing.T) {
+	// Baselines without exact values fall back to the display strings
+	path := filepath.Join(t.TempDir(), "old.json")
+	os.WriteFile(path, []byte(`{"duration": "10s", "durations": {"p95": "1.0s"}}`), 0o644)
+	m, err := LoadBaseline(path)
+	if err != nil || m.Duration.P95 != time.Second {
+		t.Errorf("expected p95 1s, got %v (%v)", m.Duration.P95, err)
+	}
+}
+
+func TestLoadBaseline_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadBaseline(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte(`{"duration": "10s", "durations": {"p95": "fast"}}`), 0o644)
+	_, err := LoadBaseline(bad)
+	if err == nil || !strings.Contains(err.Error(), "durations") {
+		t.Errorf("expected durations error, got %v", err)
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	base := baselineRun(20*time.Millisecond, 100*time.Millisecond, 50, 10*time.Second)
+
+	tests := []struct {
+		name        string
+		current     *Metrics
+		wantPassed  bool
+		wantFailing []string // step/metric of failing rows
+	}{
+		{
+			name:       "unchanged",
+			current:    baselineRun(20*time.Millisecond, 100*time.Millisecond, 50, 10*time.Second),
+			wantPassed: true,
+		},
+		{
+			name:        "one step slower",
+			current:     baselineRun(20*time.Millisecond, 130*time.Millisecond, 50, 10*time.Second),
+			wantFailing: []string{"/http_req_duration.p95", "orders/http_req_duration.p95"},
+		},
+		{
+			name:        "throughput dropped",
+			current:     baselineRun(20*time.Millisecond, 100*time.Millisecond, 45, 10*time.Second),
+			wantFailing: []string{"/requests_per_sec", "login/requests_per_sec", "orders/requests_per_sec"},
+		},
+		{
+			name:       "faster and more throughput",
+			current:    baselineRun(10*time.Millisecond, 50*time.Millisecond, 80, 10*time.Second),
+			wantPassed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CompareBaseline("base.json", base, tt.current, DefaultRegressionRules)
+			if c.Passed != tt.wantPassed {
+				t.Errorf("expected passed=%v, got %v", tt.wantPassed, c.Passed)
+			}
+			// 2 rules over the whole run and both steps
+			if len(c.Rows) != 6 {
+				t.Fatalf("expected 6 rows, got %d: %+v", len(c.Rows), c.Rows)
+			}
+			var failing []string
+			for _, row := range c.Rows {
+				if !row.Passed {
+					failing = append(failing, row.Step+"/"+row.Metric)
+				}
+			}
+			if strings.Join(failing, ",") != strings.Join(tt.wantFailing, ",") {
+				t.Errorf("expected failing %v, got %v", tt.wantFailing, failing)
+			}
+		})
+	}
+}
+
+func TestCompareBaseline_Deltas(t *testing.T) {
+	base := baselineRun(100*time.Millisecond, 100*time.Millisecond, 50, 10*time.Second)
+	current := baselineRun(108*time.Millisecond, 108*time.Millisecond, 50, 10*time.Second)
+	current.Steps["search"] = &StepMetrics{Count: 1} // not in the baseline
+
+	failedRule, _ := ParseRegressionRule("http_req_failed", "+1%")
+	rules := append([]RegressionRule{failedRule}, DefaultRegressionRules...)
+	c := CompareBaseline("base.json", base, current, rules)
+
+	if !c.Passed {
+		t.Errorf("expected comparison to pass: %+v", c.Rows)
+	}
+	for _, row := range c.Rows {
+		if row.Step == "search" {
+			t.Errorf("steps missing from the baseline should be skipped, got %+v", row)
+		}
+	}
+	if c.Rows[0].Delta != "+0.00pp" {
+		t.Errorf("expected rates compared in points, got %q", c.Rows[0].Delta)
+	}
+	p95 := c.Rows[1]
+	if p95.Baseline != "100ms" || p95.Current != "108ms" || p95.Delta != "+8.0%" || p95.Limit != "+10%" {
+		t.Errorf("unexpected p95 row %+v", p95)
+	}
+}
+
+func TestParseRegressionRule_Errors(t *testing.T) {
+	tests := []struct {
+		metric, limit string
+		want          string
+	}{
+		{"rps", "-5%", "unknown metric"},
+		{"http_req_duration{step:a}.p95", "+10%", "scopes are not supported"},
+		{"http_req_duration.p99.9", "+10%", "baselines only record"},
+		{"http_req_duration.p95", "10", "must be a percentage"},
+		{"http_req_duration.p95", "lots%", "invalid limit"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRegressionRule(tt.metric, tt.limit)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: %s: expected error containing %q, got %v", tt.metric, tt.limit, tt.want, err)
+		}
+	}
+}
+
+func TestThresholds_UnmarshalRegressions(t *testing.T) {
+	content := `
+regressions:
+  http_req_duration.p99: +20%
+  requests_per_sec: -5%
+`
+	var thresholds Thresholds
+	if err := yaml.Unmarshal([]byte(content), &thresholds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(thresholds.Regressions) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(thresholds.Regressions))
+	}
+	if r := thresholds.Regressions[1]; r.Metric != "requests_per_sec" || r.Limit != -5 {
+		t.Errorf("unexpected rule %+v", r)
+	}
+
+	err := yaml.Unmarshal([]byte("regressions:\n  - p95\n"), &Thresholds{})
+	if err == nil || !strings.Contains(err.Error(), "must be a mapping") {
+		t.Errorf("expected mapping error, got %v", err)
+	}
+}
+
+func TestFormat_BaselineComparison(t *testing.T) {
+	base := baselineRun(20*time.Millisecond, 100*time.Millisecond, 50, 10*time.Second)
+	current := baselineRun(20*time.Millisecond, 130*time.Millisecond, 50, 10*time.Second)
+	results := &ThresholdResults{Passed: true}
+	results.AddComparison(CompareBaseline("base.json", base, current, DefaultRegressionRules))
+	if results.Passed {
+		t.Error("expected a failed comparison to fail the results")
+	}
+
+	var text bytes.Buffer
+	FormatText(&text, current, results)
+	out := text.String()
+	for _, want := range []string{
+		"Baseline Comparison (vs base.json):",
+		"✓ (all)",
+		"✗ orders          http_req_duration.p95         100ms      130ms    +30.0%  +10%",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	var js bytes.Buffer
+	FormatJSON(&js, current, results)
+	if !strings.Contains(js.String(), `"baseline": "base.json"`) || !strings.Contains(js.String(), `"delta": "+30.0%"`) {
+		t.Errorf("expected comparison in JSON output:\n%s", js.String())
+	}
+}
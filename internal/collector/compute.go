@@ -12,6 +12,7 @@ func ComputeMetrics(events []core.Event, testDuration time.Duration) *Metrics {
 	m := &Metrics{
 		Steps:        make(map[string]*StepMetrics),
 		TestDuration: testDuration,
+		events:       events,
 	}
 
 	if len(events) == 0 {
@@ -20,6 +21,7 @@ func ComputeMetrics(events []core.Event, testDuration time.Duration) *Metrics {
 
 	allDurations := make([]time.Duration, 0, len(events))
 	stepDurations := make(map[string][]time.Duration)
+	var iterDurations []time.Duration
 
 	for _, e := range events {
 		m.TotalRequests++
@@ -27,9 +29,16 @@ func ComputeMetrics(events []core.Event, testDuration time.Duration) *Metrics {
 			m.SuccessCount++
 		} else {
 			m.FailureCount++
+			countError(&m.Errors, e.ErrorType)
 		}
 
 		allDurations = append(allDurations, e.Duration)
+		m.BytesSent += e.BytesSent
+		m.BytesReceived += e.BytesRecv
+		if e.IterationDuration > 0 {
+			m.Iterations++
+			iterDurations = append(iterDurations, e.IterationDuration)
+		}
 
 		if _, exists := m.Steps[e.Step]; !exists {
 			m.Steps[e.Step] = &StepMetrics{}
@@ -42,8 +51,41 @@ func ComputeMetrics(events []core.Event, testDuration time.Duration) *Metrics {
 			step.Success++
 		} else {
 			step.Failed++
+			countError(&step.Errors, e.ErrorType)
 		}
 		stepDurations[e.Step] = append(stepDurations[e.Step], e.Duration)
+
+		if e.StatusCode != 0 {
+			// Steps report an unexpected status as a status error
+			matched := e.ErrorType != core.ErrorTypeStatus
+			countStatus(&m.StatusCodes, e.StatusCode, matched)
+			countStatus(&step.StatusCodes, e.StatusCode, matched)
+		}
+
+		if e.SchemaViolations > 0 {
+			step.Schema.InvalidResponses++
+			step.Schema.Violations += e.SchemaViolations
+			m.Schema.InvalidResponses++
+			m.Schema.Violations += e.SchemaViolations
+		}
+
+		for _, c := range e.Checks {
+			if step.Checks == nil {
+				step.Checks = make(map[string]*CheckMetrics)
+			}
+			cm, ok := step.Checks[c.Name]
+			if !ok {
+				cm = &CheckMetrics{}
+				step.Checks[c.Name] = cm
+			}
+			if c.Passed {
+				cm.Passes++
+				m.Checks.Passes++
+			} else {
+				cm.Fails++
+				m.Checks.Fails++
+			}
+		}
 	}
 
 	if m.TotalRequests > 0 {
@@ -55,6 +97,7 @@ func ComputeMetrics(events []core.Event, testDuration time.Duration) *Metrics {
 	}
 
 	m.Duration = ComputeDurationMetrics(allDurations)
+	m.IterationDuration = ComputeDurationMetrics(iterDurations)
 
 	for step, durations := range stepDurations {
 		m.Steps[step].Duration = ComputeDurationMetrics(durations)
@@ -62,3 +105,33 @@ func ComputeMetrics(events []core.Event, testDuration time.Duration) *Metrics {
 
 	return m
 }
+
+// countStatus records a response with the given status code, creating
+// the map on first use.
+func countStatus(codes *map[int]*StatusMetrics, code int, matched bool) {
+	if *codes == nil {
+		*codes = make(map[int]*StatusMetrics)
+	}
+	sm, ok := (*codes)[code]
+	if !ok {
+		sm = &StatusMetrics{}
+		(*codes)[code] = sm
+	}
+	if matched {
+		sm.Matched++
+	} else {
+		sm.Unmatched++
+	}
+}
+
+// countError increments the failure count for errType, creating the map on
+// first use. Failures without a category are counted as "other".
+func countError(errors *map[string]int, errType string) {
+	if errType == "" {
+		errType = "other"
+	}
+	if *errors == nil {
+		*errors = make(map[string]int)
+	}
+	(*errors)[errType]++
+}
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -215,7 +216,7 @@ func TestComputeMetrics_DoesNotModifyInput(t *testing.T) {
 	if len(events) != originalLen {
 		t.Error("ComputeMetrics should not modify input slice length")
 	}
-	if events[0] != originalEvent {
+	if !reflect.DeepEqual(events[0], originalEvent) {
 		t.Error("ComputeMetrics should not modify input slice elements")
 	}
 }
@@ -247,3 +248,133 @@ func BenchmarkCollectorReport(b *testing.B) {
 	b.StopTimer()
 	c.Close()
 }
+
+func TestComputeMetrics_ErrorsByType(t *testing.T) {
+	events := []core.Event{
+		{Step: "slow", Success: false, ErrorType: core.ErrorTypeTimeout},
+		{Step: "slow", Success: false, ErrorType: core.ErrorTypeTimeout},
+		{Step: "slow", Success: true},
+		{Step: "api", Success: false, ErrorType: core.ErrorTypeStatus},
+		{Step: "api", Success: false},
+	}
+
+	m := ComputeMetrics(events, time.Second)
+
+	if m.Errors[core.ErrorTypeTimeout] != 2 {
+		t.Errorf("expected 2 timeouts, got %d", m.Errors[core.ErrorTypeTimeout])
+	}
+	if m.Errors[core.ErrorTypeStatus] != 1 {
+		t.Errorf("expected 1 status error, got %d", m.Errors[core.ErrorTypeStatus])
+	}
+	if m.Errors["other"] != 1 {
+		t.Errorf("expected 1 uncategorized error, got %d", m.Errors["other"])
+	}
+	if m.Steps["slow"].Errors[core.ErrorTypeTimeout] != 2 {
+		t.Errorf("expected 2 timeouts for step slow, got %v", m.Steps["slow"].Errors)
+	}
+	if _, ok := m.Steps["slow"].Errors[core.ErrorTypeStatus]; ok {
+		t.Error("expected no status errors for step slow")
+	}
+}
+
+func TestComputeMetrics_NoErrors(t *testing.T) {
+	events := []core.Event{{Step: "ok", Success: true}}
+
+	m := ComputeMetrics(events, time.Second)
+
+	if m.Errors != nil {
+		t.Errorf("expected nil errors map, got %v", m.Errors)
+	}
+}
+
+func TestComputeMetrics_Checks(t *testing.T) {
+	events := []core.Event{
+		{Step: "login", Success: true, Checks: []core.CheckResult{{Name: "status", Passed: true}, {Name: "token", Passed: true}}},
+		{Step: "login", Success: false, Checks: []core.CheckResult{{Name: "status", Passed: true}, {Name: "token", Passed: false}}},
+		{Step: "health", Success: true},
+	}
+
+	m := ComputeMetrics(events, time.Second)
+
+	if m.Checks.Passes != 3 || m.Checks.Fails != 1 {
+		t.Errorf("expected 3 passes and 1 fail, got %+v", m.Checks)
+	}
+	if m.Checks.Rate() != 75 {
+		t.Errorf("expected 75%% pass rate, got %.1f", m.Checks.Rate())
+	}
+
+	token := m.Steps["login"].Checks["token"]
+	if token == nil || token.Passes != 1 || token.Fails != 1 {
+		t.Errorf("expected token check 1/1, got %+v", token)
+	}
+	if len(m.Steps["health"].Checks) != 0 {
+		t.Errorf("expected no checks for health, got %v", m.Steps["health"].Checks)
+	}
+}
+
+func TestComputeMetrics_SchemaViolations(t *testing.T) {
+	events := []core.Event{
+		{Step: "orders", Success: false, ErrorType: core.ErrorTypeSchema, SchemaViolations: 3},
+		{Step: "orders", Success: false, ErrorType: core.ErrorTypeStatus},
+		{Step: "orders", Success: true},
+		{Step: "health", Success: false, ErrorType: core.ErrorTypeCheck, SchemaViolations: 1},
+	}
+
+	m := ComputeMetrics(events, time.Second)
+
+	if m.Schema.InvalidResponses != 2 || m.Schema.Violations != 4 {
+		t.Errorf("expected 4 violations in 2 responses, got %+v", m.Schema)
+	}
+	if m.Steps["orders"].Schema.Violations != 3 {
+		t.Errorf("expected 3 violations for orders, got %+v", m.Steps["orders"].Schema)
+	}
+	if m.Errors[core.ErrorTypeSchema] != 1 {
+		t.Errorf("expected 1 schema error, got %d", m.Errors[core.ErrorTypeSchema])
+	}
+}
+
+func TestComputeMetrics_StatusCodes(t *testing.T) {
+	events := []core.Event{
+		{Step: "login", Success: true, StatusCode: 200},
+		{Step: "login", Success: false, StatusCode: 302, ErrorType: core.ErrorTypeStatus},
+		{Step: "missing", Success: true, StatusCode: 404},
+		{Step: "missing", Success: false, StatusCode: 404, ErrorType: core.ErrorTypeCheck},
+		{Step: "missing", Success: false, ErrorType: core.ErrorTypeNetwork},
+	}
+
+	m := ComputeMetrics(events, time.Second)
+
+	if len(m.StatusCodes) != 3 {
+		t.Fatalf("expected 3 status codes, got %v", m.StatusCodes)
+	}
+	if sc := m.StatusCodes[302]; sc.Matched != 0 || sc.Unmatched != 1 {
+		t.Errorf("expected 302 unmatched once, got %+v", sc)
+	}
+	if sc := m.StatusCodes[404]; sc.Matched != 2 || sc.Unmatched != 0 {
+		t.Errorf("expected 404 matched twice, got %+v", sc)
+	}
+	if sc := m.Steps["login"].StatusCodes[200]; sc == nil || sc.Matched != 1 {
+		t.Errorf("expected 200 matched for login, got %+v", sc)
+	}
+}
+
+func TestComputeMetrics_DataAndIterations(t *testing.T) {
+	events := []core.Event{
+		{Step: "a", Success: true, BytesSent: 10, BytesRecv: 100},
+		{Step: "b", Success: true, BytesSent: 20, BytesRecv: 200, IterationDuration: 300 * time.Millisecond},
+		{Step: "a", Success: true, BytesSent: 10, BytesRecv: 100},
+		{Step: "b", Success: true, BytesSent: 20, BytesRecv: 200, IterationDuration: 100 * time.Millisecond},
+	}
+
+	m := ComputeMetrics(events, time.Second)
+
+	if m.BytesSent != 60 || m.BytesReceived != 600 {
+		t.Errorf("expected 60 sent / 600 received, got %d / %d", m.BytesSent, m.BytesReceived)
+	}
+	if m.Iterations != 2 {
+		t.Errorf("expected 2 iterations, got %d", m.Iterations)
+	}
+	if m.IterationDuration.Avg != 200*time.Millisecond {
+		t.Errorf("expected avg iteration 200ms, got %v", m.IterationDuration.Avg)
+	}
+}
@@ -0,0 +1,359 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"maestro/internal/core"
+)
+
+// Expression is a threshold written as `metric{scope}.stat op value`,
+// for example `http_req_duration{step:checkout}.p99.9 < 2s` or
+// `requests_per_sec >= 500`. The scope and stat are optional; each
+// metric has a default stat.
+type Expression struct {
+	Source    string
+	Metric    string
+	Scope     Scope
+	Stat      string
+	Op        string
+	Threshold string // as written, e.g. "800ms"
+
+	kind   valueKind
+	target float64
+}
+
+// valueKind determines how threshold values are parsed and displayed.
+type valueKind int
+
+const (
+	kindCount    valueKind = iota // plain count
+	kindNumber                    // fractional number, e.g. a per-second rate
+	kindDuration                  // nanoseconds
+	kindRate                      // percentage 0-100
+	kindBytes                     // bytes, or bytes per second
+)
+
+var exprRe = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)(\{[^}]*\})?(?:\.([A-Za-z0-9_.]+))?\s*(<=|>=|==|<|>)\s*(\S+)\s*$`)
+
+// durationStats are the stats precomputed in DurationMetrics.
+var durationStats = map[string]func(DurationMetrics) time.Duration{
+	"avg": func(d DurationMetrics) time.Duration { return d.Avg },
+	"min": func(d DurationMetrics) time.Duration { return d.Min },
+	"max": func(d DurationMetrics) time.Duration { return d.Max },
+	"med": func(d DurationMetrics) time.Duration { return d.P50 },
+	"p50": func(d DurationMetrics) time.Duration { return d.P50 },
+	"p90": func(d DurationMetrics) time.Duration { return d.P90 },
+	"p95": func(d DurationMetrics) time.Duration { return d.P95 },
+	"p99": func(d DurationMetrics) time.Duration { return d.P99 },
+}
+
+// metricNames lists the metrics expressions can refer to, for error messages.
+// There are no user-defined metrics, so this is the complete set.
+var metricNames = []string{
+	"checks", "data_received", "data_sent", "dropped_events",
+	"http_req_duration", "http_req_failed", "http_reqs",
+	"iteration_duration", "iterations", "requests_per_sec", "schema_violations",
+}
+
+// ParseExpression parses and validates a threshold expression.
+func ParseExpression(s string) (Expression, error) {
+	match := exprRe.FindStringSubmatch(s)
+	if match == nil {
+		return Expression{}, fmt.Errorf("invalid threshold expression %q (expected `metric{scope}.stat op value`)", s)
+	}
+
+	e := Expression{
+		Source:    strings.TrimSpace(s),
+		Metric:    match[1],
+		Stat:      match[3],
+		Op:        match[4],
+		Threshold: match[5],
+	}
+	if match[2] != "" {
+		_, scope, err := parseMetricKey(match[1] + match[2])
+		if err != nil {
+			return Expression{}, err
+		}
+		e.Scope = scope
+	}
+
+	// Evaluating against empty metrics validates the metric and stat
+	_, kind, err := e.value(&Metrics{})
+	if err != nil {
+		return Expression{}, fmt.Errorf("threshold %q: %w", e.Source, err)
+	}
+	e.kind = kind
+
+	target, err := parseValue(e.Threshold, e.kind)
+	if err != nil {
+		return Expression{}, fmt.Errorf("threshold %q: %w", e.Source, err)
+	}
+	e.target = target
+	return e, nil
+}
+
+// Name returns the expression without its comparison, e.g. http_reqs{status:5xx}.count.
+func (e Expression) Name() string {
+	name := e.Metric
+	if len(e.Scope) > 0 {
+		name += e.Scope.String()
+	}
+	if e.Stat != "" {
+		name += "." + e.Stat
+	}
+	return name
+}
+
+// value looks up the metric in m, returning it in the unit of its kind.
+func (e Expression) value(m *Metrics) (float64, valueKind, error) {
+	stat := e.Stat
+	switch e.Metric {
+	case "http_req_duration":
+		return durationStat(m.Duration, m.events, false, stat, "p95")
+
+	case "iteration_duration":
+		return durationStat(m.IterationDuration, m.events, true, stat, "p95")
+
+	case "http_reqs":
+		return countOrRate(float64(m.TotalRequests), m, stat, kindCount)
+
+	case "requests_per_sec":
+		if stat != "" {
+			return 0, 0, fmt.Errorf("requests_per_sec has no stats")
+		}
+		return m.RequestsPerSec, kindNumber, nil
+
+	case "http_req_failed":
+		switch stat {
+		case "", "rate":
+			if m.TotalRequests == 0 {
+				return 0, kindRate, nil
+			}
+			return 100 - m.SuccessRate, kindRate, nil
+		case "count":
+			return float64(m.FailureCount), kindCount, nil
+		}
+
+	case "checks":
+		switch stat {
+		case "", "rate":
+			return m.Checks.Rate(), kindRate, nil
+		case "passes":
+			return float64(m.Checks.Passes), kindCount, nil
+		case "fails":
+			return float64(m.Checks.Fails), kindCount, nil
+		}
+
+	case "data_received":
+		return countOrRate(float64(m.BytesReceived), m, stat, kindBytes)
+
+	case "data_sent":
+		return countOrRate(float64(m.BytesSent), m, stat, kindBytes)
+
+	case "iterations":
+		return countOrRate(float64(m.Iterations), m, stat, kindCount)
+
+	case "schema_violations":
+		if stat == "" || stat == "count" {
+			return float64(m.Schema.Violations), kindCount, nil
+		}
+
+	case "dropped_events":
+		if stat == "" || stat == "count" {
+			return float64(m.DroppedEvents), kindCount, nil
+		}
+
+	default:
+		return 0, 0, fmt.Errorf("unknown metric %q (available: %s)", e.Metric, strings.Join(metricNames, ", "))
+	}
+	return 0, 0, fmt.Errorf("unknown stat %q for %s", stat, e.Metric)
+}
+
+// countOrRate returns a total (stat "count") or its per-second rate (stat "rate").
+func countOrRate(total float64, m *Metrics, stat string, kind valueKind) (float64, valueKind, error) {
+	switch stat {
+	case "", "count":
+		return total, kind, nil
+	case "rate":
+		if m.TestDuration <= 0 {
+			return 0, rateKind(kind), nil
+		}
+		return total / m.TestDuration.Seconds(), rateKind(kind), nil
+	}
+	return 0, 0, fmt.Errorf("unknown stat %q (expected count or rate)", stat)
+}
+
+func rateKind(kind valueKind) valueKind {
+	if kind == kindBytes {
+		return kindBytes
+	}
+	return kindNumber
+}
+
+// durationStat resolves avg/min/max/med/pN. Percentiles not precomputed in
+// d, such as p99.9, are computed from the events.
+func durationStat(d DurationMetrics, events []core.Event, iterations bool, stat, def string) (float64, valueKind, error) {
+	if stat == "" {
+		stat = def
+	}
+	if get, ok := durationStats[stat]; ok {
+		return float64(get(d)), kindDuration, nil
+	}
+	if !strings.HasPrefix(stat, "p") {
+		return 0, 0, fmt.Errorf("unknown stat %q (expected avg, min, max, med or a percentile like p99.9)", stat)
+	}
+	p, err := strconv.ParseFloat(stat[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, 0, fmt.Errorf("invalid percentile %q", stat)
+	}
+	durations := durationsOf(events, iterations)
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return float64(ComputePercentile(durations, p/100)), kindDuration, nil
+}
+
+// durationsOf returns request durations, or iteration durations when
+// iterations is true.
+func durationsOf(events []core.Event, iterations bool) []time.Duration {
+	durations := make([]time.Duration, 0, len(events))
+	for _, e := range events {
+		switch {
+		case !iterations:
+			durations = append(durations, e.Duration)
+		case e.IterationDuration > 0:
+			durations = append(durations, e.IterationDuration)
+		}
+	}
+	return durations
+}
+
+// parseValue parses a threshold value in the unit of kind.
+func parseValue(s string, kind valueKind) (float64, error) {
+	switch kind {
+	case kindDuration:
+		if d, err := time.ParseDuration(s); err == nil {
+			return float64(d), nil
+		}
+		// Bare numbers are milliseconds
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f * float64(time.Millisecond), nil
+		}
+		return 0, fmt.Errorf("invalid duration %q", s)
+
+	case kindRate:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage %q", s)
+		}
+		return f, nil
+
+	case kindBytes:
+		return parseBytes(s)
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+var byteUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+}
+
+func parseBytes(s string) (float64, error) {
+	upper := strings.ToUpper(s)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			f, err := strconv.ParseFloat(strings.TrimSuffix(upper, u.suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+			return f * u.size, nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return f, nil
+}
+
+// FormatBytes formats a byte count for display.
+func FormatBytes(n float64) string {
+	for _, u := range byteUnits {
+		if n >= u.size && u.size > 1 {
+			return strconv.FormatFloat(math.Round(n/u.size*10)/10, 'f', -1, 64) + " " + u.suffix
+		}
+	}
+	return strconv.FormatFloat(math.Round(n), 'f', -1, 64) + " B"
+}
+
+func formatValue(v float64, kind valueKind) string {
+	switch kind {
+	case kindDuration:
+		return FormatDuration(time.Duration(v))
+	case kindRate:
+		return fmt.Sprintf("%.2f%%", v)
+	case kindBytes:
+		return FormatBytes(v)
+	case kindCount:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+func compareValues(actual float64, op string, target float64) bool {
+	switch op {
+	case "<":
+		return actual < target
+	case "<=":
+		return actual <= target
+	case ">":
+		return actual > target
+	case ">=":
+		return actual >= target
+	case "==":
+		return actual == target
+	}
+	return false
+}
+
+// checkExpression evaluates one expression, over the events in its scope if any.
+func (r *ThresholdResults) checkExpression(e Expression, m *Metrics) {
+	noData := false
+	if len(e.Scope) > 0 {
+		sm := ComputeMetrics(filterEvents(m.events, e.Scope), m.TestDuration)
+		sm.DroppedEvents = m.DroppedEvents
+		// Latencies and rates over nothing are meaningless; counts are not
+		noData = sm.TotalRequests == 0 && (e.kind == kindDuration || e.kind == kindRate)
+		m = sm
+	}
+
+	result := ThresholdResult{
+		Name:      e.Name(),
+		Operator:  e.Op,
+		Threshold: e.Threshold,
+	}
+	if noData {
+		result.Actual = "no requests"
+	} else {
+		actual, _, _ := e.value(m) // validated by ParseExpression
+		result.Passed = compareValues(actual, e.Op, e.target)
+		result.Actual = formatValue(actual, e.kind)
+	}
+
+	if !result.Passed {
+		r.Passed = false
+	}
+	r.Results = append(r.Results, result)
+}
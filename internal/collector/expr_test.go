@@ -0,0 +1,181 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/core"
+
+	"gopkg.in/yaml.v3"
+)
+
+func exprTestMetrics() *Metrics {
+	var events []core.Event
+	for i := 1; i <= 1000; i++ {
+		e := core.Event{
+			Step:       "api",
+			Success:    true,
+			StatusCode: 200,
+			Duration:   time.Duration(i) * time.Millisecond,
+			BytesSent:  100,
+			BytesRecv:  1024,
+		}
+		if i%100 == 0 {
+			e.Success = false
+			e.StatusCode = 503
+			e.ErrorType = core.ErrorTypeStatus
+		}
+		if i%2 == 0 {
+			e.IterationDuration = 2 * e.Duration
+		}
+		events = append(events, e)
+	}
+	m := ComputeMetrics(events, 10*time.Second)
+	m.DroppedEvents = 3
+	return m
+}
+
+func TestExpression_Evaluate(t *testing.T) {
+	tests := []struct {
+		expr       string
+		wantPassed bool
+		wantActual string
+	}{
+		{"requests_per_sec >= 100", true, "100.0"},
+		{"requests_per_sec > 100", false, "100.0"},
+		{"http_reqs.count == 1000", true, "1000"},
+		{"http_reqs < 500", false, "1000"},
+		{"http_reqs.rate >= 50", true, "100.0"},
+		{"http_reqs{status:5xx}.count == 0", false, "10"},
+		{"http_reqs{status:503}.count <= 10", true, "10"},
+		{"http_reqs{status:2xx}.count == 990", true, "990"},
+		{"http_req_duration.p95 < 1s", true, "950ms"},
+		{"http_req_duration.p99.9 < 999ms", false, "999ms"},
+		{"http_req_duration.max <= 1000", true, "1.0s"},
+		{"http_req_duration.med < 500ms", false, "500ms"},
+		{"http_req_failed.rate < 1%", false, "1.00%"},
+		{"http_req_failed.rate <= 1%", true, "1.00%"},
+		{"http_req_failed.count == 10", true, "10"},
+		{"checks.rate >= 99%", true, "100.00%"},
+		{"data_received > 900KB", true, "1000 KB"},
+		{"data_sent.rate < 10KB", true, "9.8 KB"},
+		{"iterations == 500", true, "500"},
+		{"iteration_duration.avg < 1s", false, "1.0s"},
+		{"iteration_duration.p99.9 <= 2s", true, "2.0s"},
+		{"dropped_events == 0", false, "3"},
+		{"schema_violations.count == 0", true, "0"},
+	}
+
+	m := exprTestMetrics()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			thresholds := &Thresholds{}
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			thresholds.Expressions = []Expression{expr}
+
+			results := thresholds.Check(m)
+
+			r := results.Results[0]
+			if r.Passed != tt.wantPassed {
+				t.Errorf("expected passed=%v, got %v (actual %s)", tt.wantPassed, r.Passed, r.Actual)
+			}
+			if r.Actual != tt.wantActual {
+				t.Errorf("expected actual %q, got %q", tt.wantActual, r.Actual)
+			}
+		})
+	}
+}
+
+func TestExpression_ResultName(t *testing.T) {
+	expr, err := ParseExpression("http_req_duration{step:checkout}.p99.9 <= 800ms")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	results := (&Thresholds{Expressions: []Expression{expr}}).Check(exprTestMetrics())
+
+	r := results.Results[0]
+	if r.Name != "http_req_duration{step:checkout}.p99.9" || r.Operator != "<=" || r.Threshold != "800ms" {
+		t.Errorf("unexpected result %+v", r)
+	}
+	// No checkout requests: latency thresholds fail rather than pass vacuously
+	if r.Passed || r.Actual != "no requests" {
+		t.Errorf("expected failure with no requests, got %+v", r)
+	}
+}
+
+func TestParseExpression_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"requests_per_sec 500", "invalid threshold expression"},
+		{"http_req_duration.p95 != 1s", "invalid threshold expression"},
+		{"latency.p95 < 1s", `unknown metric "latency"`},
+		{"http_req_duration.p101 < 1s", `invalid percentile "p101"`},
+		{"http_req_duration.mean < 1s", `unknown stat "mean"`},
+		{"http_req_failed.p95 < 1%", `unknown stat "p95"`},
+		{"requests_per_sec.rate > 1", "has no stats"},
+		{"http_req_duration.p95 < fast", `invalid duration "fast"`},
+		{"data_received < 10XB", `invalid size "10XB"`},
+		{"http_reqs{status} == 0", "key:value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseExpression(tt.expr)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestThresholds_UnmarshalExpressions(t *testing.T) {
+	content := `
+http_req_duration:
+  p95: 500ms
+expressions:
+  - "requests_per_sec >= 500"
+  - "http_reqs{status:5xx}.count == 0"
+`
+	var thresholds Thresholds
+	if err := yaml.Unmarshal([]byte(content), &thresholds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(thresholds.Expressions) != 2 {
+		t.Fatalf("expected 2 expressions, got %d", len(thresholds.Expressions))
+	}
+	if thresholds.Expressions[1].Name() != "http_reqs{status:5xx}.count" {
+		t.Errorf("unexpected expression %+v", thresholds.Expressions[1])
+	}
+
+	err := yaml.Unmarshal([]byte("expressions:\n  - \"rps > 1\"\n"), &Thresholds{})
+	if err == nil || !strings.Contains(err.Error(), `unknown metric "rps"`) {
+		t.Errorf("expected unknown metric error, got %v", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5 MB"},
+		{2.25 * 1024 * 1024 * 1024, "2.3 GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%v): expected %q, got %q", tt.n, tt.want, got)
+		}
+	}
+}
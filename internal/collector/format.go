@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -23,6 +25,14 @@ func FormatText(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
 	fmt.Fprintf(w, "Success Rate:   %.1f%% (%s / %s)\n",
 		m.SuccessRate, formatNumber(m.SuccessCount), formatNumber(m.TotalRequests))
 	fmt.Fprintf(w, "Requests/sec:   %.1f\n", m.RequestsPerSec)
+	if m.Iterations > 0 {
+		fmt.Fprintf(w, "Iterations:     %s (avg=%s  p95=%s)\n", formatNumber(m.Iterations),
+			FormatDuration(m.IterationDuration.Avg), FormatDuration(m.IterationDuration.P95))
+	}
+	if m.BytesReceived > 0 || m.BytesSent > 0 {
+		fmt.Fprintf(w, "Data:           %s received, %s sent\n",
+			FormatBytes(float64(m.BytesReceived)), FormatBytes(float64(m.BytesSent)))
+	}
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "Response Times:")
 	fmt.Fprintf(w, "  Min:    %s\n", FormatDuration(m.Duration.Min))
@@ -32,6 +42,13 @@ func FormatText(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
 	fmt.Fprintf(w, "  P95:    %s\n", FormatDuration(m.Duration.P95))
 	fmt.Fprintf(w, "  P99:    %s\n", FormatDuration(m.Duration.P99))
 	fmt.Fprintf(w, "  Max:    %s\n", FormatDuration(m.Duration.Max))
+	if len(m.Errors) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Errors:")
+		for _, errType := range sortedKeys(m.Errors) {
+			fmt.Fprintf(w, "  %-10s %s\n", errType+":", formatNumber(m.Errors[errType]))
+		}
+	}
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "By Step:")
 	for step, sm := range m.Steps {
@@ -42,6 +59,65 @@ func FormatText(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
 			FormatDuration(sm.Duration.P99))
 	}
 
+	if len(m.StatusCodes) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Status Codes:")
+		for _, code := range sortedCodes(m.StatusCodes) {
+			sc := m.StatusCodes[code]
+			if sc.Unmatched == 0 {
+				fmt.Fprintf(w, "  ✓ %d: %s\n", code, formatNumber(sc.Matched))
+				continue
+			}
+			var steps []string
+			for _, step := range sortedStepNames(m.Steps) {
+				if ssc := m.Steps[step].StatusCodes[code]; ssc != nil && ssc.Unmatched > 0 {
+					steps = append(steps, fmt.Sprintf("%s=%s", step, formatNumber(ssc.Unmatched)))
+				}
+			}
+			fmt.Fprintf(w, "  ✗ %d: %s (%s unexpected: %s)\n",
+				code, formatNumber(sc.Matched+sc.Unmatched), formatNumber(sc.Unmatched),
+				strings.Join(steps, ", "))
+		}
+	}
+
+	if m.Checks.Total() > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "Checks: %.1f%% passed (%s / %s)\n",
+			m.Checks.Rate(), formatNumber(m.Checks.Passes), formatNumber(m.Checks.Total()))
+		for _, step := range sortedStepNames(m.Steps) {
+			checks := m.Steps[step].Checks
+			names := make([]string, 0, len(checks))
+			for name := range checks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				cm := checks[name]
+				symbol := "✓"
+				if cm.Fails > 0 {
+					symbol = "✗"
+				}
+				fmt.Fprintf(w, "  %s %s: %s  %.1f%% (%s / %s)\n",
+					symbol, step, name, cm.Rate(),
+					formatNumber(cm.Passes), formatNumber(cm.Total()))
+			}
+		}
+	}
+
+	if m.Schema.Violations > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "Schema Violations: %s in %s responses\n",
+			formatNumber(m.Schema.Violations), formatNumber(m.Schema.InvalidResponses))
+		for _, step := range sortedStepNames(m.Steps) {
+			sm := m.Steps[step].Schema
+			if sm.Violations == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "  %-15s %s in %s responses\n",
+				step, formatNumber(sm.Violations), formatNumber(sm.InvalidResponses))
+		}
+	}
+
 	if thresholds != nil && len(thresholds.Results) > 0 {
 		fmt.Fprintln(w, "")
 		fmt.Fprintln(w, "Thresholds:")
@@ -50,25 +126,43 @@ func FormatText(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
 			if !result.Passed {
 				symbol = "✗"
 			}
-			fmt.Fprintf(w, "  %s %s < %s (actual: %s)\n",
-				symbol, result.Name, result.Threshold, result.Actual)
+			op := result.Operator
+			if op == "" {
+				op = "<"
+			}
+			fmt.Fprintf(w, "  %s %s %s %s (actual: %s)\n",
+				symbol, result.Name, op, result.Threshold, result.Actual)
+		}
+	}
+
+	if thresholds != nil && thresholds.Baseline != nil {
+		formatComparison(w, thresholds.Baseline)
+	}
+}
+
+// formatComparison writes the baseline comparison as a table, whole-run
+// rows first and then one block per step.
+func formatComparison(w io.Writer, c *Comparison) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Baseline Comparison (vs %s):\n", c.Baseline)
+	fmt.Fprintf(w, "    %-15s %-24s %10s %10s %9s  %s\n", "Step", "Metric", "Baseline", "Current", "Delta", "Limit")
+	for _, row := range c.Rows {
+		symbol := "✓"
+		if !row.Passed {
+			symbol = "✗"
 		}
+		step := row.Step
+		if step == "" {
+			step = "(all)"
+		}
+		fmt.Fprintf(w, "  %s %-15s %-24s %10s %10s %9s  %s\n",
+			symbol, step, row.Metric, row.Baseline, row.Current, row.Delta, row.Limit)
 	}
 }
 
 // FormatJSON writes metrics in JSON format.
 func FormatJSON(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
-	output := struct {
-		Duration       string                     `json:"duration"`
-		TotalRequests  int                        `json:"totalRequests"`
-		SuccessCount   int                        `json:"successCount"`
-		FailureCount   int                        `json:"failureCount"`
-		SuccessRate    float64                    `json:"successRate"`
-		RequestsPerSec float64                    `json:"requestsPerSec"`
-		Durations      jsonDurationMetrics        `json:"durations"`
-		Steps          map[string]jsonStepMetrics `json:"steps"`
-		Thresholds     *ThresholdResults          `json:"thresholds,omitempty"`
-	}{
+	output := jsonMetrics{
 		Duration:       m.TestDuration.Round(time.Millisecond).String(),
 		TotalRequests:  m.TotalRequests,
 		SuccessCount:   m.SuccessCount,
@@ -76,17 +170,48 @@ func FormatJSON(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
 		SuccessRate:    m.SuccessRate,
 		RequestsPerSec: m.RequestsPerSec,
 		Durations:      toJSONDurationMetrics(m.Duration),
+		Iterations:     m.Iterations,
+		IterationDur:   toJSONDurationMetrics(m.IterationDuration),
+		BytesSent:      m.BytesSent,
+		BytesReceived:  m.BytesReceived,
+		DroppedEvents:  m.DroppedEvents,
+		Errors:         m.Errors,
+		StatusCodes:    m.StatusCodes,
 		Steps:          make(map[string]jsonStepMetrics),
 		Thresholds:     thresholds,
 	}
 
+	if m.Checks.Total() > 0 {
+		checks := toJSONCheckMetrics(m.Checks)
+		output.Checks = &checks
+	}
+
+	if m.Schema.Violations > 0 {
+		output.Schema = &m.Schema
+	}
+
 	for step, sm := range m.Steps {
+		var schema *SchemaMetrics
+		if sm.Schema.Violations > 0 {
+			schema = &sm.Schema
+		}
+		var checks map[string]jsonCheckMetrics
+		if len(sm.Checks) > 0 {
+			checks = make(map[string]jsonCheckMetrics, len(sm.Checks))
+			for name, cm := range sm.Checks {
+				checks[name] = toJSONCheckMetrics(*cm)
+			}
+		}
 		output.Steps[step] = jsonStepMetrics{
 			Count:       sm.Count,
 			Success:     sm.Success,
 			Failed:      sm.Failed,
 			SuccessRate: float64(sm.Success) / float64(sm.Count) * 100,
 			Durations:   toJSONDurationMetrics(sm.Duration),
+			Errors:      sm.Errors,
+			Checks:      checks,
+			Schema:      schema,
+			StatusCodes: sm.StatusCodes,
 		}
 	}
 
@@ -95,6 +220,29 @@ func FormatJSON(w io.Writer, m *Metrics, thresholds *ThresholdResults) {
 	_ = encoder.Encode(output) // stdout errors are unrecoverable
 }
 
+// jsonMetrics is the document written by FormatJSON and read back by
+// LoadBaseline.
+type jsonMetrics struct {
+	Duration       string                     `json:"duration"`
+	TotalRequests  int                        `json:"totalRequests"`
+	SuccessCount   int                        `json:"successCount"`
+	FailureCount   int                        `json:"failureCount"`
+	SuccessRate    float64                    `json:"successRate"`
+	RequestsPerSec float64                    `json:"requestsPerSec"`
+	Durations      jsonDurationMetrics        `json:"durations"`
+	Iterations     int                        `json:"iterations"`
+	IterationDur   jsonDurationMetrics        `json:"iterationDurations"`
+	BytesSent      int64                      `json:"bytesSent"`
+	BytesReceived  int64                      `json:"bytesReceived"`
+	DroppedEvents  int64                      `json:"droppedEvents,omitempty"`
+	Errors         map[string]int             `json:"errors,omitempty"`
+	Checks         *jsonCheckMetrics          `json:"checks,omitempty"`
+	Schema         *SchemaMetrics             `json:"schema,omitempty"`
+	StatusCodes    map[int]*StatusMetrics     `json:"statusCodes,omitempty"`
+	Steps          map[string]jsonStepMetrics `json:"steps"`
+	Thresholds     *ThresholdResults          `json:"thresholds,omitempty"`
+}
+
 type jsonDurationMetrics struct {
 	Min string `json:"min"`
 	Max string `json:"max"`
@@ -103,14 +251,42 @@ type jsonDurationMetrics struct {
 	P90 string `json:"p90"`
 	P95 string `json:"p95"`
 	P99 string `json:"p99"`
+
+	// Nanoseconds holds the exact values. The strings above are rounded
+	// for display and would skew baseline comparisons.
+	Nanoseconds *jsonDurationNanos `json:"nanoseconds,omitempty"`
+}
+
+type jsonDurationNanos struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+	Avg int64 `json:"avg"`
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P95 int64 `json:"p95"`
+	P99 int64 `json:"p99"`
 }
 
 type jsonStepMetrics struct {
-	Count       int                 `json:"count"`
-	Success     int                 `json:"success"`
-	Failed      int                 `json:"failed"`
-	SuccessRate float64             `json:"successRate"`
-	Durations   jsonDurationMetrics `json:"durations"`
+	Count       int                         `json:"count"`
+	Success     int                         `json:"success"`
+	Failed      int                         `json:"failed"`
+	SuccessRate float64                     `json:"successRate"`
+	Durations   jsonDurationMetrics         `json:"durations"`
+	Errors      map[string]int              `json:"errors,omitempty"`
+	Checks      map[string]jsonCheckMetrics `json:"checks,omitempty"`
+	Schema      *SchemaMetrics              `json:"schema,omitempty"`
+	StatusCodes map[int]*StatusMetrics      `json:"statusCodes,omitempty"`
+}
+
+type jsonCheckMetrics struct {
+	Passes int     `json:"passes"`
+	Fails  int     `json:"fails"`
+	Rate   float64 `json:"rate"`
+}
+
+func toJSONCheckMetrics(c CheckMetrics) jsonCheckMetrics {
+	return jsonCheckMetrics{Passes: c.Passes, Fails: c.Fails, Rate: c.Rate()}
 }
 
 func toJSONDurationMetrics(d DurationMetrics) jsonDurationMetrics {
@@ -122,6 +298,15 @@ func toJSONDurationMetrics(d DurationMetrics) jsonDurationMetrics {
 		P90: FormatDuration(d.P90),
 		P95: FormatDuration(d.P95),
 		P99: FormatDuration(d.P99),
+		Nanoseconds: &jsonDurationNanos{
+			Min: int64(d.Min),
+			Max: int64(d.Max),
+			Avg: int64(d.Avg),
+			P50: int64(d.P50),
+			P90: int64(d.P90),
+			P95: int64(d.P95),
+			P99: int64(d.P99),
+		},
 	}
 }
 
@@ -131,3 +316,30 @@ func formatNumber(n int) string {
 	}
 	return fmt.Sprintf("%d,%03d", n/1000, n%1000)
 }
+
+func sortedStepNames(steps map[string]*StepMetrics) []string {
+	names := make([]string, 0, len(steps))
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedCodes(m map[int]*StatusMetrics) []int {
+	codes := make([]int, 0, len(m))
+	for code := range m {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
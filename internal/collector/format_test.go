@@ -265,3 +265,81 @@ func TestFormatText_LargeNumbers(t *testing.T) {
 		t.Errorf("expected formatted number 1,500 in output, got: %s", output)
 	}
 }
+
+func TestFormatText_Errors(t *testing.T) {
+	m := &Metrics{
+		TotalRequests: 10,
+		SuccessCount:  7,
+		FailureCount:  3,
+		Errors:        map[string]int{"timeout": 2, "status": 1},
+		Steps:         map[string]*StepMetrics{},
+	}
+
+	var buf bytes.Buffer
+	FormatText(&buf, m, nil)
+	output := buf.String()
+
+	if !strings.Contains(output, "Errors:") {
+		t.Errorf("expected Errors section, got: %s", output)
+	}
+	// Sorted by category name
+	statusIdx := strings.Index(output, "status:")
+	timeoutIdx := strings.Index(output, "timeout:")
+	if statusIdx == -1 || timeoutIdx == -1 || statusIdx > timeoutIdx {
+		t.Errorf("expected sorted error categories, got: %s", output)
+	}
+}
+
+func TestFormatText_SchemaViolations(t *testing.T) {
+	m := &Metrics{
+		TotalRequests: 10,
+		SuccessCount:  7,
+		FailureCount:  3,
+		Schema:        SchemaMetrics{InvalidResponses: 3, Violations: 5},
+		Steps: map[string]*StepMetrics{
+			"orders": {Count: 5, Schema: SchemaMetrics{InvalidResponses: 3, Violations: 5}},
+			"health": {Count: 5},
+		},
+	}
+
+	var buf bytes.Buffer
+	FormatText(&buf, m, nil)
+	output := buf.String()
+
+	if !strings.Contains(output, "Schema Violations: 5 in 3 responses") {
+		t.Errorf("expected schema violation totals, got: %s", output)
+	}
+	if !strings.Contains(output, "orders          5 in 3 responses") {
+		t.Errorf("expected per-step schema violations, got: %s", output)
+	}
+	if strings.Contains(output, "health          0") {
+		t.Errorf("expected steps without violations to be omitted, got: %s", output)
+	}
+}
+
+func TestFormatText_StatusCodes(t *testing.T) {
+	m := &Metrics{
+		TotalRequests: 12,
+		SuccessCount:  9,
+		FailureCount:  3,
+		StatusCodes: map[int]*StatusMetrics{
+			200: {Matched: 9},
+			302: {Unmatched: 3},
+		},
+		Steps: map[string]*StepMetrics{
+			"account": {Count: 5, StatusCodes: map[int]*StatusMetrics{200: {Matched: 3}, 302: {Unmatched: 2}}},
+			"orders":  {Count: 7, StatusCodes: map[int]*StatusMetrics{200: {Matched: 6}, 302: {Unmatched: 1}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	FormatText(&buf, m, nil)
+	output := buf.String()
+
+	if !strings.Contains(output, "✓ 200: 9") {
+		t.Errorf("expected matched status line, got: %s", output)
+	}
+	if !strings.Contains(output, "✗ 302: 3 (3 unexpected: account=2, orders=1)") {
+		t.Errorf("expected unmatched status line, got: %s", output)
+	}
+}
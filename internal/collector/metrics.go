@@ -3,18 +3,33 @@ package collector
 import (
 	"sort"
 	"time"
+
+	"maestro/internal/core"
 )
 
 // Metrics contains aggregated test results.
 type Metrics struct {
-	TotalRequests  int                    `json:"totalRequests"`
-	SuccessCount   int                    `json:"successCount"`
-	FailureCount   int                    `json:"failureCount"`
-	SuccessRate    float64                `json:"successRate"`
-	RequestsPerSec float64                `json:"requestsPerSec"`
-	TestDuration   time.Duration          `json:"testDuration"`
-	Duration       DurationMetrics        `json:"durations"`
-	Steps          map[string]*StepMetrics `json:"steps"`
+	TotalRequests     int                     `json:"totalRequests"`
+	SuccessCount      int                     `json:"successCount"`
+	FailureCount      int                     `json:"failureCount"`
+	SuccessRate       float64                 `json:"successRate"`
+	RequestsPerSec    float64                 `json:"requestsPerSec"`
+	TestDuration      time.Duration           `json:"testDuration"`
+	Duration          DurationMetrics         `json:"durations"`
+	Errors            map[string]int          `json:"errors,omitempty"` // failures by core.ErrorType*
+	Checks            CheckMetrics            `json:"checks"`           // totals over all checks
+	Schema            SchemaMetrics           `json:"schema"`           // totals over all schema validations
+	StatusCodes       map[int]*StatusMetrics  `json:"statusCodes,omitempty"`
+	BytesSent         int64                   `json:"bytesSent"`
+	BytesReceived     int64                   `json:"bytesReceived"`
+	Iterations        int                     `json:"iterations"` // completed workflow iterations
+	IterationDuration DurationMetrics         `json:"iterationDuration"`
+	DroppedEvents     int64                   `json:"droppedEvents,omitempty"` // set by the caller from Collector.DroppedEvents
+	Steps             map[string]*StepMetrics `json:"steps"`
+
+	// events are retained so scoped thresholds can recompute metrics
+	// over a subset of them.
+	events []core.Event
 }
 
 // DurationMetrics contains latency statistics.
@@ -30,10 +45,46 @@ type DurationMetrics struct {
 
 // StepMetrics contains per-step statistics.
 type StepMetrics struct {
-	Count    int             `json:"count"`
-	Success  int             `json:"success"`
-	Failed   int             `json:"failed"`
-	Duration DurationMetrics `json:"durations"`
+	Count       int                      `json:"count"`
+	Success     int                      `json:"success"`
+	Failed      int                      `json:"failed"`
+	Duration    DurationMetrics          `json:"durations"`
+	Errors      map[string]int           `json:"errors,omitempty"`
+	Checks      map[string]*CheckMetrics `json:"checks,omitempty"`
+	Schema      SchemaMetrics            `json:"schema"`
+	StatusCodes map[int]*StatusMetrics   `json:"statusCodes,omitempty"`
+}
+
+// StatusMetrics counts responses with one status code, split by whether
+// the code was expected by the step.
+type StatusMetrics struct {
+	Matched   int `json:"matched"`
+	Unmatched int `json:"unmatched"`
+}
+
+// SchemaMetrics counts JSON Schema violations in response bodies.
+type SchemaMetrics struct {
+	InvalidResponses int `json:"invalidResponses"` // responses with at least one violation
+	Violations       int `json:"violations"`
+}
+
+// CheckMetrics counts passes and failures of response checks.
+type CheckMetrics struct {
+	Passes int `json:"passes"`
+	Fails  int `json:"fails"`
+}
+
+// Total returns the number of check evaluations.
+func (c CheckMetrics) Total() int {
+	return c.Passes + c.Fails
+}
+
+// Rate returns the pass rate as a percentage (100 when nothing was checked).
+func (c CheckMetrics) Rate() float64 {
+	if c.Total() == 0 {
+		return 100
+	}
+	return float64(c.Passes) / float64(c.Total()) * 100
 }
 
 // ComputePercentile calculates the percentile value from a sorted slice.
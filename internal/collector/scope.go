@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"maestro/internal/core"
+)
+
+// Scope restricts a threshold to a subset of events, written as
+// {step:checkout} or {group:auth,env:staging}. All filters must match.
+type Scope []ScopeFilter
+
+// ScopeFilter matches events by step, group, scenario or a custom tag.
+type ScopeFilter struct {
+	Key   string
+	Value string
+}
+
+// Match reports whether e belongs to the scope.
+func (s Scope) Match(e core.Event) bool {
+	for _, f := range s {
+		var actual string
+		switch f.Key {
+		case "step":
+			actual = e.Step
+		case "group":
+			actual = e.Group
+		case "scenario":
+			actual = e.Scenario
+		case "status":
+			if !matchStatus(f.Value, e.StatusCode) {
+				return false
+			}
+			continue
+		default:
+			actual = e.Tags[f.Key]
+		}
+		if actual != f.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Scope) String() string {
+	parts := make([]string, len(s))
+	for i, f := range s {
+		parts[i] = f.Key + ":" + f.Value
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// matchStatus matches a status code against "200" or a class like "5xx".
+func matchStatus(pattern string, code int) bool {
+	if code == 0 {
+		return false
+	}
+	if len(pattern) == 3 && strings.EqualFold(pattern[1:], "xx") {
+		return pattern[0] == byte('0'+code/100)
+	}
+	return pattern == strconv.Itoa(code)
+}
+
+// parseMetricKey splits a threshold key such as
+// "http_req_duration{step:checkout}" into the metric name and its scope.
+func parseMetricKey(key string) (string, Scope, error) {
+	open := strings.Index(key, "{")
+	if open == -1 {
+		return strings.TrimSpace(key), nil, nil
+	}
+	if !strings.HasSuffix(key, "}") {
+		return "", nil, fmt.Errorf("threshold %q: missing closing }", key)
+	}
+	metric := strings.TrimSpace(key[:open])
+	body := key[open+1 : len(key)-1]
+
+	var scope Scope
+	for _, part := range strings.Split(body, ",") {
+		k, v, ok := strings.Cut(part, ":")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			return "", nil, fmt.Errorf("threshold %q: scope filters must be key:value", key)
+		}
+		scope = append(scope, ScopeFilter{Key: k, Value: v})
+	}
+	return metric, scope, nil
+}
+
+// filterEvents returns the events that belong to scope.
+func filterEvents(events []core.Event, scope Scope) []core.Event {
+	var matched []core.Event
+	for _, e := range events {
+		if scope.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
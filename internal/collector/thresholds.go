@@ -5,12 +5,35 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Thresholds defines pass/fail criteria for the test.
 type Thresholds struct {
 	HTTPReqDuration *DurationThresholds `yaml:"http_req_duration"`
 	HTTPReqFailed   *FailureThresholds  `yaml:"http_req_failed"`
+	Checks          *CheckThresholds    `yaml:"checks"`
+
+	// Scoped holds thresholds written with a scope, such as
+	// http_req_duration{step:checkout}, in config order.
+	Scoped []ScopedThresholds `yaml:"-"`
+
+	// Expressions are free-form thresholds over any metric, listed
+	// under the expressions key.
+	Expressions []Expression `yaml:"-"`
+
+	// Regressions limit changes against a baseline run given with
+	// --baseline, listed under the regressions key.
+	Regressions []RegressionRule `yaml:"-"`
+}
+
+// ScopedThresholds are thresholds evaluated only over events in Scope.
+type ScopedThresholds struct {
+	Scope           Scope
+	HTTPReqDuration *DurationThresholds
+	HTTPReqFailed   *FailureThresholds
+	Checks          *CheckThresholds
 }
 
 // DurationThresholds defines latency limits.
@@ -27,21 +50,114 @@ type FailureThresholds struct {
 	Rate string `yaml:"rate"`
 }
 
+// CheckThresholds defines the minimum check pass rate.
+type CheckThresholds struct {
+	Rate string `yaml:"rate"`
+}
+
 // ThresholdResult represents the outcome of a single threshold check.
 type ThresholdResult struct {
 	Name      string `json:"name"`
 	Passed    bool   `json:"passed"`
+	Operator  string `json:"operator"`
 	Threshold string `json:"threshold"`
 	Actual    string `json:"actual"`
 }
 
 // ThresholdResults contains all threshold check results.
 type ThresholdResults struct {
-	Passed  bool              `json:"passed"`
-	Results []ThresholdResult `json:"results"`
+	Passed   bool              `json:"passed"`
+	Results  []ThresholdResult `json:"results"`
+	Baseline *Comparison       `json:"baseline,omitempty"`
+}
+
+// AddComparison records a baseline comparison, failing the results if
+// any regression rule was violated.
+func (r *ThresholdResults) AddComparison(c *Comparison) {
+	r.Baseline = c
+	if !c.Passed {
+		r.Passed = false
+	}
+}
+
+// UnmarshalYAML decodes threshold keys, which may carry a scope
+// such as http_req_failed{group:auth}.
+func (t *Thresholds) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: thresholds must be a mapping", value.Line)
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, node := value.Content[i].Value, value.Content[i+1]
+		if key == "expressions" {
+			if err := t.decodeExpressions(node); err != nil {
+				return err
+			}
+			continue
+		}
+		if key == "regressions" {
+			if err := t.decodeRegressions(node); err != nil {
+				return err
+			}
+			continue
+		}
+
+		metric, scope, err := parseMetricKey(key)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", value.Content[i].Line, err)
+		}
+
+		duration, failed, checks := &t.HTTPReqDuration, &t.HTTPReqFailed, &t.Checks
+		if scope != nil {
+			st := t.scoped(scope)
+			duration, failed, checks = &st.HTTPReqDuration, &st.HTTPReqFailed, &st.Checks
+		}
+
+		switch metric {
+		case "http_req_duration":
+			err = node.Decode(duration)
+		case "http_req_failed":
+			err = node.Decode(failed)
+		case "checks":
+			err = node.Decode(checks)
+		default:
+			err = fmt.Errorf("line %d: unknown threshold metric %q", value.Content[i].Line, metric)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Thresholds) decodeExpressions(node *yaml.Node) error {
+	var sources []string
+	if err := node.Decode(&sources); err != nil {
+		return err
+	}
+	for _, src := range sources {
+		expr, err := ParseExpression(src)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		t.Expressions = append(t.Expressions, expr)
+	}
+	return nil
+}
+
+// scoped returns the thresholds for scope, adding an entry on first use.
+func (t *Thresholds) scoped(scope Scope) *ScopedThresholds {
+	for i := range t.Scoped {
+		if t.Scoped[i].Scope.String() == scope.String() {
+			return &t.Scoped[i]
+		}
+	}
+	t.Scoped = append(t.Scoped, ScopedThresholds{Scope: scope})
+	return &t.Scoped[len(t.Scoped)-1]
 }
 
 // Check evaluates all thresholds against computed metrics.
+// Scoped thresholds are evaluated over the events m was computed from.
 func (t *Thresholds) Check(m *Metrics) *ThresholdResults {
 	if t == nil {
 		return &ThresholdResults{Passed: true, Results: nil}
@@ -52,28 +168,59 @@ func (t *Thresholds) Check(m *Metrics) *ThresholdResults {
 		Results: make([]ThresholdResult, 0),
 	}
 
-	if t.HTTPReqDuration != nil {
-		results.checkDurationThresholds(t.HTTPReqDuration, &m.Duration)
+	results.checkMetrics("", t.HTTPReqDuration, t.HTTPReqFailed, t.Checks, m)
+
+	for _, st := range t.Scoped {
+		sm := ComputeMetrics(filterEvents(m.events, st.Scope), m.TestDuration)
+		first := len(results.Results)
+		results.checkMetrics(st.Scope.String(), st.HTTPReqDuration, st.HTTPReqFailed, st.Checks, sm)
+
+		// A scope that matched nothing is most likely a typo; don't let it pass
+		if sm.TotalRequests == 0 {
+			for i := first; i < len(results.Results); i++ {
+				results.Results[i].Passed = false
+				results.Results[i].Actual = "no requests"
+			}
+			if len(results.Results) > first {
+				results.Passed = false
+			}
+		}
 	}
 
-	if t.HTTPReqFailed != nil && t.HTTPReqFailed.Rate != "" {
-		results.checkFailureRate(t.HTTPReqFailed, m)
+	for _, expr := range t.Expressions {
+		results.checkExpression(expr, m)
 	}
 
 	return results
 }
 
-func (r *ThresholdResults) checkDurationThresholds(thresholds *DurationThresholds, actual *DurationMetrics) {
+// checkMetrics evaluates one set of thresholds. scope is appended to metric
+// names so scoped results are reported separately.
+func (r *ThresholdResults) checkMetrics(scope string, duration *DurationThresholds, failed *FailureThresholds, checks *CheckThresholds, m *Metrics) {
+	if duration != nil {
+		r.checkDurationThresholds("http_req_duration"+scope, duration, &m.Duration)
+	}
+
+	if failed != nil && failed.Rate != "" {
+		r.checkFailureRate("http_req_failed"+scope, failed, m)
+	}
+
+	if checks != nil && checks.Rate != "" {
+		r.checkCheckRate("checks"+scope, checks, m)
+	}
+}
+
+func (r *ThresholdResults) checkDurationThresholds(metric string, thresholds *DurationThresholds, actual *DurationMetrics) {
 	checks := []struct {
 		name      string
 		threshold time.Duration
 		actual    time.Duration
 	}{
-		{"http_req_duration.avg", thresholds.Avg, actual.Avg},
-		{"http_req_duration.p50", thresholds.P50, actual.P50},
-		{"http_req_duration.p90", thresholds.P90, actual.P90},
-		{"http_req_duration.p95", thresholds.P95, actual.P95},
-		{"http_req_duration.p99", thresholds.P99, actual.P99},
+		{metric + ".avg", thresholds.Avg, actual.Avg},
+		{metric + ".p50", thresholds.P50, actual.P50},
+		{metric + ".p90", thresholds.P90, actual.P90},
+		{metric + ".p95", thresholds.P95, actual.P95},
+		{metric + ".p99", thresholds.P99, actual.P99},
 	}
 
 	for _, check := range checks {
@@ -89,13 +236,14 @@ func (r *ThresholdResults) checkDurationThresholds(thresholds *DurationThreshold
 		r.Results = append(r.Results, ThresholdResult{
 			Name:      check.name,
 			Passed:    passed,
+			Operator:  "<",
 			Threshold: FormatDuration(check.threshold),
 			Actual:    FormatDuration(check.actual),
 		})
 	}
 }
 
-func (r *ThresholdResults) checkFailureRate(thresholds *FailureThresholds, m *Metrics) {
+func (r *ThresholdResults) checkFailureRate(metric string, thresholds *FailureThresholds, m *Metrics) {
 	thresholdRate, err := parsePercentage(thresholds.Rate)
 	if err != nil {
 		return
@@ -109,8 +257,31 @@ func (r *ThresholdResults) checkFailureRate(thresholds *FailureThresholds, m *Me
 	}
 
 	r.Results = append(r.Results, ThresholdResult{
-		Name:      "http_req_failed.rate",
+		Name:      metric + ".rate",
+		Passed:    passed,
+		Operator:  "<",
+		Threshold: thresholds.Rate,
+		Actual:    fmt.Sprintf("%.2f%%", actualRate),
+	})
+}
+
+func (r *ThresholdResults) checkCheckRate(metric string, thresholds *CheckThresholds, m *Metrics) {
+	thresholdRate, err := parsePercentage(thresholds.Rate)
+	if err != nil {
+		return
+	}
+
+	actualRate := m.Checks.Rate()
+	passed := actualRate >= thresholdRate
+
+	if !passed {
+		r.Passed = false
+	}
+
+	r.Results = append(r.Results, ThresholdResult{
+		Name:      metric + ".rate",
 		Passed:    passed,
+		Operator:  ">=",
 		Threshold: thresholds.Rate,
 		Actual:    fmt.Sprintf("%.2f%%", actualRate),
 	})
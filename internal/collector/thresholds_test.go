@@ -1,8 +1,13 @@
 package collector
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"maestro/internal/core"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestThresholds_NilThresholds(t *testing.T) {
@@ -232,3 +237,149 @@ func TestThresholdResults_Violations(t *testing.T) {
 		}
 	}
 }
+
+func TestThresholds_CheckRate(t *testing.T) {
+	thresholds := &Thresholds{
+		Checks: &CheckThresholds{Rate: "99%"},
+	}
+
+	tests := []struct {
+		name   string
+		checks CheckMetrics
+		want   bool
+	}{
+		{"all pass", CheckMetrics{Passes: 100}, true},
+		{"at threshold", CheckMetrics{Passes: 99, Fails: 1}, true},
+		{"below threshold", CheckMetrics{Passes: 95, Fails: 5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := thresholds.Check(&Metrics{Checks: tt.checks})
+			if results.Passed != tt.want {
+				t.Errorf("expected passed=%v, got %v", tt.want, results.Passed)
+			}
+			if results.Results[0].Operator != ">=" {
+				t.Errorf("expected operator >=, got %q", results.Results[0].Operator)
+			}
+		})
+	}
+}
+
+func TestThresholds_UnmarshalScoped(t *testing.T) {
+	content := `
+http_req_duration:
+  p95: 500ms
+http_req_duration{step:checkout}:
+  p95: 800ms
+http_req_failed{step:checkout}:
+  rate: 1%
+checks{group:auth, env:staging}:
+  rate: 99%
+`
+	var thresholds Thresholds
+	if err := yaml.Unmarshal([]byte(content), &thresholds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if thresholds.HTTPReqDuration == nil || thresholds.HTTPReqDuration.P95 != 500*time.Millisecond {
+		t.Errorf("expected global p95 500ms, got %+v", thresholds.HTTPReqDuration)
+	}
+	if len(thresholds.Scoped) != 2 {
+		t.Fatalf("expected 2 scopes, got %d", len(thresholds.Scoped))
+	}
+
+	checkout := thresholds.Scoped[0]
+	if checkout.Scope.String() != "{step:checkout}" {
+		t.Errorf("expected scope {step:checkout}, got %s", checkout.Scope)
+	}
+	if checkout.HTTPReqDuration.P95 != 800*time.Millisecond || checkout.HTTPReqFailed.Rate != "1%" {
+		t.Errorf("unexpected checkout thresholds: %+v", checkout)
+	}
+	if got := thresholds.Scoped[1].Scope.String(); got != "{group:auth,env:staging}" {
+		t.Errorf("expected scope {group:auth,env:staging}, got %s", got)
+	}
+}
+
+func TestThresholds_UnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		wantErr string
+	}{
+		{"http_req_latency:\n  p95: 1s\n", `unknown threshold metric "http_req_latency"`},
+		{"http_req_duration{step:checkout:\n  p95: 1s\n", "missing closing }"},
+		{"http_req_duration{checkout}:\n  p95: 1s\n", "key:value"},
+	}
+
+	for _, tt := range tests {
+		var thresholds Thresholds
+		err := yaml.Unmarshal([]byte(tt.content), &thresholds)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+		}
+	}
+}
+
+func TestThresholds_ScopedResultsReportedSeparately(t *testing.T) {
+	var events []core.Event
+	for i := 0; i < 20; i++ {
+		events = append(events,
+			core.Event{Step: "health", Group: "monitoring", Success: true, Duration: 5 * time.Millisecond},
+			core.Event{Step: "checkout", Success: i%10 != 0, Duration: 900 * time.Millisecond, Tags: map[string]string{"tier": "critical"}},
+		)
+	}
+	m := ComputeMetrics(events, 10*time.Second)
+
+	thresholds := &Thresholds{
+		HTTPReqDuration: &DurationThresholds{P50: time.Second},
+		Scoped: []ScopedThresholds{
+			{Scope: Scope{{Key: "step", Value: "checkout"}}, HTTPReqDuration: &DurationThresholds{P95: 800 * time.Millisecond}},
+			{Scope: Scope{{Key: "group", Value: "monitoring"}}, HTTPReqFailed: &FailureThresholds{Rate: "1%"}},
+			{Scope: Scope{{Key: "tier", Value: "critical"}}, HTTPReqFailed: &FailureThresholds{Rate: "5%"}},
+		},
+	}
+
+	results := thresholds.Check(m)
+
+	want := map[string]bool{
+		"http_req_duration.p50":                  true,
+		"http_req_duration{step:checkout}.p95":   false,
+		"http_req_failed{group:monitoring}.rate": true,
+		"http_req_failed{tier:critical}.rate":    false,
+	}
+	if len(results.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), results.Results)
+	}
+	for _, r := range results.Results {
+		passed, ok := want[r.Name]
+		if !ok {
+			t.Errorf("unexpected result %q", r.Name)
+			continue
+		}
+		if r.Passed != passed {
+			t.Errorf("%s: expected passed=%v, got %v (actual %s)", r.Name, passed, r.Passed, r.Actual)
+		}
+	}
+	if results.Passed {
+		t.Error("expected overall failure")
+	}
+}
+
+func TestThresholds_ScopeWithoutRequestsFails(t *testing.T) {
+	m := ComputeMetrics([]core.Event{{Step: "health", Success: true}}, time.Second)
+
+	thresholds := &Thresholds{
+		Scoped: []ScopedThresholds{
+			{Scope: Scope{{Key: "step", Value: "chekout"}}, HTTPReqDuration: &DurationThresholds{P95: time.Second}},
+		},
+	}
+
+	results := thresholds.Check(m)
+
+	if results.Passed {
+		t.Error("expected threshold on a scope without requests to fail")
+	}
+	if results.Results[0].Actual != "no requests" {
+		t.Errorf("expected actual 'no requests', got %q", results.Results[0].Actual)
+	}
+}
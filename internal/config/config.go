@@ -13,16 +13,22 @@ import (
 
 // Config is the root configuration structure.
 type Config struct {
+	Include     []string              `yaml:"include,omitempty"`   // Files whose fragments are imported
+	Fragments   map[string]Fragment   `yaml:"fragments,omitempty"` // Reusable step sequences
 	Workflow    WorkflowConfig        `yaml:"workflow"`
 	LoadProfile *LoadProfile          `yaml:"loadProfile,omitempty"`
 	Thresholds  *collector.Thresholds `yaml:"thresholds,omitempty"`
 	Execution   ExecutionConfig       `yaml:"execution,omitempty"`
+	HTTP        HTTPConfig            `yaml:"http,omitempty"` // Connection pool, protocol and TLS settings
 }
 
 // ExecutionConfig controls iteration-level execution behavior.
 type ExecutionConfig struct {
 	MaxIterations    int `yaml:"max_iterations"`
 	WarmupIterations int `yaml:"warmup_iterations"`
+	// Seed makes random template functions reproducible. Nil means
+	// unseeded.
+	Seed *int64 `yaml:"seed,omitempty"`
 }
 
 // LoadProfile defines the load pattern for a test.
@@ -51,9 +57,16 @@ type Phase struct {
 
 // WorkflowConfig defines a named workflow with a sequence of steps.
 type WorkflowConfig struct {
-	Name  string                      `yaml:"name"`
-	Data  map[string]DataSourceConfig `yaml:"data,omitempty"`
-	Steps []StepConfig                `yaml:"steps"`
+	Name      string                      `yaml:"name"`
+	Variables map[string]any              `yaml:"variables,omitempty"` // Visible to every step, overridable from the CLI
+	Defaults  DefaultsConfig              `yaml:"defaults,omitempty"`  // Settings every step inherits
+	Data      map[string]DataSourceConfig `yaml:"data,omitempty"`
+	Steps     []StepConfig                `yaml:"steps"`
+
+	// Deprecated: aliases of defaults.timeout and defaults.expect_status.
+	// LoadConfig moves them into Defaults and leaves them zero.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Expect  StatusCodes   `yaml:"expect_status,omitempty"`
 }
 
 // DataSourceConfig defines a data file for parameterization.
@@ -64,15 +77,97 @@ type DataSourceConfig struct {
 
 // StepConfig defines a single request step.
 type StepConfig struct {
-	Name    string            `yaml:"name"`
-	Method  string            `yaml:"method"`
-	URL     string            `yaml:"url"`
-	Headers map[string]string `yaml:"headers"`
-	Body    string            `yaml:"body"`
-	Extract map[string]string `yaml:"extract,omitempty"` // JSONPath extraction rules
+	Name    string                   `yaml:"name"`
+	Method  string                   `yaml:"method"`
+	URL     string                   `yaml:"url"`
+	Headers map[string]string        `yaml:"headers"`
+	Body    string                   `yaml:"body"`
+	Extract map[string]ExtractConfig `yaml:"extract,omitempty"`       // Variables taken from the response
+	Timeout time.Duration            `yaml:"timeout,omitempty"`       // Overrides defaults.timeout
+	Checks  []CheckConfig            `yaml:"checks,omitempty"`        // Assertions on the response
+	Schema  string                   `yaml:"schema,omitempty"`        // JSON Schema file for the response body, relative to the config file
+	Expect  StatusCodes              `yaml:"expect_status,omitempty"` // Overrides defaults.expect_status
+	// FollowRedirects defaults to true unless Expect includes a 3xx code
+	FollowRedirects *bool `yaml:"follow_redirects,omitempty"`
+
+	// Alternatives to Body. At most one body option is set.
+	BodyFile  string            `yaml:"bodyFile,omitempty"`  // Templated body read from a file, relative to the config file
+	Form      map[string]string `yaml:"form,omitempty"`      // URL-encoded form fields
+	Multipart *MultipartConfig  `yaml:"multipart,omitempty"` // multipart/form-data fields and files
+	JSON      any               `yaml:"json,omitempty"`      // YAML value sent as JSON, with templated strings
+
+	// BaseURL and Query are usually inherited from the workflow defaults.
+	BaseURL string            `yaml:"baseURL,omitempty"` // Base for a relative URL
+	Query   map[string]string `yaml:"query,omitempty"`   // Query parameters the URL does not set itself
+	// Cookies are sent with this request only, besides those in the jar.
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+
+	// Group and Tags label the step's events for scoped thresholds.
+	// Steps expanded from a fragment default to the fragment name as group.
+	Group string            `yaml:"group,omitempty"`
+	Tags  map[string]string `yaml:"tags,omitempty"`
+
+	// Composition entries are expanded by LoadConfig and never reach execution.
+	Include string            `yaml:"include,omitempty"` // Inline the steps of another file
+	Use     string            `yaml:"use,omitempty"`     // Inline a named fragment
+	With    map[string]string `yaml:"with,omitempty"`    // Fragment parameter values
+}
+
+// MultipartConfig is a multipart/form-data body. Field values, file names
+// and inline contents are templates.
+type MultipartConfig struct {
+	Fields map[string]string `yaml:"fields,omitempty"`
+	Files  []MultipartFile   `yaml:"files,omitempty"`
+}
+
+// MultipartFile is a file part. Exactly one of Path and Content is set.
+type MultipartFile struct {
+	Field       string `yaml:"field"`
+	Path        string `yaml:"path,omitempty"`        // File to upload, relative to the config file
+	Content     string `yaml:"content,omitempty"`     // Inline, templated content instead of a file
+	Filename    string `yaml:"filename,omitempty"`    // Defaults to the base name of Path
+	ContentType string `yaml:"contentType,omitempty"` // Defaults from the file extension
+}
+
+// CheckConfig defines a single assertion on a step's response.
+// Exactly one subject is set: Status, JSONPath, Header, BodyContains,
+// BodySize or ResponseTime. JSONPath and Header values are compared with
+// Equals, Contains or Matches; with none of them the value must be present.
+type CheckConfig struct {
+	Name string `yaml:"name,omitempty"` // Defaults to a description of the check
+
+	Status       StatusCodes   `yaml:"status,omitempty"`       // Status code must match, as in expect_status
+	JSONPath     string        `yaml:"jsonpath,omitempty"`     // Value at this path in a JSON body
+	Header       string        `yaml:"header,omitempty"`       // Value of this response header
+	BodyContains string        `yaml:"bodyContains,omitempty"` // Body must contain this substring
+	BodySize     *SizeRange    `yaml:"bodySize,omitempty"`     // Body size must be in this range
+	ResponseTime time.Duration `yaml:"responseTime,omitempty"` // Response must arrive faster than this
+
+	Equals   *string `yaml:"equals,omitempty"`
+	Contains string  `yaml:"contains,omitempty"`
+	Matches  string  `yaml:"matches,omitempty"` // Regular expression
+
+	// Soft checks are counted in metrics but do not fail the step.
+	Soft bool `yaml:"soft,omitempty"`
+}
+
+// SizeRange is an inclusive byte range. A zero Max means no upper bound.
+type SizeRange struct {
+	Min int64 `yaml:"min"`
+	Max int64 `yaml:"max"`
+}
+
+// Fragment is a reusable, optionally parameterized sequence of steps.
+// Parameters are referenced in step fields as ${name} and are replaced
+// when the fragment is expanded.
+type Fragment struct {
+	Params []string     `yaml:"params,omitempty"`
+	Steps  []StepConfig `yaml:"steps"`
 }
 
 // LoadConfig reads and parses a YAML configuration file.
+// Included files and fragment uses are resolved relative to the file that
+// references them, and the returned workflow contains only concrete steps.
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -84,5 +179,18 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
 
+	if err := cfg.Workflow.moveDeprecatedDefaults(); err != nil {
+		return nil, err
+	}
+	if err := resolveIncludes(&cfg, path); err != nil {
+		return nil, err
+	}
+	cfg.HTTP.TLS.resolvePaths(path)
+	cfg.Workflow.Defaults = resolveKeyFiles(cfg.Workflow.Defaults, path)
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
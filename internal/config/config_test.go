@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -285,3 +286,346 @@ func createTempFile(t *testing.T, content string) string {
 	}
 	return tmpFile
 }
+
+func TestLoadConfig_Checks(t *testing.T) {
+	content := `
+workflow:
+  name: "Checks"
+  steps:
+    - name: "get"
+      method: GET
+      url: "https://example.com/api"
+      checks:
+        - status: [200, 201]
+        - jsonpath: "$.status"
+          equals: "active"
+        - header: "Content-Type"
+          contains: "json"
+        - bodySize: {min: 10, max: 1000}
+        - responseTime: 200ms
+          soft: true
+`
+	cfg := loadConfigFromString(t, content)
+
+	checks := cfg.Workflow.Steps[0].Checks
+	if len(checks) != 5 {
+		t.Fatalf("expected 5 checks, got %d", len(checks))
+	}
+	if len(checks[0].Status) != 2 || checks[0].Status[1].Min != 201 {
+		t.Errorf("unexpected status check: %+v", checks[0])
+	}
+	if checks[1].Equals == nil || *checks[1].Equals != "active" {
+		t.Errorf("unexpected jsonpath check: %+v", checks[1])
+	}
+	if checks[3].BodySize == nil || checks[3].BodySize.Max != 1000 {
+		t.Errorf("unexpected body size check: %+v", checks[3])
+	}
+	if checks[4].ResponseTime != 200*time.Millisecond || !checks[4].Soft {
+		t.Errorf("unexpected response time check: %+v", checks[4])
+	}
+}
+
+func TestLoadConfig_InvalidChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   string
+		wantErr string
+	}{
+		{"no subject", "- equals: x", "exactly one of"},
+		{"two subjects", "- status: [200]\n          bodyContains: ok", "exactly one of"},
+		{"comparison without value", "- bodyContains: ok\n          equals: x", "apply only to"},
+		{"bad regex", "- jsonpath: $.id\n          matches: \"[\"", "invalid matches pattern"},
+		{"bad size range", "- bodySize: {min: 10, max: 1}", "must be <= max"},
+		{"bad jsonpath", "- jsonpath: \"$.items[?(@.id = 1)]\"", "use == to compare"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `
+workflow:
+  steps:
+    - name: "get"
+      method: GET
+      url: "https://example.com"
+      checks:
+        ` + tt.check + "\n"
+			tmpFile := createTempFile(t, content)
+			_, err := LoadConfig(tmpFile)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Extract(t *testing.T) {
+	content := `
+workflow:
+  steps:
+    - name: "login"
+      method: POST
+      url: "https://example.com/login"
+      extract:
+        token: "$.auth.token"
+        next: "header:Location"
+        csrf: 'regex:name="csrf" value="([^"]+)"'
+        page:
+          from: "$.page"
+          default: 1
+        cursor:
+          from: "cookie:cursor"
+          optional: true
+        session: "xpath://Header/Session/@id"
+        nonce:
+          from: "css: input[name=nonce]@value"
+          as: xml
+`
+	cfg, err := LoadConfig(createTempFile(t, content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	extract := cfg.Workflow.Steps[0].Extract
+	tests := []struct {
+		name, kind, arg string
+	}{
+		{"token", ExtractJSONPath, "$.auth.token"},
+		{"next", ExtractHeader, "Location"},
+		{"csrf", ExtractRegex, `name="csrf" value="([^"]+)"`},
+		{"page", ExtractJSONPath, "$.page"},
+		{"cursor", ExtractCookie, "cursor"},
+		{"session", ExtractXPath, "//Header/Session/@id"},
+		{"nonce", ExtractCSS, "input[name=nonce]@value"},
+	}
+	for _, tt := range tests {
+		kind, arg := extract[tt.name].Source()
+		if kind != tt.kind || arg != tt.arg {
+			t.Errorf("%s: expected %s %q, got %s %q", tt.name, tt.kind, tt.arg, kind, arg)
+		}
+	}
+	if extract["page"].Default != 1 {
+		t.Errorf("expected default 1, got %v", extract["page"].Default)
+	}
+	if !extract["cursor"].Optional {
+		t.Error("expected cursor to be optional")
+	}
+	if extract["nonce"].As != AsXML {
+		t.Errorf("expected nonce parsed as xml, got %q", extract["nonce"].As)
+	}
+}
+
+func TestLoadConfig_InvalidExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr string
+	}{
+		{"empty header", `"header:"`, "header name is required"},
+		{"bad regex", `"regex:["`, "invalid regex"},
+		{"regex without group", `"regex:csrf=\\w+"`, "must have a capture group"},
+		{"missing from", "{optional: true}", "from is required"},
+		{"unsupported jsonpath", `"$.items[(@.length-1)]"`, "script expressions"},
+		{"unknown jsonpath function", `"$.items.count()"`, "unsupported function count()"},
+		{"bad xpath", `"xpath://a[@b=='c']"`, "invalid XPath"},
+		{"bad css selector", `"css:a:hover"`, "unsupported pseudo-class"},
+		{"unknown format", `{from: "css:input@value", as: json}`, "as must be xml or html"},
+		{"format on jsonpath", `{from: "$.id", as: xml}`, "as only applies to xpath and css"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `
+workflow:
+  steps:
+    - name: "get"
+      method: GET
+      url: "https://example.com"
+      extract:
+        value: ` + tt.rule + "\n"
+			_, err := LoadConfig(createTempFile(t, content))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_InvalidTemplates(t *testing.T) {
+	content := `
+workflow:
+  steps:
+    - name: "create"
+      method: POST
+      url: "https://example.com/${tenant | reverse}"
+      body: '{"total": ${price *}}'
+      headers:
+        X-Sig: "${nope(body)}"
+        X-Ok: "${token}"
+`
+	_, err := LoadConfig(createTempFile(t, content))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		`step 1 (create): url: ${tenant | reverse}: unknown filter "reverse"`,
+		"body: ${price *}",
+		`header "X-Sig": ${nope(body)}`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %q", want, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), "X-Ok") {
+		t.Errorf("valid header reported: %q", err.Error())
+	}
+}
+
+func TestLoadConfig_BodyOptions(t *testing.T) {
+	content := `
+workflow:
+  steps:
+    - name: "login"
+      method: POST
+      url: "https://example.com/login"
+      form:
+        username: "${user}"
+    - name: "create"
+      method: POST
+      url: "https://example.com/items"
+      json:
+        name: "${name}"
+        qty: 3
+        tags: [a, b]
+`
+	cfg := loadConfigFromString(t, content)
+	if cfg.Workflow.Steps[0].Form["username"] != "${user}" {
+		t.Errorf("unexpected form: %v", cfg.Workflow.Steps[0].Form)
+	}
+	body, ok := cfg.Workflow.Steps[1].JSON.(map[string]any)
+	if !ok || body["qty"] != 3 {
+		t.Errorf("expected json mapping, got %#v", cfg.Workflow.Steps[1].JSON)
+	}
+}
+
+func TestLoadConfig_InvalidBodyOptions(t *testing.T) {
+	content := `
+workflow:
+  steps:
+    - name: "both"
+      method: POST
+      url: "https://example.com"
+      body: "x"
+      form:
+        a: "b"
+    - name: "missing"
+      method: POST
+      url: "https://example.com"
+      bodyFile: "nope.json"
+    - name: "upload"
+      method: POST
+      url: "https://example.com"
+      multipart:
+        fields:
+          note: "${note | bogus}"
+        files:
+          - field: doc
+          - path: "a.txt"
+    - name: "json"
+      method: POST
+      url: "https://example.com"
+      json:
+        items: ["${price *}"]
+`
+	_, err := LoadConfig(createTempFile(t, content))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		"step 1 (both): only one of body, bodyFile, form, multipart and json may be set",
+		"step 2 (missing): bodyFile: open",
+		`step 3 (upload): multipart field "note"`,
+		`multipart file 1: field "doc": must set exactly one of path and content`,
+		"multipart file 2: field is required",
+		"step 4 (json): json: $.items[0]: ${price *}",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %q", want, err.Error())
+		}
+	}
+}
+
+func TestLoadConfig_HTTP(t *testing.T) {
+	path := createTempFile(t, `
+http:
+  maxIdleConnsPerHost: 200
+  disableKeepAlives: true
+  http2: false
+  tls:
+    caFile: "certs/ca.pem"
+    certFile: "/etc/maestro/client.pem"
+    keyFile: "/etc/maestro/client-key.pem"
+    minVersion: "1.2"
+workflow:
+  steps:
+    - name: "get"
+      method: GET
+      url: "https://example.com"
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := cfg.HTTP
+	if h.MaxIdleConnsPerHost != 200 || !h.DisableKeepAlives || h.HTTP2 == nil || *h.HTTP2 {
+		t.Errorf("unexpected http config %+v", h)
+	}
+	if want := filepath.Join(filepath.Dir(path), "certs/ca.pem"); h.TLS.CAFile != want {
+		t.Errorf("expected caFile %q, got %q", want, h.TLS.CAFile)
+	}
+	if h.TLS.CertFile != "/etc/maestro/client.pem" || h.TLS.MinVersion != "1.2" {
+		t.Errorf("unexpected tls config %+v", h.TLS)
+	}
+}
+
+func TestLoadConfig_InvalidHTTP(t *testing.T) {
+	_, err := LoadConfig(createTempFile(t, `
+http:
+  maxConnsPerHost: -1
+  connections: per-user
+  cookies: forever
+  tls:
+    certFile: "client.pem"
+workflow:
+  steps:
+    - name: "get"
+      method: GET
+      url: "https://example.com"
+`))
+	for _, want := range []string{
+		"http: maxConnsPerHost must not be negative",
+		`unknown connections "per-user"`,
+		`unknown cookies "forever"`,
+		"tls: certFile and keyFile must be set together",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+
+	_, err = LoadConfig(createTempFile(t, `
+http:
+  tls:
+    minVersion: "1.4"
+workflow:
+  steps: []
+`))
+	if err == nil || !strings.Contains(err.Error(), `unknown minVersion "1.4"`) {
+		t.Errorf("expected minVersion error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultsConfig holds step settings shared by a workflow. A step inherits
+// each one it does not set itself.
+type DefaultsConfig struct {
+	BaseURL string            `yaml:"baseURL,omitempty"`       // Base for relative step URLs
+	Headers map[string]string `yaml:"headers,omitempty"`       // Merged under step headers
+	Query   map[string]string `yaml:"query,omitempty"`         // Merged under step query parameters
+	Timeout time.Duration     `yaml:"timeout,omitempty"`       // Default per-request timeout
+	Expect  StatusCodes       `yaml:"expect_status,omitempty"` // Default expected status codes
+}
+
+// Apply returns step with the defaults it does not override filled in.
+// Header names match case-insensitively; step maps are not modified.
+func (d DefaultsConfig) Apply(step StepConfig) StepConfig {
+	if step.BaseURL == "" {
+		step.BaseURL = d.BaseURL
+	}
+	if step.Timeout == 0 {
+		step.Timeout = d.Timeout
+	}
+	if len(step.Expect) == 0 {
+		step.Expect = d.Expect
+	}
+	step.Headers = mergeDefaults(d.Headers, step.Headers, strings.ToLower)
+	step.Query = mergeDefaults(d.Query, step.Query, func(k string) string { return k })
+	return step
+}
+
+// moveDeprecatedDefaults moves the workflow-level timeout and
+// expect_status into Defaults, the only place steps inherit them from.
+// Setting both spellings of one option is an error.
+func (w *WorkflowConfig) moveDeprecatedDefaults() error {
+	var errs []error
+	if w.Timeout != 0 {
+		if w.Defaults.Timeout != 0 {
+			errs = append(errs, errors.New("workflow: timeout and defaults.timeout are both set; use defaults.timeout"))
+		} else {
+			w.Defaults.Timeout = w.Timeout
+		}
+		w.Timeout = 0
+	}
+	if len(w.Expect) > 0 {
+		if len(w.Defaults.Expect) > 0 {
+			errs = append(errs, errors.New("workflow: expect_status and defaults.expect_status are both set; use defaults.expect_status"))
+		} else {
+			w.Defaults.Expect = w.Expect
+		}
+		w.Expect = nil
+	}
+	return errors.Join(errs...)
+}
+
+// mergeDefaults returns values with the defaults whose key it lacks added.
+// key normalizes keys for the comparison.
+func mergeDefaults(defaults, values map[string]string, key func(string) string) map[string]string {
+	if len(defaults) == 0 {
+		return values
+	}
+	merged := make(map[string]string, len(defaults)+len(values))
+	set := make(map[string]bool, len(values))
+	for k, v := range values {
+		merged[k] = v
+		set[key(k)] = true
+	}
+	for k, v := range defaults {
+		if !set[key(k)] {
+			merged[k] = v
+		}
+	}
+	return merged
+}
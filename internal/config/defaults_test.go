@@ -0,0 +1,125 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaults_Apply(t *testing.T) {
+	defaults := DefaultsConfig{
+		BaseURL: "https://api.example.com",
+		Headers: map[string]string{"Authorization": "Bearer ${token}", "Accept": "application/json"},
+		Query:   map[string]string{"api_version": "2", "tenant": "acme"},
+		Timeout: 5 * time.Second,
+		Expect:  StatusCodes{{Min: 200, Max: 299}},
+	}
+
+	step := StepConfig{
+		URL:     "/users/me",
+		Headers: map[string]string{"accept": "text/csv"},
+		Query:   map[string]string{"tenant": "globex"},
+	}
+	got := defaults.Apply(step)
+
+	if got.BaseURL != defaults.BaseURL || got.Timeout != defaults.Timeout || !reflect.DeepEqual(got.Expect, defaults.Expect) {
+		t.Errorf("expected inherited base URL, timeout and status, got %+v", got)
+	}
+	wantHeaders := map[string]string{"Authorization": "Bearer ${token}", "accept": "text/csv"}
+	if !reflect.DeepEqual(got.Headers, wantHeaders) {
+		t.Errorf("expected headers %v, got %v", wantHeaders, got.Headers)
+	}
+	wantQuery := map[string]string{"api_version": "2", "tenant": "globex"}
+	if !reflect.DeepEqual(got.Query, wantQuery) {
+		t.Errorf("expected query %v, got %v", wantQuery, got.Query)
+	}
+	if len(step.Headers) != 1 || len(step.Query) != 1 {
+		t.Error("Apply modified the step's maps")
+	}
+
+	own := defaults.Apply(StepConfig{BaseURL: "https://auth.example.com", Timeout: time.Second, Expect: StatusCodes{{Min: 404, Max: 404}}})
+	if own.BaseURL != "https://auth.example.com" || own.Timeout != time.Second || own.Expect.String() != "404" {
+		t.Errorf("expected step settings to win, got %+v", own)
+	}
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg := loadConfigFromString(t, `
+workflow:
+  defaults:
+    baseURL: "${env:API_URL}"
+    headers:
+      Authorization: "Bearer ${token}"
+    query:
+      api_version: "2"
+    timeout: 5s
+    expect_status: [2xx]
+  steps:
+    - name: "me"
+      method: GET
+      url: "/users/me"
+`)
+	d := cfg.Workflow.Defaults
+	if d.BaseURL != "${env:API_URL}" || d.Timeout != 5*time.Second || d.Expect.String() != "2xx" {
+		t.Errorf("unexpected defaults %+v", d)
+	}
+	if d.Headers["Authorization"] != "Bearer ${token}" || d.Query["api_version"] != "2" {
+		t.Errorf("unexpected default headers or query %+v", d)
+	}
+
+	_, err := LoadConfig(createTempFile(t, `
+workflow:
+  defaults:
+    headers:
+      X-Bad: "${nope()}"
+  steps:
+    - name: "me"
+      method: GET
+      url: "/users/me"
+      query:
+        q: "${q | bogus}"
+`))
+	for _, want := range []string{`defaults: header "X-Bad"`, `step 1 (me): query "q"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_WorkflowTimeoutAndExpectAreDefaults(t *testing.T) {
+	cfg := loadConfigFromString(t, `
+workflow:
+  timeout: 2s
+  expect_status: 2xx
+  steps:
+    - name: "me"
+      method: GET
+      url: "https://example.com/me"
+`)
+	w := cfg.Workflow
+	if w.Defaults.Timeout != 2*time.Second || w.Defaults.Expect.String() != "2xx" {
+		t.Errorf("expected workflow timeout and expect_status in defaults, got %+v", w.Defaults)
+	}
+	if w.Timeout != 0 || len(w.Expect) != 0 {
+		t.Errorf("expected workflow-level keys to be cleared, got %s and %s", w.Timeout, w.Expect)
+	}
+
+	_, err := LoadConfig(createTempFile(t, `
+workflow:
+  timeout: 2s
+  expect_status: 2xx
+  defaults:
+    timeout: 5s
+    expect_status: [200]
+  steps:
+    - name: "me"
+      method: GET
+      url: "https://example.com/me"
+`))
+	for _, want := range []string{"timeout and defaults.timeout are both set", "expect_status and defaults.expect_status are both set"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"maestro/internal/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Extraction sources, written as a prefix of ExtractConfig.From.
+const (
+	ExtractJSONPath = "jsonpath" // $.path into a JSON body (the default)
+	ExtractHeader   = "header"   // header:Location
+	ExtractCookie   = "cookie"   // cookie:SESSIONID, from Set-Cookie
+	ExtractStatus   = "status"   // the status code
+	ExtractRegex    = "regex"    // regex:pattern, first capture group of the body
+	ExtractBody     = "body"     // the raw body
+	ExtractXPath    = "xpath"    // xpath://Session/@id into an XML or HTML body
+	ExtractCSS      = "css"      // css:input[name=csrf]@value into an HTML or XML body
+)
+
+// Body formats for ExtractConfig.As.
+const (
+	AsXML  = "xml"
+	AsHTML = "html"
+)
+
+// ExtractConfig defines where a variable's value is taken from. In YAML it
+// is either the source alone (`"$.token"`, `"header:Location"`) or a
+// mapping with from, default and optional.
+type ExtractConfig struct {
+	From string `yaml:"from"`
+	// Default is used when the value is missing; it implies Optional.
+	Default any `yaml:"default,omitempty"`
+	// Optional leaves the variable unchanged instead of failing the step
+	// when the value is missing.
+	Optional bool `yaml:"optional,omitempty"`
+	// As parses the body as xml or html for xpath and css rules, instead of
+	// going by the Content-Type header.
+	As string `yaml:"as,omitempty"`
+}
+
+// UnmarshalYAML accepts a source string or a mapping.
+func (e *ExtractConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.From = value.Value
+		return nil
+	}
+	type plain ExtractConfig
+	return value.Decode((*plain)(e))
+}
+
+// Source splits From into the source kind and its argument: a header or
+// cookie name, a pattern, an XPath, a CSS selector or a JSONPath. Strings without a known prefix are
+// JSONPaths.
+func (e ExtractConfig) Source() (kind, arg string) {
+	switch e.From {
+	case ExtractStatus, ExtractBody:
+		return e.From, ""
+	}
+	prefix, rest, ok := strings.Cut(e.From, ":")
+	if ok {
+		switch prefix {
+		case ExtractJSONPath, ExtractHeader, ExtractCookie, ExtractRegex, ExtractXPath, ExtractCSS:
+			return prefix, strings.TrimSpace(rest)
+		}
+	}
+	return ExtractJSONPath, e.From
+}
+
+func (e ExtractConfig) validate() error {
+	kind, arg := e.Source()
+	switch kind {
+	case ExtractHeader, ExtractCookie:
+		if arg == "" {
+			return fmt.Errorf("%s name is required", kind)
+		}
+	case ExtractRegex:
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		if re.NumSubexp() == 0 {
+			return errors.New("regex must have a capture group")
+		}
+	case ExtractJSONPath:
+		if strings.TrimSpace(arg) == "" {
+			return errors.New("from is required")
+		}
+		if _, err := template.ParseJSONPath(arg); err != nil {
+			return err
+		}
+	case ExtractXPath:
+		if _, err := template.ParseXPath(arg); err != nil {
+			return err
+		}
+	case ExtractCSS:
+		if _, err := template.ParseSelector(arg); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case e.As == "":
+	case kind != ExtractXPath && kind != ExtractCSS:
+		return errors.New("as only applies to xpath and css rules")
+	case e.As != AsXML && e.As != AsHTML:
+		return fmt.Errorf("as must be %s or %s, got %q", AsXML, AsHTML, e.As)
+	}
+	return nil
+}
+
+// sortedNames returns the keys of m in order, so errors are reported
+// deterministically.
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
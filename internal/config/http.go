@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HTTPConfig tunes the transport shared by all actors. Zero values keep
+// the defaults of the http package.
+type HTTPConfig struct {
+	MaxIdleConns        int           `yaml:"maxIdleConns,omitempty"`        // Idle connections kept across all hosts
+	MaxIdleConnsPerHost int           `yaml:"maxIdleConnsPerHost,omitempty"` // Idle connections kept per host
+	MaxConnsPerHost     int           `yaml:"maxConnsPerHost,omitempty"`     // Limit on open connections per host, 0 = unlimited
+	IdleConnTimeout     time.Duration `yaml:"idleConnTimeout,omitempty"`     // How long an idle connection is kept
+	DisableKeepAlives   bool          `yaml:"disableKeepAlives,omitempty"`   // A new connection for every request
+	Connections         string        `yaml:"connections,omitempty"`         // shared (default), actor or iteration
+	Cookies             string        `yaml:"cookies,omitempty"`             // iteration (default), actor or disabled
+	// HTTP2 defaults to true: HTTP/2 is negotiated with servers that offer
+	// it over TLS. False forces HTTP/1.1.
+	HTTP2 *bool     `yaml:"http2,omitempty"`
+	TLS   TLSConfig `yaml:"tls,omitempty"`
+}
+
+// TLSConfig configures server verification and client certificates.
+// Paths are relative to the config file.
+type TLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"` // Accept any server certificate
+	CAFile             string `yaml:"caFile,omitempty"`             // PEM bundle of CAs trusted in addition to the system pool
+	CertFile           string `yaml:"certFile,omitempty"`           // PEM client certificate for mutual TLS
+	KeyFile            string `yaml:"keyFile,omitempty"`            // PEM key of CertFile
+	MinVersion         string `yaml:"minVersion,omitempty"`         // "1.0", "1.1", "1.2" or "1.3"
+}
+
+// Connection modes. With shared, all actors use one connection pool.
+// With actor, each actor has its own pool, like a separate user. With
+// iteration, each iteration starts with a new one, like a first-time
+// visitor, so TLS handshakes are measured.
+const (
+	ConnectionsShared    = "shared"
+	ConnectionsActor     = "actor"
+	ConnectionsIteration = "iteration"
+)
+
+// Cookie jar scopes. Every actor has its own jar, which is emptied at the
+// start of each iteration or kept for the actor's life. Disabled ignores
+// Set-Cookie.
+const (
+	CookiesIteration = "iteration"
+	CookiesActor     = "actor"
+	CookiesDisabled  = "disabled"
+)
+
+// tlsVersions are the accepted minVersion values.
+var tlsVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+func (h HTTPConfig) validate() error {
+	var errs []error
+	for _, n := range []struct {
+		name  string
+		value int
+	}{
+		{"maxIdleConns", h.MaxIdleConns},
+		{"maxIdleConnsPerHost", h.MaxIdleConnsPerHost},
+		{"maxConnsPerHost", h.MaxConnsPerHost},
+	} {
+		if n.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", n.name))
+		}
+	}
+	switch h.Connections {
+	case "", ConnectionsShared, ConnectionsActor, ConnectionsIteration:
+	default:
+		errs = append(errs, fmt.Errorf("unknown connections %q (expected shared, actor or iteration)", h.Connections))
+	}
+	switch h.Cookies {
+	case "", CookiesIteration, CookiesActor, CookiesDisabled:
+	default:
+		errs = append(errs, fmt.Errorf("unknown cookies %q (expected iteration, actor or disabled)", h.Cookies))
+	}
+	if err := h.TLS.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (t TLSConfig) validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("certFile and keyFile must be set together")
+	}
+	if t.MinVersion != "" {
+		for _, v := range tlsVersions {
+			if t.MinVersion == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown minVersion %q (expected one of 1.0, 1.1, 1.2, 1.3)", t.MinVersion)
+	}
+	return nil
+}
+
+// resolvePaths makes the file paths relative to the config file absolute.
+func (t *TLSConfig) resolvePaths(configPath string) {
+	for _, p := range []*string{&t.CAFile, &t.CertFile, &t.KeyFile} {
+		if *p != "" {
+			*p = resolvePath(*p, configPath)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"maestro/internal/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includedFile is the shape of a file referenced by include.
+// It may hold fragments, a bare step list, a whole workflow, or any mix.
+type includedFile struct {
+	Include   []string            `yaml:"include,omitempty"`
+	Fragments map[string]Fragment `yaml:"fragments,omitempty"`
+	Steps     []StepConfig        `yaml:"steps,omitempty"`
+	Workflow  *WorkflowConfig     `yaml:"workflow,omitempty"`
+}
+
+// fragmentDef is a fragment together with the file that defined it,
+// so nested includes inside the fragment resolve relative to that file.
+type fragmentDef struct {
+	Fragment
+	origin string
+}
+
+// includeResolver expands include and use entries across multiple files.
+type includeResolver struct {
+	rootDir   string
+	fragments map[string]fragmentDef
+	imported  map[string]bool // files whose fragments are already registered
+	fileStack []string        // include chain, for cycle detection
+	useStack  []string        // fragment expansion chain, for cycle detection
+}
+
+// resolveIncludes imports fragments from cfg.Include and replaces every
+// include/use step in cfg.Workflow with the steps it refers to.
+func resolveIncludes(cfg *Config, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving config path: %w", err)
+	}
+
+	r := &includeResolver{
+		rootDir:   filepath.Dir(absPath),
+		fragments: make(map[string]fragmentDef),
+		imported:  make(map[string]bool),
+		fileStack: []string{absPath},
+	}
+
+	file := &includedFile{Include: cfg.Include, Fragments: cfg.Fragments}
+	if err := r.importFile(absPath, file); err != nil {
+		return err
+	}
+
+	steps, err := r.expandSteps(cfg.Workflow.Steps, absPath)
+	if err != nil {
+		return err
+	}
+	cfg.Workflow.Steps = steps
+	return nil
+}
+
+// importFile registers the fragments of an already parsed file and
+// recursively imports the files it includes.
+func (r *includeResolver) importFile(path string, file *includedFile) error {
+	if r.imported[path] {
+		return nil
+	}
+	r.imported[path] = true
+
+	for name, frag := range file.Fragments {
+		if existing, ok := r.fragments[name]; ok {
+			return fmt.Errorf("fragment %q defined in both %s and %s",
+				name, r.display(existing.origin), r.display(path))
+		}
+		r.fragments[name] = fragmentDef{Fragment: frag, origin: path}
+	}
+
+	for _, inc := range file.Include {
+		incPath := resolvePath(inc, path)
+		incFile, err := r.readFile(incPath)
+		if err != nil {
+			return err
+		}
+		if err := r.importFile(incPath, incFile); err != nil {
+			return err
+		}
+		r.fileStack = r.fileStack[:len(r.fileStack)-1]
+	}
+	return nil
+}
+
+// readFile parses an included file and pushes it onto the include chain.
+// Callers must pop the chain once they are done with the file.
+func (r *includeResolver) readFile(path string) (*includedFile, error) {
+	for _, p := range r.fileStack {
+		if p == path {
+			return nil, fmt.Errorf("include cycle: %s", r.chain(r.fileStack, path))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading included file: %w", err)
+	}
+
+	var file includedFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing included file %s: %w", r.display(path), err)
+	}
+	if ignored := ignoredKeys(data); len(ignored) > 0 {
+		return nil, fmt.Errorf("included file %s: %s cannot be set in an included file (only include, fragments, steps and workflow.steps are used)",
+			r.display(path), strings.Join(ignored, ", "))
+	}
+
+	r.fileStack = append(r.fileStack, path)
+	return &file, nil
+}
+
+// ignoredKeys returns the keys of an included file that includedFile does
+// not read, such as workflow variables or a load profile, sorted. The
+// workflow name only labels the file and may stay.
+func ignoredKeys(data []byte) []string {
+	var raw map[string]any
+	if yaml.Unmarshal(data, &raw) != nil {
+		return nil
+	}
+	var ignored []string
+	for key, value := range raw {
+		switch key {
+		case "include", "fragments", "steps":
+		case "workflow":
+			workflow, _ := value.(map[string]any)
+			for wkey := range workflow {
+				if wkey != "steps" && wkey != "name" {
+					ignored = append(ignored, "workflow."+wkey)
+				}
+			}
+		default:
+			ignored = append(ignored, key)
+		}
+	}
+	sort.Strings(ignored)
+	return ignored
+}
+
+// expandSteps returns steps with include and use entries replaced by
+// their (recursively expanded) contents. from is the file the steps came from.
+func (r *includeResolver) expandSteps(steps []StepConfig, from string) ([]StepConfig, error) {
+	var result []StepConfig
+	for i, step := range steps {
+		switch {
+		case step.Include != "" && step.Use != "":
+			return nil, fmt.Errorf("%s: step %d: include and use are mutually exclusive", r.display(from), i+1)
+
+		case step.Include != "":
+			expanded, err := r.expandInclude(step.Include, from)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, expanded...)
+
+		case step.Use != "":
+			expanded, err := r.expandUse(step.Use, step.With, from)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, expanded...)
+
+		default:
+			if len(step.With) > 0 {
+				return nil, fmt.Errorf("%s: step %q: with requires use", r.display(from), step.Name)
+			}
+			if step.Schema != "" {
+				step.Schema = resolvePath(step.Schema, from)
+			}
+			if step.BodyFile != "" {
+				step.BodyFile = resolvePath(step.BodyFile, from)
+			}
+			if mp := step.Multipart; mp != nil && len(mp.Files) > 0 {
+				// Copy before resolving: fragment steps share their files
+				files := make([]MultipartFile, len(mp.Files))
+				for i, f := range mp.Files {
+					if f.Path != "" {
+						f.Path = resolvePath(f.Path, from)
+					}
+					files[i] = f
+				}
+				step.Multipart = &MultipartConfig{Fields: mp.Fields, Files: files}
+			}
+			result = append(result, resolveKeyFiles(step, from))
+		}
+	}
+	return result, nil
+}
+
+// expandInclude inlines the steps (or workflow steps) of another file.
+func (r *includeResolver) expandInclude(inc, from string) ([]StepConfig, error) {
+	path := resolvePath(inc, from)
+	file, err := r.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { r.fileStack = r.fileStack[:len(r.fileStack)-1] }()
+
+	if err := r.importFile(path, file); err != nil {
+		return nil, err
+	}
+
+	steps := file.Steps
+	if file.Workflow != nil {
+		steps = append(steps, file.Workflow.Steps...)
+	}
+	return r.expandSteps(steps, path)
+}
+
+// expandUse inlines a fragment with its parameters substituted.
+func (r *includeResolver) expandUse(name string, with map[string]string, from string) ([]StepConfig, error) {
+	frag, ok := r.fragments[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown fragment %q", r.display(from), name)
+	}
+
+	for _, used := range r.useStack {
+		if used == name {
+			return nil, fmt.Errorf("fragment cycle: %s", r.chain(r.useStack, name))
+		}
+	}
+
+	if err := checkParams(name, frag.Params, with); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.display(from), err)
+	}
+
+	r.useStack = append(r.useStack, name)
+	defer func() { r.useStack = r.useStack[:len(r.useStack)-1] }()
+
+	steps := make([]StepConfig, len(frag.Steps))
+	for i, step := range frag.Steps {
+		steps[i] = substituteParams(step, with)
+		if steps[i].Group == "" {
+			steps[i].Group = name
+		}
+	}
+	return r.expandSteps(steps, frag.origin)
+}
+
+// checkParams verifies that with supplies exactly the declared parameters.
+// Fragments that declare no parameters accept any with values.
+func checkParams(name string, params []string, with map[string]string) error {
+	if len(params) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		declared[p] = true
+		if _, ok := with[p]; !ok {
+			return fmt.Errorf("fragment %q: missing parameter %q", name, p)
+		}
+	}
+	for k := range with {
+		if !declared[k] {
+			return fmt.Errorf("fragment %q: unknown parameter %q", name, k)
+		}
+	}
+	return nil
+}
+
+// chain formats a cycle such as "a.yaml -> b.yaml -> a.yaml".
+func (r *includeResolver) chain(stack []string, repeat string) string {
+	parts := make([]string, 0, len(stack)+1)
+	for _, s := range append(stack, repeat) {
+		parts = append(parts, r.display(s))
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// display returns path relative to the root config directory when possible.
+func (r *includeResolver) display(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	if rel, err := filepath.Rel(r.rootDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// resolvePath resolves path relative to the directory of the file that references it.
+func resolvePath(path, from string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	return filepath.Clean(path)
+}
+
+// substituteParams returns a deep copy of step with ${param} replaced in
+// every string field, map value and slice element.
+func substituteParams(step StepConfig, params map[string]string) StepConfig {
+	if len(params) == 0 {
+		return step
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "${"+k+"}", v)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	out := reflect.New(reflect.TypeOf(step)).Elem()
+	copyReplacing(out, reflect.ValueOf(step), replacer.Replace)
+	return out.Interface().(StepConfig)
+}
+
+// resolveKeyFiles returns a deep copy of v whose jwt_sign key files are
+// relative to the directory of from instead of the working directory.
+func resolveKeyFiles[T any](v T, from string) T {
+	dir := filepath.Dir(from)
+	out := reflect.New(reflect.TypeOf(v)).Elem()
+	copyReplacing(out, reflect.ValueOf(v), func(s string) string {
+		return template.ResolveKeyFiles(s, dir)
+	})
+	return out.Interface().(T)
+}
+
+// copyReplacing deep-copies src into dst, applying replace to strings.
+func copyReplacing(dst, src reflect.Value, replace func(string) string) {
+	switch src.Kind() {
+	case reflect.String:
+		dst.SetString(replace(src.String()))
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyReplacing(dst.Field(i), src.Field(i), replace)
+			}
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			copyReplacing(v, iter.Value(), replace)
+			m.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(m)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyReplacing(s.Index(i), src.Index(i), replace)
+		}
+		dst.Set(s)
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		copyReplacing(p.Elem(), src.Elem(), replace)
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		v := reflect.New(src.Elem().Type()).Elem()
+		copyReplacing(v, src.Elem(), replace)
+		dst.Set(v)
+	default:
+		dst.Set(src)
+	}
+}
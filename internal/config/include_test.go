@@ -0,0 +1,621 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_IncludeSteps(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "common/login.yaml", `
+steps:
+  - name: "login"
+    method: POST
+    url: "https://example.com/login"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  name: "Include"
+  steps:
+    - include: "common/login.yaml"
+    - name: "profile"
+      method: GET
+      url: "https://example.com/me"
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStepNames(t, cfg.Workflow.Steps, "login", "profile")
+	if cfg.Workflow.Steps[0].Include != "" {
+		t.Error("expected include entry to be expanded")
+	}
+}
+
+func TestLoadConfig_IncludeWholeWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "teardown.yaml", `
+workflow:
+  name: "Teardown"
+  steps:
+    - name: "logout"
+      method: POST
+      url: "https://example.com/logout"
+    - name: "cleanup"
+      method: DELETE
+      url: "https://example.com/session"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  name: "Main"
+  steps:
+    - name: "work"
+      method: GET
+      url: "https://example.com/work"
+    - include: "teardown.yaml"
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStepNames(t, cfg.Workflow.Steps, "work", "logout", "cleanup")
+	if cfg.Workflow.Name != "Main" {
+		t.Errorf("expected workflow name 'Main', got %q", cfg.Workflow.Name)
+	}
+}
+
+func TestLoadConfig_NestedIncludeRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a/b/inner.yaml", `
+steps:
+  - name: "inner"
+    method: GET
+    url: "https://example.com/inner"
+`)
+	writeFile(t, dir, "a/outer.yaml", `
+steps:
+  - name: "outer"
+    method: GET
+    url: "https://example.com/outer"
+  - include: "b/inner.yaml"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - include: "a/outer.yaml"
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStepNames(t, cfg.Workflow.Steps, "outer", "inner")
+}
+
+func TestLoadConfig_UseFragmentWithParams(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.yaml", `
+fragments:
+  login:
+    params: [user, password]
+    steps:
+      - name: "login_${user}"
+        method: POST
+        url: "https://example.com/login"
+        headers:
+          X-User: "${user}"
+        body: '{"user": "${user}", "pass": "${password}", "token": "${token}"}'
+
+workflow:
+  steps:
+    - use: login
+      with:
+        user: alice
+        password: secret
+    - use: login
+      with:
+        user: bob
+        password: hunter2
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStepNames(t, cfg.Workflow.Steps, "login_alice", "login_bob")
+
+	alice := cfg.Workflow.Steps[0]
+	if alice.Headers["X-User"] != "alice" {
+		t.Errorf("expected X-User header 'alice', got %q", alice.Headers["X-User"])
+	}
+	// Non-parameter placeholders are left for runtime substitution
+	expected := `{"user": "alice", "pass": "secret", "token": "${token}"}`
+	if alice.Body != expected {
+		t.Errorf("expected body %q, got %q", expected, alice.Body)
+	}
+
+	// Each expansion must get its own copy of the headers map
+	if cfg.Workflow.Steps[1].Headers["X-User"] != "bob" {
+		t.Errorf("expected X-User header 'bob', got %q", cfg.Workflow.Steps[1].Headers["X-User"])
+	}
+}
+
+func TestLoadConfig_FragmentStepsDefaultGroup(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.yaml", `
+fragments:
+  auth:
+    steps:
+      - name: "login"
+        method: POST
+        url: "https://example.com/login"
+      - name: "refresh"
+        method: POST
+        url: "https://example.com/refresh"
+        group: "tokens"
+workflow:
+  steps:
+    - use: auth
+    - name: "profile"
+      method: GET
+      url: "https://example.com/me"
+      tags:
+        tier: critical
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	steps := cfg.Workflow.Steps
+	if steps[0].Group != "auth" {
+		t.Errorf("expected group 'auth', got %q", steps[0].Group)
+	}
+	if steps[1].Group != "tokens" {
+		t.Errorf("expected explicit group 'tokens' to be kept, got %q", steps[1].Group)
+	}
+	if steps[2].Group != "" || steps[2].Tags["tier"] != "critical" {
+		t.Errorf("unexpected group/tags for profile: %q %v", steps[2].Group, steps[2].Tags)
+	}
+}
+
+func TestLoadConfig_FragmentsFromIncludedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "fragments/auth.yaml", `
+fragments:
+  login:
+    steps:
+      - name: "login"
+        method: POST
+        url: "https://example.com/login"
+      - include: "../steps/verify.yaml"
+`)
+	writeFile(t, dir, "steps/verify.yaml", `
+steps:
+  - name: "verify"
+    method: GET
+    url: "https://example.com/verify"
+`)
+	writeFile(t, dir, "config.yaml", `
+include:
+  - "fragments/auth.yaml"
+workflow:
+  steps:
+    - use: login
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStepNames(t, cfg.Workflow.Steps, "login", "verify")
+}
+
+func TestLoadConfig_IncludeSameFileTwice(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "ping.yaml", `
+fragments:
+  noop:
+    steps: []
+steps:
+  - name: "ping"
+    method: GET
+    url: "https://example.com/ping"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - include: "ping.yaml"
+    - include: "ping.yaml"
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStepNames(t, cfg.Workflow.Steps, "ping", "ping")
+}
+
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"a.yaml":      "steps:\n  - include: b.yaml\n",
+				"b.yaml":      "steps:\n  - include: a.yaml\n",
+				"config.yaml": "workflow:\n  steps:\n    - include: a.yaml\n",
+			},
+			wantErr: "include cycle: config.yaml -> a.yaml -> b.yaml -> a.yaml",
+		},
+		{
+			name: "self include",
+			files: map[string]string{
+				"config.yaml": "workflow:\n  steps:\n    - include: config.yaml\n",
+			},
+			wantErr: "include cycle: config.yaml -> config.yaml",
+		},
+		{
+			name: "top-level include cycle",
+			files: map[string]string{
+				"a.yaml":      "include: [b.yaml]\n",
+				"b.yaml":      "include: [a.yaml]\n",
+				"config.yaml": "include: [a.yaml]\n",
+			},
+			wantErr: "include cycle: config.yaml -> a.yaml -> b.yaml -> a.yaml",
+		},
+		{
+			name: "fragment cycle",
+			files: map[string]string{
+				"config.yaml": `
+fragments:
+  a:
+    steps:
+      - use: b
+  b:
+    steps:
+      - use: a
+workflow:
+  steps:
+    - use: a
+`,
+			},
+			wantErr: "fragment cycle: a -> b -> a",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"config.yaml": "workflow:\n  steps:\n    - include: missing.yaml\n",
+			},
+			wantErr: "reading included file",
+		},
+		{
+			name: "unknown fragment",
+			files: map[string]string{
+				"config.yaml": "workflow:\n  steps:\n    - use: nope\n",
+			},
+			wantErr: `unknown fragment "nope"`,
+		},
+		{
+			name: "missing parameter",
+			files: map[string]string{
+				"config.yaml": `
+fragments:
+  login:
+    params: [user]
+    steps: []
+workflow:
+  steps:
+    - use: login
+`,
+			},
+			wantErr: `missing parameter "user"`,
+		},
+		{
+			name: "unknown parameter",
+			files: map[string]string{
+				"config.yaml": `
+fragments:
+  login:
+    params: [user]
+    steps: []
+workflow:
+  steps:
+    - use: login
+      with:
+        user: a
+        typo: b
+`,
+			},
+			wantErr: `unknown parameter "typo"`,
+		},
+		{
+			name: "duplicate fragment",
+			files: map[string]string{
+				"other.yaml": "fragments:\n  login:\n    steps: []\n",
+				"config.yaml": `
+include: [other.yaml]
+fragments:
+  login:
+    steps: []
+`,
+			},
+			wantErr: `fragment "login" defined in both`,
+		},
+		{
+			name: "include and use",
+			files: map[string]string{
+				"config.yaml": "workflow:\n  steps:\n    - include: a.yaml\n      use: b\n",
+			},
+			wantErr: "mutually exclusive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, dir, name, content)
+			}
+
+			_, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_JWTKeyFilesRelativeToConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "keys/hs.key", "s3cr3t")
+	writeFile(t, dir, "shared/keys/partner.key", "partner")
+	writeFile(t, dir, "shared/steps.yaml", `
+steps:
+  - name: "partner"
+    method: GET
+    url: "https://example.com/partner"
+    headers:
+      Authorization: "Bearer ${jwt_sign(HS256, keys/partner.key, sub='p')}"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  defaults:
+    headers:
+      Authorization: "Bearer ${jwt_sign(HS256, keys/hs.key, sub=user_id)}"
+  steps:
+    - name: "me"
+      method: GET
+      url: "https://example.com/me"
+    - include: "shared/steps.yaml"
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Bearer ${jwt_sign(HS256, '" + filepath.Join(dir, "keys", "hs.key") + "', sub=user_id)}"
+	if got := cfg.Workflow.Defaults.Headers["Authorization"]; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	want = "Bearer ${jwt_sign(HS256, '" + filepath.Join(dir, "shared", "keys", "partner.key") + "', sub='p')}"
+	if got := cfg.Workflow.Steps[1].Headers["Authorization"]; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestLoadConfig_MissingJWTKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - name: "me"
+      method: GET
+      url: "https://example.com/me"
+      headers:
+        Authorization: "Bearer ${jwt_sign(RS256, keys/missing.pem)}"
+`)
+
+	_, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "jwt_sign key file") || !strings.Contains(err.Error(), "missing.pem") {
+		t.Errorf("expected missing key file error, got %v", err)
+	}
+}
+
+func TestSubstituteParams_DoesNotMutateOriginal(t *testing.T) {
+	original := StepConfig{
+		Name:    "${x}",
+		Headers: map[string]string{"H": "${x}"},
+	}
+
+	result := substituteParams(original, map[string]string{"x": "1"})
+
+	if result.Name != "1" || result.Headers["H"] != "1" {
+		t.Errorf("expected substituted values, got %+v", result)
+	}
+	if original.Name != "${x}" || original.Headers["H"] != "${x}" {
+		t.Errorf("original step was mutated: %+v", original)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func assertStepNames(t *testing.T, steps []StepConfig, names ...string) {
+	t.Helper()
+	if len(steps) != len(names) {
+		t.Fatalf("expected %d steps, got %d", len(names), len(steps))
+	}
+	for i, name := range names {
+		if steps[i].Name != name {
+			t.Errorf("step %d: expected name %q, got %q", i, name, steps[i].Name)
+		}
+	}
+}
+
+func TestLoadConfig_SchemaPathsRelativeToDefiningFile(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"type": "object"}`
+	writeFile(t, dir, "schemas/order.json", schema)
+	writeFile(t, dir, "common/schemas/user.json", schema)
+	writeFile(t, dir, "common/user.yaml", `
+steps:
+  - name: "user"
+    method: GET
+    url: "https://example.com/user"
+    schema: "schemas/user.json"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - name: "order"
+      method: GET
+      url: "https://example.com/order"
+      schema: "schemas/order.json"
+    - include: "common/user.yaml"
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := filepath.Join(dir, "schemas/order.json"); cfg.Workflow.Steps[0].Schema != want {
+		t.Errorf("expected schema %q, got %q", want, cfg.Workflow.Steps[0].Schema)
+	}
+	if want := filepath.Join(dir, "common/schemas/user.json"); cfg.Workflow.Steps[1].Schema != want {
+		t.Errorf("expected schema %q, got %q", want, cfg.Workflow.Steps[1].Schema)
+	}
+}
+
+func TestLoadConfig_InvalidSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing schema file",
+			files:   map[string]string{},
+			wantErr: "reading schema",
+		},
+		{
+			name:    "unsupported keyword",
+			files:   map[string]string{"order.json": `{"unevaluatedProperties": false}`},
+			wantErr: `keyword "unevaluatedProperties" is not supported`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, dir, name, content)
+			}
+			writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - name: "order"
+      method: GET
+      url: "https://example.com/order"
+      schema: "order.json"
+`)
+
+			_, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_BodyFilePathsRelativeToDefiningFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "bodies/order.json", `{"id": "${order_id}"}`)
+	writeFile(t, dir, "common/files/avatar.png", "png")
+	writeFile(t, dir, "common/upload.yaml", `
+steps:
+  - name: "upload"
+    method: POST
+    url: "https://example.com/avatar"
+    multipart:
+      files:
+        - field: avatar
+          path: "files/avatar.png"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - name: "order"
+      method: POST
+      url: "https://example.com/orders"
+      bodyFile: "bodies/order.json"
+    - include: "common/upload.yaml"
+`)
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "bodies/order.json"); cfg.Workflow.Steps[0].BodyFile != want {
+		t.Errorf("expected bodyFile %q, got %q", want, cfg.Workflow.Steps[0].BodyFile)
+	}
+	if want := filepath.Join(dir, "common/files/avatar.png"); cfg.Workflow.Steps[1].Multipart.Files[0].Path != want {
+		t.Errorf("expected file path %q, got %q", want, cfg.Workflow.Steps[1].Multipart.Files[0].Path)
+	}
+}
+
+func TestLoadConfig_IncludeRejectsIgnoredKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "shared.yaml", `
+loadProfile:
+  phases: []
+workflow:
+  name: "Shared"
+  variables:
+    tenant: "acme"
+  timeout: 5s
+  steps:
+    - name: "ping"
+      method: GET
+      url: "https://example.com/ping"
+`)
+	writeFile(t, dir, "config.yaml", `
+workflow:
+  steps:
+    - include: "shared.yaml"
+`)
+
+	_, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	want := "included file shared.yaml: loadProfile, workflow.timeout, workflow.variables cannot be set in an included file"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error containing %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StatusCodes is a set of expected HTTP status codes. In YAML it accepts a
+// single entry or a list, where each entry is a code (200), a class (2xx)
+// or an inclusive range (200-204).
+type StatusCodes []StatusRange
+
+// StatusRange is an inclusive range of status codes.
+type StatusRange struct {
+	Min, Max int
+}
+
+// Match reports whether code is expected. An empty set expects any
+// status below 400.
+func (s StatusCodes) Match(code int) bool {
+	if len(s) == 0 {
+		return code < 400
+	}
+	for _, r := range s {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectsRedirect reports whether any 3xx code is explicitly expected.
+func (s StatusCodes) ExpectsRedirect() bool {
+	for _, r := range s {
+		if r.Min < 400 && r.Max >= 300 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s StatusCodes) String() string {
+	if len(s) == 0 {
+		return "< 400"
+	}
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r StatusRange) String() string {
+	switch {
+	case r.Min == r.Max:
+		return strconv.Itoa(r.Min)
+	case r.Min%100 == 0 && r.Max == r.Min+99:
+		return fmt.Sprintf("%dxx", r.Min/100)
+	default:
+		return fmt.Sprintf("%d-%d", r.Min, r.Max)
+	}
+}
+
+// UnmarshalYAML accepts `200`, `2xx`, `[200, 201]` or `[2xx, 304]`.
+func (s *StatusCodes) UnmarshalYAML(value *yaml.Node) error {
+	var entries []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		entries = []string{value.Value}
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: expected status code, got %s", item.Line, describeNode(item))
+			}
+			entries = append(entries, item.Value)
+		}
+	default:
+		return fmt.Errorf("line %d: expected status code or list of status codes", value.Line)
+	}
+
+	codes := make(StatusCodes, 0, len(entries))
+	for _, entry := range entries {
+		r, err := parseStatusRange(entry)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", value.Line, err)
+		}
+		codes = append(codes, r)
+	}
+	*s = codes
+	return nil
+}
+
+func parseStatusRange(s string) (StatusRange, error) {
+	s = strings.TrimSpace(s)
+	var r StatusRange
+	switch {
+	case len(s) == 3 && strings.EqualFold(s[1:], "xx"):
+		class, err := strconv.Atoi(s[:1])
+		if err != nil {
+			return r, fmt.Errorf("invalid status class %q", s)
+		}
+		r = StatusRange{Min: class * 100, Max: class*100 + 99}
+	case strings.Contains(s, "-"):
+		lo, hi, _ := strings.Cut(s, "-")
+		min, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		max, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || min > max {
+			return r, fmt.Errorf("invalid status range %q", s)
+		}
+		r = StatusRange{Min: min, Max: max}
+	default:
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return r, fmt.Errorf("invalid status code %q", s)
+		}
+		r = StatusRange{Min: code, Max: code}
+	}
+	if r.Min < 100 || r.Max > 599 {
+		return r, fmt.Errorf("status %q out of range 100-599", s)
+	}
+	return r, nil
+}
+
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "list"
+	}
+	return "value"
+}
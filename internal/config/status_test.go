@@ -0,0 +1,123 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStatusCodes_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"200", "200"},
+		{"2xx", "2xx"},
+		{"[200, 201]", "200, 201"},
+		{"[2XX, 304]", "2xx, 304"},
+		{"200-204", "200-204"},
+		{"[404]", "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var codes StatusCodes
+			if err := yaml.Unmarshal([]byte(tt.input), &codes); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if codes.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, codes.String())
+			}
+		})
+	}
+}
+
+func TestStatusCodes_UnmarshalYAMLErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{"ok", `invalid status code "ok"`},
+		{"[200, 9xx]", "out of range"},
+		{"axx", `invalid status class "axx"`},
+		{"300-200", `invalid status range "300-200"`},
+		{"{a: 1}", "expected status code"},
+		{"[[200]]", "got list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var codes StatusCodes
+			err := yaml.Unmarshal([]byte(tt.input), &codes)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestStatusCodes_Match(t *testing.T) {
+	tests := []struct {
+		codes StatusCodes
+		code  int
+		want  bool
+	}{
+		{nil, 200, true},
+		{nil, 302, true},
+		{nil, 404, false},
+		{StatusCodes{{200, 200}, {201, 201}}, 201, true},
+		{StatusCodes{{200, 299}}, 302, false},
+		{StatusCodes{{404, 404}}, 404, true},
+		{StatusCodes{{404, 404}}, 200, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.codes.Match(tt.code); got != tt.want {
+			t.Errorf("%v.Match(%d): expected %v, got %v", tt.codes, tt.code, tt.want, got)
+		}
+	}
+}
+
+func TestStatusCodes_ExpectsRedirect(t *testing.T) {
+	if (StatusCodes{{200, 299}}).ExpectsRedirect() {
+		t.Error("2xx should not expect a redirect")
+	}
+	if !(StatusCodes{{302, 302}}).ExpectsRedirect() {
+		t.Error("302 should expect a redirect")
+	}
+	if !(StatusCodes{{200, 399}}).ExpectsRedirect() {
+		t.Error("200-399 should expect a redirect")
+	}
+}
+
+func TestLoadConfig_ExpectStatus(t *testing.T) {
+	content := `
+workflow:
+  expect_status: 2xx
+  steps:
+    - name: "missing"
+      method: GET
+      url: "https://example.com/missing"
+      expect_status: [404]
+    - name: "login"
+      method: GET
+      url: "https://example.com/login"
+      expect_status: [200, 302]
+      follow_redirects: true
+`
+	cfg := loadConfigFromString(t, content)
+
+	if cfg.Workflow.Defaults.Expect.String() != "2xx" {
+		t.Errorf("expected workflow expect_status 2xx in defaults, got %q", cfg.Workflow.Defaults.Expect)
+	}
+	if cfg.Workflow.Steps[0].Expect.String() != "404" {
+		t.Errorf("expected step expect_status 404, got %q", cfg.Workflow.Steps[0].Expect)
+	}
+	if f := cfg.Workflow.Steps[1].FollowRedirects; f == nil || !*f {
+		t.Error("expected follow_redirects true")
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"maestro/internal/schema"
+	"maestro/internal/template"
+)
+
+// validate reports configuration mistakes that would otherwise only
+// surface as failures at runtime.
+func (c *Config) validate() error {
+	var errs []error
+	if err := c.HTTP.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("http: %w", err))
+	}
+	if err := c.Workflow.Defaults.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("defaults: %w", err))
+	}
+	for i, step := range c.Workflow.Steps {
+		for j, check := range step.Checks {
+			if err := check.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("step %d (%s): check %d: %w", i+1, step.Name, j+1, err))
+			}
+		}
+		for _, name := range sortedNames(step.Extract) {
+			if err := step.Extract[name].validate(); err != nil {
+				errs = append(errs, fmt.Errorf("step %d (%s): extract %q: %w", i+1, step.Name, name, err))
+			}
+		}
+		if step.Schema != "" {
+			if _, err := schema.Load(step.Schema); err != nil {
+				errs = append(errs, fmt.Errorf("step %d (%s): %w", i+1, step.Name, err))
+			}
+		}
+		if err := step.validateTemplates(); err != nil {
+			errs = append(errs, fmt.Errorf("step %d (%s): %w", i+1, step.Name, err))
+		}
+		if err := step.validateBody(); err != nil {
+			errs = append(errs, fmt.Errorf("step %d (%s): %w", i+1, step.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateTemplates compiles the step's URL, body, header, query and
+// cookie templates to report syntax errors such as unknown functions or filters.
+func (s StepConfig) validateTemplates() error {
+	var errs []error
+	check := func(field, text string) {
+		if err := checkTemplate(text); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+	check("url", s.URL)
+	check("body", s.Body)
+	check("baseURL", s.BaseURL)
+	for _, name := range sortedNames(s.Headers) {
+		check(fmt.Sprintf("header %q", name), s.Headers[name])
+	}
+	for _, name := range sortedNames(s.Query) {
+		check(fmt.Sprintf("query %q", name), s.Query[name])
+	}
+	for _, name := range sortedNames(s.Cookies) {
+		check(fmt.Sprintf("cookie %q", name), s.Cookies[name])
+	}
+	return errors.Join(errs...)
+}
+
+// checkTemplate compiles text and checks that the key files its jwt_sign
+// calls read exist.
+func checkTemplate(text string) error {
+	if _, err := template.Compile(text); err != nil {
+		return err
+	}
+	for _, path := range template.KeyFiles(text) {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("jwt_sign key file: %w", err)
+		}
+	}
+	return nil
+}
+
+// validate compiles the default base URL, header and query templates.
+func (d DefaultsConfig) validate() error {
+	return StepConfig{BaseURL: d.BaseURL, Headers: d.Headers, Query: d.Query}.validateTemplates()
+}
+
+// validateBody checks that at most one body option is set, that body
+// files are readable and that their templates compile.
+func (s StepConfig) validateBody() error {
+	set := 0
+	for _, ok := range []bool{
+		s.Body != "",
+		s.BodyFile != "",
+		s.Form != nil,
+		s.Multipart != nil,
+		s.JSON != nil,
+	} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return errors.New("only one of body, bodyFile, form, multipart and json may be set")
+	}
+
+	var errs []error
+	check := func(field, text string) {
+		if err := checkTemplate(text); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+	switch {
+	case s.BodyFile != "":
+		data, err := os.ReadFile(s.BodyFile)
+		if err != nil {
+			return fmt.Errorf("bodyFile: %w", err)
+		}
+		check("bodyFile "+s.BodyFile, template.ResolveKeyFiles(string(data), filepath.Dir(s.BodyFile)))
+	case s.Form != nil:
+		for _, name := range sortedNames(s.Form) {
+			check(fmt.Sprintf("form field %q", name), s.Form[name])
+		}
+	case s.Multipart != nil:
+		for _, name := range sortedNames(s.Multipart.Fields) {
+			check(fmt.Sprintf("multipart field %q", name), s.Multipart.Fields[name])
+		}
+		for i, f := range s.Multipart.Files {
+			if err := f.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("multipart file %d: %w", i+1, err))
+				continue
+			}
+			check(fmt.Sprintf("multipart file %q: filename", f.Field), f.Filename)
+			check(fmt.Sprintf("multipart file %q: content", f.Field), f.Content)
+		}
+	case s.JSON != nil:
+		if _, err := template.CompileValue(s.JSON); err != nil {
+			errs = append(errs, fmt.Errorf("json: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f MultipartFile) validate() error {
+	if f.Field == "" {
+		return errors.New("field is required")
+	}
+	if (f.Path == "") == (f.Content == "") {
+		return fmt.Errorf("field %q: must set exactly one of path and content", f.Field)
+	}
+	if f.Path != "" {
+		if _, err := os.Stat(f.Path); err != nil {
+			return fmt.Errorf("field %q: %w", f.Field, err)
+		}
+	}
+	return nil
+}
+
+func (c CheckConfig) validate() error {
+	subjects := 0
+	for _, set := range []bool{
+		len(c.Status) > 0,
+		c.JSONPath != "",
+		c.Header != "",
+		c.BodyContains != "",
+		c.BodySize != nil,
+		c.ResponseTime > 0,
+	} {
+		if set {
+			subjects++
+		}
+	}
+	if subjects != 1 {
+		return errors.New("must set exactly one of status, jsonpath, header, bodyContains, bodySize, responseTime")
+	}
+
+	hasComparison := c.Equals != nil || c.Contains != "" || c.Matches != ""
+	if hasComparison && c.JSONPath == "" && c.Header == "" {
+		return errors.New("equals, contains and matches apply only to jsonpath and header checks")
+	}
+	if c.JSONPath != "" {
+		if _, err := template.ParseJSONPath(c.JSONPath); err != nil {
+			return err
+		}
+	}
+	if c.Matches != "" {
+		if _, err := regexp.Compile(c.Matches); err != nil {
+			return fmt.Errorf("invalid matches pattern: %w", err)
+		}
+	}
+	if c.BodySize != nil && c.BodySize.Max > 0 && c.BodySize.Min > c.BodySize.Max {
+		return fmt.Errorf("bodySize min (%d) must be <= max (%d)", c.BodySize.Min, c.BodySize.Max)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variable sources, from lowest to highest precedence.
+const (
+	SourceConfig  = "config"
+	SourceVarFile = "var-file"
+	SourceCLI     = "cli"
+)
+
+// VariableLayer is one set of workflow variable values and where it came from.
+type VariableLayer struct {
+	Source string
+	Values map[string]any
+}
+
+// ResolvedVariable is the final value of a workflow variable.
+type ResolvedVariable struct {
+	Name   string
+	Value  any
+	Source string
+}
+
+// ResolveVariables merges layers in order, later layers overriding earlier ones.
+// The result is sorted by name.
+func ResolveVariables(layers ...VariableLayer) []ResolvedVariable {
+	merged := make(map[string]ResolvedVariable)
+	for _, layer := range layers {
+		for name, value := range layer.Values {
+			merged[name] = ResolvedVariable{Name: name, Value: value, Source: layer.Source}
+		}
+	}
+
+	result := make([]ResolvedVariable, 0, len(merged))
+	for _, v := range merged {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// VariableMap converts resolved variables back into a name/value map.
+func VariableMap(resolved []ResolvedVariable) map[string]any {
+	if len(resolved) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(resolved))
+	for _, v := range resolved {
+		m[v.Name] = v.Value
+	}
+	return m
+}
+
+// LoadVarFile reads a YAML mapping of variable names to values.
+func LoadVarFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading var file: %w", err)
+	}
+
+	var vars map[string]any
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("parsing var file %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// ParseVar parses a command-line variable of the form key=value.
+func ParseVar(s string) (string, string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid variable %q (expected key=value)", s)
+	}
+	return key, value, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig_WorkflowVariables(t *testing.T) {
+	content := `
+workflow:
+  name: "Vars"
+  variables:
+    base_url: "https://dev.example.com"
+    page_size: 50
+  steps:
+    - name: "list"
+      method: GET
+      url: "${base_url}/items?size=${page_size}"
+`
+	cfg := loadConfigFromString(t, content)
+
+	if cfg.Workflow.Variables["base_url"] != "https://dev.example.com" {
+		t.Errorf("expected base_url, got %v", cfg.Workflow.Variables["base_url"])
+	}
+	if cfg.Workflow.Variables["page_size"] != 50 {
+		t.Errorf("expected page_size 50, got %v", cfg.Workflow.Variables["page_size"])
+	}
+}
+
+func TestResolveVariables_Precedence(t *testing.T) {
+	resolved := ResolveVariables(
+		VariableLayer{Source: SourceConfig, Values: map[string]any{"env": "dev", "tenant": "t1", "size": 10}},
+		VariableLayer{Source: SourceVarFile, Values: map[string]any{"env": "staging", "tenant": "t2"}},
+		VariableLayer{Source: SourceCLI, Values: map[string]any{"env": "prod"}},
+	)
+
+	expected := []ResolvedVariable{
+		{Name: "env", Value: "prod", Source: SourceCLI},
+		{Name: "size", Value: 10, Source: SourceConfig},
+		{Name: "tenant", Value: "t2", Source: SourceVarFile},
+	}
+	if len(resolved) != len(expected) {
+		t.Fatalf("expected %d variables, got %d", len(expected), len(resolved))
+	}
+	for i, exp := range expected {
+		if resolved[i] != exp {
+			t.Errorf("variable %d: expected %+v, got %+v", i, exp, resolved[i])
+		}
+	}
+
+	m := VariableMap(resolved)
+	if m["env"] != "prod" || m["tenant"] != "t2" || m["size"] != 10 {
+		t.Errorf("unexpected variable map: %v", m)
+	}
+}
+
+func TestResolveVariables_Empty(t *testing.T) {
+	resolved := ResolveVariables(VariableLayer{Source: SourceConfig})
+	if len(resolved) != 0 {
+		t.Errorf("expected no variables, got %v", resolved)
+	}
+	if VariableMap(resolved) != nil {
+		t.Error("expected nil map for no variables")
+	}
+}
+
+func TestLoadVarFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "prod.yaml", "base_url: https://api.example.com\ntenant: acme\n")
+
+	vars, err := LoadVarFile(filepath.Join(dir, "prod.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vars["base_url"] != "https://api.example.com" || vars["tenant"] != "acme" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+}
+
+func TestLoadVarFile_Errors(t *testing.T) {
+	if _, err := LoadVarFile("/nonexistent/vars.yaml"); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	dir := t.TempDir()
+	writeFile(t, dir, "bad.yaml", "- not\n- a\n- mapping\n")
+	if _, err := LoadVarFile(filepath.Join(dir, "bad.yaml")); err == nil {
+		t.Error("expected error for non-mapping var file")
+	}
+}
+
+func TestParseVar(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantKey   string
+		wantValue string
+		wantErr   bool
+	}{
+		{"env=prod", "env", "prod", false},
+		{"query=a=b", "query", "a=b", false},
+		{"empty=", "empty", "", false},
+		{"novalue", "", "", true},
+		{"=value", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			key, value, err := ParseVar(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if key != tt.wantKey || value != tt.wantValue {
+				t.Errorf("expected %q=%q, got %q=%q", tt.wantKey, tt.wantValue, key, value)
+			}
+		})
+	}
+}
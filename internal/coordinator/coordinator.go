@@ -28,27 +28,37 @@ type Coordinator struct {
 	activeCount atomic.Int32
 	stopChans   []chan struct{}
 	stopMu      sync.Mutex
+	run         *core.RunState
 }
 
 func NewCoordinator(reporter core.Reporter) *Coordinator {
 	return &Coordinator{
 		reporter: reporter,
+		run:      core.NewRunState(core.RealClock{}),
 	}
 }
 
+// RunState returns the state shared by the actors of this run.
+func (c *Coordinator) RunState() *core.RunState {
+	return c.run
+}
+
 func (c *Coordinator) Spawn(ctx context.Context, count int, workflow core.Workflow) {
+	ctx = core.ContextWithRunState(ctx, c.run)
 	for i := 0; i < count; i++ {
 		actorID := int(c.nextID.Add(1))
 		c.wg.Add(1)
 		go func(id int) {
 			defer c.wg.Done()
 			defer c.recoverPanic(id)
-			for {
+			ctx, actor := withActorState(ctx)
+			defer actor.Close()
+			for iteration := 1; ; iteration++ {
 				select {
 				case <-ctx.Done():
 					return
 				default:
-					if err := workflow.Run(ctx, id, c, c.reporter); err != nil {
+					if err := workflow.Run(core.ContextWithIteration(ctx, iteration), id, c, c.reporter); err != nil {
 						return
 					}
 				}
@@ -59,12 +69,15 @@ func (c *Coordinator) Spawn(ctx context.Context, count int, workflow core.Workfl
 
 // SpawnWithConfig spawns actors using Runner for iteration-level control.
 func (c *Coordinator) SpawnWithConfig(ctx context.Context, count int, workflow core.Workflow, config core.RunnerConfig) {
+	ctx = core.ContextWithRunState(ctx, c.run)
 	for i := 0; i < count; i++ {
 		actorID := int(c.nextID.Add(1))
 		c.wg.Add(1)
 		go func(id int) {
 			defer c.wg.Done()
 			defer c.recoverPanic(id)
+			ctx, actor := withActorState(ctx)
+			defer actor.Close()
 			runner := core.NewRunner(workflow, c.reporter, c, id, config)
 			for {
 				select {
@@ -84,6 +97,12 @@ func (c *Coordinator) SpawnWithConfig(ctx context.Context, count int, workflow c
 	}
 }
 
+// withActorState gives an actor its own state, closed when the actor stops.
+func withActorState(ctx context.Context) (context.Context, *core.ActorState) {
+	actor := core.NewActorState()
+	return core.ContextWithActorState(ctx, actor), actor
+}
+
 func (c *Coordinator) Wait() {
 	c.wg.Wait()
 }
@@ -93,6 +112,7 @@ func (c *Coordinator) ActiveActors() int {
 }
 
 func (c *Coordinator) spawnWithStop(ctx context.Context, workflow core.Workflow) chan struct{} {
+	ctx = core.ContextWithRunState(ctx, c.run)
 	stopCh := make(chan struct{})
 	actorID := int(c.nextID.Add(1))
 	c.activeCount.Add(1)
@@ -108,14 +128,16 @@ func (c *Coordinator) spawnWithStop(ctx context.Context, workflow core.Workflow)
 			c.activeCount.Add(-1)
 		}()
 		defer c.recoverPanic(id)
-		for {
+		ctx, actor := withActorState(ctx)
+		defer actor.Close()
+		for iteration := 1; ; iteration++ {
 			select {
 			case <-ctx.Done():
 				return
 			case <-stop:
 				return
 			default:
-				if err := workflow.Run(ctx, id, c, c.reporter); err != nil {
+				if err := workflow.Run(core.ContextWithIteration(ctx, iteration), id, c, c.reporter); err != nil {
 					return
 				}
 			}
@@ -126,6 +148,7 @@ func (c *Coordinator) spawnWithStop(ctx context.Context, workflow core.Workflow)
 }
 
 func (c *Coordinator) spawnWithStopConfig(ctx context.Context, workflow core.Workflow, config core.RunnerConfig) chan struct{} {
+	ctx = core.ContextWithRunState(ctx, c.run)
 	stopCh := make(chan struct{})
 	actorID := int(c.nextID.Add(1))
 	c.activeCount.Add(1)
@@ -141,6 +164,8 @@ func (c *Coordinator) spawnWithStopConfig(ctx context.Context, workflow core.Wor
 			c.activeCount.Add(-1)
 		}()
 		defer c.recoverPanic(id)
+		ctx, actor := withActorState(ctx)
+		defer actor.Close()
 		runner := core.NewRunner(workflow, c.reporter, c, id, config)
 		for {
 			select {
@@ -167,10 +192,11 @@ func (c *Coordinator) spawnWithStopConfig(ctx context.Context, workflow core.Wor
 func (c *Coordinator) recoverPanic(actorID int) {
 	if r := recover(); r != nil {
 		c.reporter.Report(core.Event{
-			ActorID: actorID,
-			Step:    "panic",
-			Success: false,
-			Error:   fmt.Sprintf("panic: %v", r),
+			ActorID:   actorID,
+			Step:      "panic",
+			Success:   false,
+			Error:     fmt.Sprintf("panic: %v", r),
+			ErrorType: core.ErrorTypePanic,
 		})
 	}
 }
@@ -236,6 +262,7 @@ func (c *Coordinator) RunWithProfileConfig(ctx context.Context, profile *config.
 				currentPhaseIdx = newPhaseIdx
 				phase := pm.CurrentPhase()
 				if phase != nil {
+					c.run.SetPhase(phase.Name)
 					if phase.RPS > 0 {
 						printMsg("Phase: %s (duration: %v, target actors: %d, rps: %d)",
 							phase.Name, phase.Duration, pm.TargetActors(), phase.RPS)
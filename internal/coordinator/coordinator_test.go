@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -584,3 +585,103 @@ func TestCoordinator_RecoversPanic(t *testing.T) {
 		t.Error("expected panic to be recovered and reported as failed event")
 	}
 }
+
+// contextWorkflow records the iteration and phase each run sees.
+type contextWorkflow struct {
+	mu         sync.Mutex
+	iterations []int
+	phases     []string
+}
+
+func (w *contextWorkflow) Run(ctx context.Context, actorID int, coord core.Coordinator, rep core.Reporter) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.iterations = append(w.iterations, core.IterationFromContext(ctx))
+	if run := core.RunStateFromContext(ctx); run != nil {
+		w.phases = append(w.phases, run.Phase())
+	}
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func TestCoordinator_IterationAndRunStateInContext(t *testing.T) {
+	coord := NewCoordinator(core.NullReporter)
+	workflow := &contextWorkflow{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	coord.Spawn(ctx, 1, workflow)
+	coord.Wait()
+
+	if len(workflow.iterations) < 2 {
+		t.Fatalf("expected several iterations, got %v", workflow.iterations)
+	}
+	for i, n := range workflow.iterations {
+		if n != i+1 {
+			t.Fatalf("expected iterations counted from 1, got %v", workflow.iterations)
+		}
+	}
+	if len(workflow.phases) != len(workflow.iterations) {
+		t.Errorf("expected run state on every iteration, got %d of %d", len(workflow.phases), len(workflow.iterations))
+	}
+}
+
+func TestCoordinator_RunWithProfile_SetsPhase(t *testing.T) {
+	coord := NewCoordinator(core.NullReporter)
+	workflow := &contextWorkflow{}
+	profile := &config.LoadProfile{Phases: []config.Phase{{Name: "steady", Duration: 200 * time.Millisecond, Actors: 1}}}
+
+	coord.RunWithProfile(context.Background(), profile, workflow, nil, nil)
+	coord.Wait()
+
+	if len(workflow.phases) == 0 || workflow.phases[0] != "steady" {
+		t.Errorf("expected phase steady, got %v", workflow.phases)
+	}
+	if coord.RunState().Phase() != "steady" {
+		t.Errorf("expected run state phase steady, got %q", coord.RunState().Phase())
+	}
+}
+
+// actorStateWorkflow counts the actor states it sees and registers a
+// cleanup on each.
+type actorStateWorkflow struct {
+	mu      sync.Mutex
+	states  map[*core.ActorState]int
+	missing int
+	closed  atomic.Int32
+}
+
+func (w *actorStateWorkflow) Run(ctx context.Context, actorID int, coord core.Coordinator, rep core.Reporter) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	actor := core.ActorStateFromContext(ctx)
+	if actor == nil {
+		w.missing++
+		return nil
+	}
+	if w.states[actor] == 0 {
+		actor.Cleanup(func() { w.closed.Add(1) })
+	}
+	w.states[actor]++
+	return nil
+}
+
+func TestCoordinator_ActorStateLivesAsLongAsTheActor(t *testing.T) {
+	coord := NewCoordinator(core.NullReporter)
+	workflow := &actorStateWorkflow{states: make(map[*core.ActorState]int)}
+
+	coord.SpawnWithConfig(context.Background(), 3, workflow, core.RunnerConfig{MaxIterations: 4})
+	coord.Wait()
+
+	if workflow.missing != 0 || len(workflow.states) != 3 {
+		t.Fatalf("expected 3 actor states, got %d (%d runs without)", len(workflow.states), workflow.missing)
+	}
+	for _, runs := range workflow.states {
+		if runs != 4 {
+			t.Errorf("expected one state for all 4 iterations of an actor, got %d runs", runs)
+		}
+	}
+	if workflow.closed.Load() != 3 {
+		t.Errorf("expected every actor state closed, got %d", workflow.closed.Load())
+	}
+}
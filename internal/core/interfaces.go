@@ -8,18 +8,38 @@ import (
 
 // Event represents a single measurement from an actor's workflow step.
 type Event struct {
-	ActorID    int
-	Timestamp  time.Time
-	Step       string
-	Protocol   string // "http", "grpc", "websocket"
-	Duration   time.Duration
-	Success    bool
-	Error      string
-	StatusCode int   // Protocol-specific status (HTTP 200, gRPC 0=OK)
-	BytesSent  int64 // Request size for throughput metrics
-	BytesRecv  int64 // Response size for throughput metrics
+	ActorID           int
+	Timestamp         time.Time
+	Step              string
+	Group             string            // Fragment or explicit group the step belongs to
+	Scenario          string            // Name of the workflow the step belongs to
+	Tags              map[string]string // User-defined step tags
+	Protocol          string            // "http", "grpc", "websocket"
+	Duration          time.Duration
+	Success           bool
+	Error             string
+	ErrorType         string // Failure category, one of the ErrorType* constants
+	StatusCode        int    // Protocol-specific status (HTTP 200, gRPC 0=OK)
+	BytesSent         int64  // Request size for throughput metrics
+	BytesRecv         int64  // Response size for throughput metrics
+	Checks            []CheckResult
+	SchemaViolations  int           // JSON Schema violations found in the response body
+	IterationDuration time.Duration // Set on the last event of a workflow iteration
 }
 
+// Failure categories for Event.ErrorType and Result.ErrorType.
+const (
+	ErrorTypeTimeout  = "timeout"  // request exceeded its timeout
+	ErrorTypeNetwork  = "network"  // connection or transport failure
+	ErrorTypeCanceled = "canceled" // test ended or was interrupted mid-request
+	ErrorTypeStatus   = "status"   // unexpected response status
+	ErrorTypeRequest  = "request"  // request could not be built (templates, URL)
+	ErrorTypeExtract  = "extract"  // variable extraction failed
+	ErrorTypeCheck    = "check"    // a response check failed
+	ErrorTypeSchema   = "schema"   // response body did not match its JSON Schema
+	ErrorTypePanic    = "panic"    // actor panicked
+)
+
 // Workflow defines a user journey that an actor executes.
 // Each workflow models a complete user journey with all its complexity.
 type Workflow interface {
@@ -59,6 +59,7 @@ func (r *Runner) RunIteration(ctx context.Context) error {
 	}
 
 	// Execute workflow
+	ctx = ContextWithIteration(ctx, r.iteration+1)
 	err := r.workflow.Run(ctx, r.actorID, r.coord, rep)
 	r.iteration++
 	return err
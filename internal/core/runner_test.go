@@ -235,6 +235,23 @@ func TestRunner_ActorIDPassedToWorkflow(t *testing.T) {
 	}
 }
 
+func TestRunner_IterationInContext(t *testing.T) {
+	var seen []int
+	workflow := &mockWorkflow{
+		runFunc: func(ctx context.Context, actorID int, coord Coordinator, rep Reporter) error {
+			seen = append(seen, IterationFromContext(ctx))
+			return nil
+		},
+	}
+	runner := NewRunner(workflow, &mockReporter{}, nil, 1, RunnerConfig{MaxIterations: 3})
+	for runner.RunIteration(context.Background()) == nil {
+	}
+
+	if len(seen) != 3 || seen[0] != 1 || seen[1] != 2 || seen[2] != 3 {
+		t.Errorf("expected iterations [1 2 3], got %v", seen)
+	}
+}
+
 func TestNullReporter(t *testing.T) {
 	// NullReporter should not panic when Report is called
 	NullReporter.Report(Event{Step: "test", Success: true})
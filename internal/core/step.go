@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,13 +14,22 @@ type Step interface {
 
 // Result represents the outcome of a step execution.
 type Result struct {
-	Duration   time.Duration
-	Success    bool
-	Error      string
-	StatusCode int
-	BytesSent  int64
-	BytesRecv  int64
-	Extract    map[string]any
+	Duration         time.Duration
+	Success          bool
+	Error            string
+	ErrorType        string
+	StatusCode       int
+	BytesSent        int64
+	BytesRecv        int64
+	Extract          map[string]any
+	Checks           []CheckResult
+	SchemaViolations int // JSON Schema violations found in the response body
+}
+
+// CheckResult is the outcome of a single response assertion.
+type CheckResult struct {
+	Name   string
+	Passed bool
 }
 
 // Variables provides shared state between steps in a workflow run.
@@ -48,10 +58,20 @@ func (v *MapVariables) Set(key string, value any) {
 	v.data[key] = value
 }
 
+// Delete removes key, so it is no longer found.
+func (v *MapVariables) Delete(key string) {
+	delete(v.data, key)
+}
+
 // Context key for passing actor ID to steps.
 type contextKey string
 
-const actorIDContextKey contextKey = "actorID"
+const (
+	actorIDContextKey   contextKey = "actorID"
+	iterationContextKey contextKey = "iteration"
+	runStateContextKey  contextKey = "runState"
+	actorContextKey     contextKey = "actor"
+)
 
 func ContextWithActorID(ctx context.Context, actorID int) context.Context {
 	return context.WithValue(ctx, actorIDContextKey, actorID)
@@ -63,3 +83,103 @@ func ActorIDFromContext(ctx context.Context) int {
 	}
 	return 0
 }
+
+// ContextWithIteration records the actor's current iteration, counted from 1.
+func ContextWithIteration(ctx context.Context, iteration int) context.Context {
+	return context.WithValue(ctx, iterationContextKey, iteration)
+}
+
+// IterationFromContext returns the actor's current iteration, or 0.
+func IterationFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(iterationContextKey).(int); ok {
+		return n
+	}
+	return 0
+}
+
+// RunState is shared by all actors of a test run: when it started and the
+// load profile phase it is in. It is safe for concurrent use.
+type RunState struct {
+	start time.Time
+	clock Clock
+	phase atomic.Value
+}
+
+// NewRunState starts a run at the clock's current time.
+func NewRunState(clock Clock) *RunState {
+	s := &RunState{start: clock.Now(), clock: clock}
+	s.phase.Store("")
+	return s
+}
+
+// Elapsed returns the time since the run started.
+func (s *RunState) Elapsed() time.Duration {
+	return s.clock.Since(s.start)
+}
+
+// Phase returns the name of the current load profile phase, or "" when the
+// run has no profile.
+func (s *RunState) Phase() string {
+	return s.phase.Load().(string)
+}
+
+// SetPhase records the phase the run has entered.
+func (s *RunState) SetPhase(name string) {
+	s.phase.Store(name)
+}
+
+func ContextWithRunState(ctx context.Context, state *RunState) context.Context {
+	return context.WithValue(ctx, runStateContextKey, state)
+}
+
+// RunStateFromContext returns the run's state, or nil outside a run.
+func RunStateFromContext(ctx context.Context) *RunState {
+	state, _ := ctx.Value(runStateContextKey).(*RunState)
+	return state
+}
+
+// ActorState holds values that live as long as one actor, such as its own
+// HTTP client. It belongs to the actor's goroutine and is not safe for
+// concurrent use.
+type ActorState struct {
+	values   map[any]any
+	cleanups []func()
+}
+
+func NewActorState() *ActorState {
+	return &ActorState{values: make(map[any]any)}
+}
+
+// Value returns the value stored under key, or nil.
+func (s *ActorState) Value(key any) any {
+	return s.values[key]
+}
+
+// SetValue stores value under key for the rest of the actor's life.
+func (s *ActorState) SetValue(key, value any) {
+	s.values[key] = value
+}
+
+// Cleanup registers fn to run when the actor stops.
+func (s *ActorState) Cleanup(fn func()) {
+	s.cleanups = append(s.cleanups, fn)
+}
+
+// Close runs the cleanup functions, last registered first.
+func (s *ActorState) Close() {
+	for i := len(s.cleanups) - 1; i >= 0; i-- {
+		s.cleanups[i]()
+	}
+	s.cleanups = nil
+}
+
+func ContextWithActorState(ctx context.Context, state *ActorState) context.Context {
+	return context.WithValue(ctx, actorContextKey, state)
+}
+
+// ActorStateFromContext returns the state of the actor running ctx, or nil
+// outside an actor.
+func ActorStateFromContext(ctx context.Context) *ActorState {
+	state, _ := ctx.Value(actorContextKey).(*ActorState)
+	return state
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 func TestMapVariables(t *testing.T) {
@@ -28,3 +29,65 @@ func TestContextWithActorID(t *testing.T) {
 		t.Errorf("expected 42, got %d", id)
 	}
 }
+
+func TestContextWithIteration(t *testing.T) {
+	ctx := context.Background()
+	if n := IterationFromContext(ctx); n != 0 {
+		t.Errorf("expected 0, got %d", n)
+	}
+	if n := IterationFromContext(ContextWithIteration(ctx, 7)); n != 7 {
+		t.Errorf("expected 7, got %d", n)
+	}
+}
+
+func TestRunState(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	state := NewRunState(clock)
+	clock.Advance(1500 * time.Millisecond)
+
+	if state.Elapsed() != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s elapsed, got %v", state.Elapsed())
+	}
+	if state.Phase() != "" {
+		t.Errorf("expected no phase, got %q", state.Phase())
+	}
+	state.SetPhase("ramp_up")
+	if state.Phase() != "ramp_up" {
+		t.Errorf("expected ramp_up, got %q", state.Phase())
+	}
+
+	if RunStateFromContext(context.Background()) != nil {
+		t.Error("expected no run state")
+	}
+	if RunStateFromContext(ContextWithRunState(context.Background(), state)) != state {
+		t.Error("expected run state from context")
+	}
+}
+
+func TestActorState(t *testing.T) {
+	state := NewActorState()
+	type key struct{}
+	if state.Value(key{}) != nil {
+		t.Error("expected no value")
+	}
+	state.SetValue(key{}, 42)
+	if state.Value(key{}) != 42 {
+		t.Errorf("expected 42, got %v", state.Value(key{}))
+	}
+
+	var order []int
+	state.Cleanup(func() { order = append(order, 1) })
+	state.Cleanup(func() { order = append(order, 2) })
+	state.Close()
+	state.Close()
+	if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+		t.Errorf("expected cleanups to run once, last first, got %v", order)
+	}
+
+	if ActorStateFromContext(context.Background()) != nil {
+		t.Error("expected no actor state")
+	}
+	if ActorStateFromContext(ContextWithActorState(context.Background(), state)) != state {
+		t.Error("expected actor state from context")
+	}
+}
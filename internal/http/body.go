@@ -0,0 +1,236 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+	"maestro/internal/template"
+)
+
+// requestBody renders a step's request body. It is compiled once in
+// NewStep from whichever of body, bodyFile, form, multipart and json the
+// step sets.
+type requestBody interface {
+	// render returns the body and the Content-Type it implies, or "" to
+	// leave the header to the step's headers.
+	render(vars core.Variables) (body, contentType string, err error)
+}
+
+// compileBody compiles the step's body option. File contents are read
+// once, here.
+func compileBody(cfg config.StepConfig) (requestBody, error) {
+	switch {
+	case cfg.BodyFile != "":
+		data, err := os.ReadFile(cfg.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("bodyFile: %w", err)
+		}
+		text := template.ResolveKeyFiles(string(data), filepath.Dir(cfg.BodyFile))
+		t, err := template.Compile(text)
+		if err != nil {
+			return nil, fmt.Errorf("bodyFile %s: %w", cfg.BodyFile, err)
+		}
+		return textBody{t}, nil
+
+	case cfg.Form != nil:
+		fields, err := compileFields("form", cfg.Form)
+		if err != nil {
+			return nil, err
+		}
+		return formBody{fields}, nil
+
+	case cfg.Multipart != nil:
+		return compileMultipart(cfg.Multipart)
+
+	case cfg.JSON != nil:
+		v, err := template.CompileValue(cfg.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		return jsonBody{v}, nil
+	}
+
+	t, err := template.Compile(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+	return textBody{t}, nil
+}
+
+// textBody is an inline or file body sent as rendered.
+type textBody struct{ t *template.Template }
+
+func (b textBody) render(vars core.Variables) (string, string, error) {
+	body, err := b.t.Execute(vars)
+	return body, "", err
+}
+
+// field is a compiled name=value pair of a form or multipart body or of
+// the query.
+type field struct {
+	name  string
+	value *template.Template
+}
+
+// compileFields compiles field values in name order, so bodies are
+// deterministic.
+func compileFields(what string, m map[string]string) ([]field, error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]field, len(names))
+	var errs []error
+	for i, name := range names {
+		t, err := template.Compile(m[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s field %q: %w", what, name, err))
+		}
+		fields[i] = field{name, t}
+	}
+	return fields, errors.Join(errs...)
+}
+
+// formBody is an application/x-www-form-urlencoded body.
+type formBody struct{ fields []field }
+
+func (b formBody) render(vars core.Variables) (string, string, error) {
+	values := make(url.Values, len(b.fields))
+	for _, f := range b.fields {
+		v, err := f.value.Execute(vars)
+		if err != nil {
+			return "", "", fmt.Errorf("form field %q: %w", f.name, err)
+		}
+		values.Set(f.name, v)
+	}
+	return values.Encode(), "application/x-www-form-urlencoded", nil
+}
+
+// filePart is a compiled multipart file. content is the file read at
+// compile time, or nil when inline holds a template.
+type filePart struct {
+	field       string
+	filename    *template.Template
+	contentType string
+	content     []byte
+	inline      *template.Template
+}
+
+// multipartBody is a multipart/form-data body of fields followed by files.
+type multipartBody struct {
+	fields []field
+	files  []filePart
+}
+
+func compileMultipart(cfg *config.MultipartConfig) (requestBody, error) {
+	fields, err := compileFields("multipart", cfg.Fields)
+	if err != nil {
+		return nil, err
+	}
+	b := multipartBody{fields: fields}
+
+	for _, f := range cfg.Files {
+		part := filePart{field: f.Field, contentType: f.ContentType}
+		name := f.Filename
+		if f.Path != "" {
+			if part.content, err = os.ReadFile(f.Path); err != nil {
+				return nil, fmt.Errorf("multipart file %q: %w", f.Field, err)
+			}
+			if name == "" {
+				name = filepath.Base(f.Path)
+			}
+		} else if part.inline, err = template.Compile(f.Content); err != nil {
+			return nil, fmt.Errorf("multipart file %q: content: %w", f.Field, err)
+		}
+		if part.filename, err = template.Compile(name); err != nil {
+			return nil, fmt.Errorf("multipart file %q: filename: %w", f.Field, err)
+		}
+		if part.contentType == "" {
+			part.contentType = mime.TypeByExtension(filepath.Ext(name))
+		}
+		if part.contentType == "" {
+			part.contentType = "application/octet-stream"
+		}
+		b.files = append(b.files, part)
+	}
+	return b, nil
+}
+
+// quoteEscaper escapes quoted Content-Disposition parameters the way
+// mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (b multipartBody) render(vars core.Variables) (string, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, f := range b.fields {
+		v, err := f.value.Execute(vars)
+		if err != nil {
+			return "", "", fmt.Errorf("multipart field %q: %w", f.name, err)
+		}
+		if err := w.WriteField(f.name, v); err != nil {
+			return "", "", err
+		}
+	}
+
+	for _, f := range b.files {
+		filename, err := f.filename.Execute(vars)
+		if err != nil {
+			return "", "", fmt.Errorf("multipart file %q: filename: %w", f.field, err)
+		}
+		content := f.content
+		if f.inline != nil {
+			text, err := f.inline.Execute(vars)
+			if err != nil {
+				return "", "", fmt.Errorf("multipart file %q: %w", f.field, err)
+			}
+			content = []byte(text)
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(f.field), quoteEscaper.Replace(filename)))
+		h.Set("Content-Type", f.contentType)
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return "", "", err
+		}
+		pw.Write(content)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), w.FormDataContentType(), nil
+}
+
+// jsonBody is a YAML value serialized as JSON.
+type jsonBody struct{ v *template.ValueTemplate }
+
+func (b jsonBody) render(vars core.Variables) (string, string, error) {
+	v, err := b.v.Render(vars)
+	if err != nil {
+		return "", "", err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", "", fmt.Errorf("encoding json body: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), "application/json", nil
+}
@@ -0,0 +1,221 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+)
+
+// captured is the request a test server received.
+type captured struct {
+	contentType string
+	body        string
+	req         *http.Request
+}
+
+func captureServer(t *testing.T, handle func(r *http.Request)) (*httptest.Server, *captured) {
+	t.Helper()
+	c := &captured{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.contentType = r.Header.Get("Content-Type")
+		if handle != nil {
+			handle(r)
+		} else {
+			body, _ := io.ReadAll(r.Body)
+			c.body = string(body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, c
+}
+
+func executeBodyStep(t *testing.T, cfg config.StepConfig, vars core.Variables) core.Result {
+	t.Helper()
+	cfg.Name = "test"
+	cfg.Method = "POST"
+	step := NewStep(cfg, &http.Client{Timeout: 5 * time.Second}, nil)
+	result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestStep_FormBody(t *testing.T) {
+	server, got := captureServer(t, nil)
+	vars := core.NewVariables()
+	vars.Set("user", "ada lovelace")
+
+	executeBodyStep(t, config.StepConfig{
+		URL:  server.URL,
+		Form: map[string]string{"username": "${user}", "next": "/home?a=1&b=2"},
+	}, vars)
+
+	if got.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("unexpected Content-Type %q", got.contentType)
+	}
+	if want := "next=%2Fhome%3Fa%3D1%26b%3D2&username=ada+lovelace"; got.body != want {
+		t.Errorf("expected body %q, got %q", want, got.body)
+	}
+}
+
+func TestStep_JSONBody(t *testing.T) {
+	server, got := captureServer(t, nil)
+	vars := core.NewVariables()
+	vars.Set("name", "<widget>")
+	vars.Set("qty", 3)
+	vars.Set("tags", []any{"a", "b"})
+
+	result := executeBodyStep(t, config.StepConfig{
+		URL: server.URL,
+		JSON: map[string]any{
+			"name":  "${name}",
+			"qty":   "${qty}",
+			"tags":  "${tags}",
+			"note":  "qty=${qty}",
+			"price": 9.5,
+		},
+	}, vars)
+
+	if got.contentType != "application/json" {
+		t.Errorf("unexpected Content-Type %q", got.contentType)
+	}
+	want := `{"name":"<widget>","note":"qty=3","price":9.5,"qty":3,"tags":["a","b"]}`
+	if got.body != want {
+		t.Errorf("expected body %s, got %s", want, got.body)
+	}
+	if result.BytesSent != int64(len(want)) {
+		t.Errorf("expected BytesSent %d, got %d", len(want), result.BytesSent)
+	}
+}
+
+func TestStep_BodyContentTypeOverride(t *testing.T) {
+	server, got := captureServer(t, nil)
+	executeBodyStep(t, config.StepConfig{
+		URL:     server.URL,
+		JSON:    map[string]any{"a": 1},
+		Headers: map[string]string{"content-type": "application/vnd.api+json"},
+	}, core.NewVariables())
+
+	if got.contentType != "application/vnd.api+json" {
+		t.Errorf("expected header to override Content-Type, got %q", got.contentType)
+	}
+}
+
+func TestStep_BodyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "order.json")
+	if err := os.WriteFile(path, []byte(`{"id": "${order_id}"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server, got := captureServer(t, nil)
+	vars := core.NewVariables()
+	vars.Set("order_id", "o-42")
+
+	executeBodyStep(t, config.StepConfig{URL: server.URL, BodyFile: path}, vars)
+
+	if want := `{"id": "o-42"}`; got.body != want {
+		t.Errorf("expected body %q, got %q", want, got.body)
+	}
+}
+
+func TestStep_MultipartBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "avatar.png")
+	if err := os.WriteFile(path, []byte("\x89PNG data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string][]string
+	type file struct{ name, contentType, content string }
+	files := map[string]file{}
+	server, got := captureServer(t, func(r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing multipart body: %v", err)
+			return
+		}
+		fields = r.MultipartForm.Value
+		for field, headers := range r.MultipartForm.File {
+			f, _ := headers[0].Open()
+			content, _ := io.ReadAll(f)
+			f.Close()
+			files[field] = file{headers[0].Filename, headers[0].Header.Get("Content-Type"), string(content)}
+		}
+	})
+
+	vars := core.NewVariables()
+	vars.Set("user", "u-7")
+	executeBodyStep(t, config.StepConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"Content-Type": "text/plain"},
+		Multipart: &config.MultipartConfig{
+			Fields: map[string]string{"owner": "${user}"},
+			Files: []config.MultipartFile{
+				{Field: "avatar", Path: path},
+				{Field: "notes", Content: "for ${user}", Filename: "${user}.txt"},
+				{Field: "blob", Content: "x", Filename: "data", ContentType: "application/x-custom"},
+			},
+		},
+	}, vars)
+
+	if !strings.HasPrefix(got.contentType, "multipart/form-data; boundary=") {
+		t.Errorf("expected multipart Content-Type to win, got %q", got.contentType)
+	}
+	if fields["owner"][0] != "u-7" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	want := map[string]file{
+		"avatar": {"avatar.png", "image/png", "\x89PNG data"},
+		"notes":  {"u-7.txt", "text/plain; charset=utf-8", "for u-7"},
+		"blob":   {"data", "application/x-custom", "x"},
+	}
+	for field, w := range want {
+		if files[field] != w {
+			t.Errorf("%s: expected %+v, got %+v", field, w, files[field])
+		}
+	}
+}
+
+func TestStep_BodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.StepConfig
+		want string
+	}{
+		{"missing file", config.StepConfig{BodyFile: "/nonexistent/body.json"}, "bodyFile:"},
+		{"missing part", config.StepConfig{Multipart: &config.MultipartConfig{
+			Files: []config.MultipartFile{{Field: "doc", Path: "/nonexistent/doc.pdf"}},
+		}}, `multipart file "doc"`},
+		{"missing variable", config.StepConfig{JSON: map[string]any{"id": "${id}"}}, `variable "id" not found`},
+	}
+	for _, tt := range tests {
+		tt.cfg.Name, tt.cfg.Method, tt.cfg.URL = "test", "POST", "http://127.0.0.1:1"
+		step := NewStep(tt.cfg, http.DefaultClient, nil)
+		result, _ := step.Execute(context.Background(), core.NewVariables())
+		if result.Success || result.ErrorType != core.ErrorTypeRequest || !strings.Contains(result.Error, tt.want) {
+			t.Errorf("%s: expected request error containing %q, got %+v", tt.name, tt.want, result)
+		}
+	}
+}
+
+func TestJSONBody_KeepsNumbers(t *testing.T) {
+	b, err := compileBody(config.StepConfig{JSON: []any{1, 2.5, "x", true, nil}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _, err := b.render(core.NewVariables())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[1,2.5,"x",true,null]`; body != want {
+		t.Errorf("expected json body %s, got %s", want, body)
+	}
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+	"maestro/internal/template"
+)
+
+// check is a compiled response assertion.
+type check struct {
+	config.CheckConfig
+	name string
+	re   *regexp.Regexp
+	path *template.JSONPath
+}
+
+// response holds what checks can inspect about a completed request.
+type response struct {
+	status   int
+	header   http.Header
+	body     []byte
+	size     int64
+	duration time.Duration
+	decoded  *decodedBody // shared by copies so the body is decoded once
+}
+
+// decodedBody caches the JSON decoding of a response body and its XML and
+// HTML parses.
+type decodedBody struct {
+	done   bool
+	doc    any
+	ok     bool
+	markup map[string]*template.Document // by format; nil if the body is not valid XML
+}
+
+// json returns the body decoded as JSON and whether it was valid.
+func (r response) json() (any, bool) {
+	if r.decoded == nil {
+		r.decoded = &decodedBody{}
+	}
+	if !r.decoded.done {
+		r.decoded.done = true
+		r.decoded.ok = json.Unmarshal(r.body, &r.decoded.doc) == nil
+	}
+	return r.decoded.doc, r.decoded.ok
+}
+
+// query evaluates a JSONPath against the decoded body.
+func (r response) query(path *template.JSONPath) (any, bool) {
+	doc, ok := r.json()
+	if !ok {
+		return nil, false
+	}
+	return path.Get(doc)
+}
+
+// document returns the body parsed as XML or HTML, and whether it parsed.
+func (r response) document(format string) (*template.Document, bool) {
+	if r.decoded == nil {
+		r.decoded = &decodedBody{}
+	}
+	doc, done := r.decoded.markup[format]
+	if !done {
+		if format == config.AsHTML {
+			doc = template.ParseHTML(r.body)
+		} else {
+			doc, _ = template.ParseXML(r.body)
+		}
+		if r.decoded.markup == nil {
+			r.decoded.markup = make(map[string]*template.Document, 1)
+		}
+		r.decoded.markup[format] = doc
+	}
+	return doc, doc != nil
+}
+
+// compileChecks prepares checks for repeated evaluation.
+// Patterns are validated at config load, so compile errors are not expected here.
+func compileChecks(cfgs []config.CheckConfig) []check {
+	checks := make([]check, len(cfgs))
+	for i, cfg := range cfgs {
+		checks[i] = check{CheckConfig: cfg, name: cfg.Name}
+		if cfg.Matches != "" {
+			checks[i].re, _ = regexp.Compile(cfg.Matches)
+		}
+		if cfg.JSONPath != "" {
+			checks[i].path, _ = template.ParseJSONPath(cfg.JSONPath)
+		}
+		if checks[i].name == "" {
+			checks[i].name = describeCheck(cfg)
+		}
+	}
+	return checks
+}
+
+// needsBody reports whether any check inspects the response body.
+func needsBody(checks []check) bool {
+	for _, c := range checks {
+		if c.JSONPath != "" || c.BodyContains != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// runChecks evaluates all checks and returns their results and the name of
+// the first failed check that affects step success ("" if none).
+func runChecks(checks []check, resp response) ([]core.CheckResult, string) {
+	if len(checks) == 0 {
+		return nil, ""
+	}
+	results := make([]core.CheckResult, len(checks))
+	failed := ""
+	for i, c := range checks {
+		passed := c.eval(resp)
+		results[i] = core.CheckResult{Name: c.name, Passed: passed}
+		if !passed && !c.Soft && failed == "" {
+			failed = c.name
+		}
+	}
+	return results, failed
+}
+
+func (c *check) eval(resp response) bool {
+	switch {
+	case len(c.Status) > 0:
+		return c.Status.Match(resp.status)
+
+	case c.JSONPath != "":
+		if c.path == nil {
+			return false
+		}
+		value, ok := resp.query(c.path)
+		if !ok {
+			return false
+		}
+		return c.compare(template.FormatValue(value))
+
+	case c.Header != "":
+		values, ok := resp.header[http.CanonicalHeaderKey(c.Header)]
+		if !ok {
+			return false
+		}
+		return c.compare(strings.Join(values, ", "))
+
+	case c.BodyContains != "":
+		return strings.Contains(string(resp.body), c.BodyContains)
+
+	case c.BodySize != nil:
+		if resp.size < c.BodySize.Min {
+			return false
+		}
+		return c.BodySize.Max == 0 || resp.size <= c.BodySize.Max
+
+	case c.ResponseTime > 0:
+		return resp.duration < c.ResponseTime
+	}
+	return false
+}
+
+// compare applies the equals/contains/matches operator to a present value.
+func (c *check) compare(value string) bool {
+	switch {
+	case c.Equals != nil:
+		return value == *c.Equals
+	case c.Contains != "":
+		return strings.Contains(value, c.Contains)
+	case c.re != nil:
+		return c.re.MatchString(value)
+	}
+	return true // presence only
+}
+
+// describeCheck derives a readable name for an unnamed check.
+func describeCheck(c config.CheckConfig) string {
+	var subject string
+	switch {
+	case len(c.Status) > 0:
+		codes := make([]string, len(c.Status))
+		for i, r := range c.Status {
+			codes[i] = r.String()
+		}
+		return "status in [" + strings.Join(codes, ",") + "]"
+	case c.JSONPath != "":
+		subject = c.JSONPath
+	case c.Header != "":
+		subject = "header " + c.Header
+	case c.BodyContains != "":
+		return fmt.Sprintf("body contains %q", c.BodyContains)
+	case c.BodySize != nil:
+		if c.BodySize.Max == 0 {
+			return fmt.Sprintf("body size >= %d", c.BodySize.Min)
+		}
+		return fmt.Sprintf("body size %d..%d", c.BodySize.Min, c.BodySize.Max)
+	case c.ResponseTime > 0:
+		return fmt.Sprintf("response time < %s", c.ResponseTime)
+	}
+
+	switch {
+	case c.Equals != nil:
+		return fmt.Sprintf("%s == %q", subject, *c.Equals)
+	case c.Contains != "":
+		return fmt.Sprintf("%s contains %q", subject, c.Contains)
+	case c.Matches != "":
+		return fmt.Sprintf("%s matches %q", subject, c.Matches)
+	}
+	return subject + " present"
+}
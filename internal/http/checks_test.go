@@ -0,0 +1,293 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestCheck_Eval(t *testing.T) {
+	resp := response{
+		status:   201,
+		header:   http.Header{"Content-Type": []string{"application/json"}, "X-Request-Id": []string{"abc-123"}},
+		body:     []byte(`{"status": "active", "id": 42, "items": [{"name": "widget"}], "total": 1e21, "tags": ["a", "b"]}`),
+		size:     60,
+		duration: 150 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.CheckConfig
+		want bool
+	}{
+		{"status match", config.CheckConfig{Status: config.StatusCodes{{Min: 200, Max: 200}, {Min: 201, Max: 201}}}, true},
+		{"status mismatch", config.CheckConfig{Status: config.StatusCodes{{Min: 200, Max: 200}}}, false},
+		{"status class", config.CheckConfig{Status: config.StatusCodes{{Min: 200, Max: 299}}}, true},
+		{"status range mismatch", config.CheckConfig{Status: config.StatusCodes{{Min: 202, Max: 204}}}, false},
+		{"jsonpath present", config.CheckConfig{JSONPath: "$.id"}, true},
+		{"jsonpath missing", config.CheckConfig{JSONPath: "$.missing"}, false},
+		{"jsonpath equals string", config.CheckConfig{JSONPath: "$.status", Equals: strPtr("active")}, true},
+		{"jsonpath equals number", config.CheckConfig{JSONPath: "$.id", Equals: strPtr("42")}, true},
+		{"jsonpath equals object", config.CheckConfig{JSONPath: "$.items[0]", Equals: strPtr(`{"name":"widget"}`)}, true},
+		{"jsonpath equals array", config.CheckConfig{JSONPath: "$.tags", Equals: strPtr(`["a","b"]`)}, true},
+		{"jsonpath equals large number", config.CheckConfig{JSONPath: "$.total", Equals: strPtr("1000000000000000000000")}, true},
+		{"jsonpath equals mismatch", config.CheckConfig{JSONPath: "$.status", Equals: strPtr("closed")}, false},
+		{"jsonpath contains", config.CheckConfig{JSONPath: "$.items[0].name", Contains: "widg"}, true},
+		{"jsonpath matches", config.CheckConfig{JSONPath: "$.id", Matches: `^\d+$`}, true},
+		{"header present", config.CheckConfig{Header: "x-request-id"}, true},
+		{"header missing", config.CheckConfig{Header: "X-Missing"}, false},
+		{"header equals", config.CheckConfig{Header: "Content-Type", Equals: strPtr("application/json")}, true},
+		{"header contains", config.CheckConfig{Header: "Content-Type", Contains: "xml"}, false},
+		{"body contains", config.CheckConfig{BodyContains: `"active"`}, true},
+		{"body contains mismatch", config.CheckConfig{BodyContains: "error"}, false},
+		{"body size in range", config.CheckConfig{BodySize: &config.SizeRange{Min: 10, Max: 100}}, true},
+		{"body size too small", config.CheckConfig{BodySize: &config.SizeRange{Min: 100}}, false},
+		{"body size too large", config.CheckConfig{BodySize: &config.SizeRange{Max: 50}}, false},
+		{"response time under", config.CheckConfig{ResponseTime: 200 * time.Millisecond}, true},
+		{"response time over", config.CheckConfig{ResponseTime: 100 * time.Millisecond}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := compileChecks([]config.CheckConfig{tt.cfg})[0]
+			if got := c.eval(resp); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheck_InvalidJSONBody(t *testing.T) {
+	c := compileChecks([]config.CheckConfig{{JSONPath: "$.id"}})[0]
+	if c.eval(response{body: []byte("not json")}) {
+		t.Error("expected jsonpath check to fail on invalid JSON")
+	}
+}
+
+func TestDescribeCheck(t *testing.T) {
+	tests := []struct {
+		cfg  config.CheckConfig
+		want string
+	}{
+		{config.CheckConfig{Status: config.StatusCodes{{Min: 200, Max: 200}, {Min: 201, Max: 201}}}, "status in [200,201]"},
+		{config.CheckConfig{Status: config.StatusCodes{{Min: 200, Max: 299}, {Min: 304, Max: 304}}}, "status in [2xx,304]"},
+		{config.CheckConfig{JSONPath: "$.token"}, "$.token present"},
+		{config.CheckConfig{JSONPath: "$.status", Equals: strPtr("ok")}, `$.status == "ok"`},
+		{config.CheckConfig{Header: "Location", Contains: "/login"}, `header Location contains "/login"`},
+		{config.CheckConfig{BodyContains: "ok"}, `body contains "ok"`},
+		{config.CheckConfig{BodySize: &config.SizeRange{Min: 1, Max: 10}}, "body size 1..10"},
+		{config.CheckConfig{ResponseTime: 200 * time.Millisecond}, "response time < 200ms"},
+		{config.CheckConfig{Name: "custom", Status: config.StatusCodes{{Min: 200, Max: 200}}}, "custom"},
+	}
+
+	for _, tt := range tests {
+		c := compileChecks([]config.CheckConfig{tt.cfg})[0]
+		if c.name != tt.want {
+			t.Errorf("expected name %q, got %q", tt.want, c.name)
+		}
+	}
+}
+
+func TestStep_Checks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "degraded"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		checks      []config.CheckConfig
+		wantSuccess bool
+		wantPassed  []bool
+	}{
+		{
+			name: "all pass",
+			checks: []config.CheckConfig{
+				{Status: config.StatusCodes{{Min: 200, Max: 200}}},
+				{Header: "Content-Type", Contains: "json"},
+			},
+			wantSuccess: true,
+			wantPassed:  []bool{true, true},
+		},
+		{
+			name: "hard check fails step",
+			checks: []config.CheckConfig{
+				{Status: config.StatusCodes{{Min: 200, Max: 200}}},
+				{Name: "healthy", JSONPath: "$.status", Equals: strPtr("ok")},
+			},
+			wantSuccess: false,
+			wantPassed:  []bool{true, false},
+		},
+		{
+			name: "soft check does not fail step",
+			checks: []config.CheckConfig{
+				{Name: "healthy", JSONPath: "$.status", Equals: strPtr("ok"), Soft: true},
+			},
+			wantSuccess: true,
+			wantPassed:  []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := NewStep(
+				config.StepConfig{Name: "test", Method: "GET", URL: server.URL, Checks: tt.checks},
+				&http.Client{Timeout: 5 * time.Second},
+				nil,
+			)
+
+			result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("expected success=%v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if !tt.wantSuccess {
+				if result.ErrorType != core.ErrorTypeCheck || result.Error != "check failed: healthy" {
+					t.Errorf("unexpected error %q (%s)", result.Error, result.ErrorType)
+				}
+			}
+			if len(result.Checks) != len(tt.wantPassed) {
+				t.Fatalf("expected %d check results, got %d", len(tt.wantPassed), len(result.Checks))
+			}
+			for i, want := range tt.wantPassed {
+				if result.Checks[i].Passed != want {
+					t.Errorf("check %d (%s): expected passed=%v", i, result.Checks[i].Name, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStep_BodySizeCheckCountsUnreadBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2048))
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{
+			Name:   "test",
+			Method: "GET",
+			URL:    server.URL,
+			Checks: []config.CheckConfig{{BodySize: &config.SizeRange{Min: 2048, Max: 2048}}},
+		},
+		&http.Client{Timeout: 5 * time.Second},
+		nil,
+	)
+
+	result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("expected body size check to pass, got %q", result.Error)
+	}
+}
+
+func TestStep_Schema(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "order.json")
+	err := os.WriteFile(schemaPath, []byte(`{
+		"type": "object",
+		"required": ["id", "status"],
+		"properties": {"id": {"type": "integer"}, "status": {"type": "string"}}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		wantSuccess    bool
+		wantErrType    string
+		wantViolations int
+	}{
+		{"valid body", 200, `{"id": 1, "status": "paid"}`, true, "", 0},
+		{"null field", 200, `{"id": null, "status": "paid"}`, false, core.ErrorTypeSchema, 1},
+		{"several violations", 200, `{"id": "1"}`, false, core.ErrorTypeSchema, 2},
+		{"error status not validated", 500, `oops`, false, core.ErrorTypeStatus, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			step := NewStep(
+				config.StepConfig{Name: "order", Method: "GET", URL: server.URL, Schema: schemaPath},
+				&http.Client{Timeout: 5 * time.Second},
+				nil,
+			)
+
+			result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("expected success=%v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if result.ErrorType != tt.wantErrType {
+				t.Errorf("expected error type %q, got %q", tt.wantErrType, result.ErrorType)
+			}
+			if result.SchemaViolations != tt.wantViolations {
+				t.Errorf("expected %d violations, got %d", tt.wantViolations, result.SchemaViolations)
+			}
+		})
+	}
+}
+
+func TestStep_SchemaViolationError(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "s.json")
+	if err := os.WriteFile(schemaPath, []byte(`{"required": ["a", "b"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{Name: "s", Method: "GET", URL: server.URL, Schema: schemaPath},
+		&http.Client{Timeout: 5 * time.Second},
+		nil,
+	)
+	result, _ := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+
+	expected := `schema violation: $: missing required property "a" (and 1 more)`
+	if result.Error != expected {
+		t.Errorf("expected error %q, got %q", expected, result.Error)
+	}
+}
+
+func TestStep_SchemaLoadError(t *testing.T) {
+	step := NewStep(
+		config.StepConfig{Name: "s", Method: "GET", URL: "http://localhost", Schema: "/nonexistent/schema.json"},
+		&http.Client{},
+		nil,
+	)
+
+	result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+	if err == nil {
+		t.Fatal("expected error for unreadable schema")
+	}
+	if result.ErrorType != core.ErrorTypeSchema {
+		t.Errorf("expected error type %q, got %q", core.ErrorTypeSchema, result.ErrorType)
+	}
+}
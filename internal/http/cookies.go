@@ -0,0 +1,172 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"maestro/internal/core"
+)
+
+// varCookiePrefix names the variables that hold the jar's cookies:
+// ${cookie.SESSIONID}.
+const varCookiePrefix = "cookie."
+
+// cookieJar is a cookie jar that also remembers what it holds, so its
+// cookies can be exposed as variables and logged. The standard jar only
+// answers which cookies to send to a given URL.
+type cookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[cookieID]*http.Cookie
+	changed bool
+	vars    map[string]bool // cookie names setVariables last set
+}
+
+// cookieID identifies a stored cookie the way the jar does.
+type cookieID struct {
+	name, domain, path string
+}
+
+func newCookieJar() *cookieJar {
+	jar, _ := cookiejar.New(nil) // never fails without options
+	return &cookieJar{jar: jar, cookies: make(map[cookieID]*http.Cookie)}
+}
+
+// SetCookies stores cookies in the standard jar and remembers those it
+// accepted. Cookies it rejects, such as one for a foreign domain, are
+// never sent, so they are not remembered either.
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		id := cookieID{name: c.Name, domain: strings.TrimPrefix(c.Domain, "."), path: c.Path}
+		if id.domain == "" {
+			id.domain = u.Hostname()
+		}
+		if !strings.HasPrefix(id.path, "/") {
+			id.path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)):
+			if _, ok := j.cookies[id]; !ok || j.accepted(id, "") {
+				continue // rejected, so the stored cookie is still sent
+			}
+			delete(j.cookies, id)
+		case j.accepted(id, c.Value):
+			stored := *c
+			stored.Domain, stored.Path = id.domain, id.path
+			if c.MaxAge > 0 {
+				// Max-Age wins over Expires, as in the standard jar
+				stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			}
+			j.cookies[id] = &stored
+		default:
+			continue
+		}
+		j.changed = true
+	}
+}
+
+// accepted reports whether the standard jar holds the cookie id with
+// value, asking for it the way a request to its domain and path would.
+// An empty value matches any value.
+func (j *cookieJar) accepted(id cookieID, value string) bool {
+	u := &url.URL{Scheme: "https", Host: id.domain, Path: id.path}
+	for _, c := range j.jar.Cookies(u) {
+		if c.Name == id.name && (value == "" || c.Value == value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// all returns the stored cookies that have not expired, ordered by name,
+// domain and path.
+func (j *cookieJar) all() []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	cookies := make([]*http.Cookie, 0, len(j.cookies))
+	for id, c := range j.cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			delete(j.cookies, id)
+			continue
+		}
+		cookies = append(cookies, c)
+	}
+	sort.Slice(cookies, func(a, b int) bool {
+		ca, cb := cookies[a], cookies[b]
+		if ca.Name != cb.Name {
+			return ca.Name < cb.Name
+		}
+		if ca.Domain != cb.Domain {
+			return ca.Domain < cb.Domain
+		}
+		return ca.Path < cb.Path
+	})
+	return cookies
+}
+
+// takeChanged reports whether cookies were set since the last call.
+func (j *cookieJar) takeChanged() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	changed := j.changed
+	j.changed = false
+	return changed
+}
+
+// setVariables sets ${cookie.NAME} for every cookie in the jar and removes
+// the variables of cookies that were deleted or have expired since. When
+// several domains or paths set the same name, the last in order wins.
+func (j *cookieJar) setVariables(vars *core.MapVariables) {
+	names := make(map[string]bool)
+	for _, c := range j.all() {
+		vars.Set(varCookiePrefix+c.Name, c.Value)
+		names[c.Name] = true
+	}
+
+	j.mu.Lock()
+	previous := j.vars
+	j.vars = names
+	j.mu.Unlock()
+	for name := range previous {
+		if !names[name] {
+			vars.Delete(varCookiePrefix + name)
+		}
+	}
+}
+
+// defaultCookiePath is the path of a cookie set without one: the
+// directory of the request path (RFC 6265, section 5.1.4).
+func defaultCookiePath(p string) string {
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// describeCookie formats a cookie for verbose output. The value is
+// redacted, as LogVariables redacts every ${cookie.NAME}.
+func describeCookie(c *http.Cookie) string {
+	s := fmt.Sprintf("%s=%s (%s%s)", c.Name, redacted, c.Domain, c.Path)
+	if !c.Expires.IsZero() {
+		s += " expires " + c.Expires.UTC().Format(time.RFC3339)
+	}
+	return s
+}
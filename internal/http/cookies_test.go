@@ -0,0 +1,200 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+)
+
+// sessionServer sets a session cookie on /login and records the Cookie
+// header of every request as "path: cookies".
+func sessionServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.Path+": "+r.Header.Get("Cookie"))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Value: "s-" + r.URL.Query().Get("user"), Path: "/"})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Path: "/", MaxAge: -1})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func cookieWorkflow(server *httptest.Server, scope string, steps ...config.StepConfig) *Workflow {
+	clients, _ := NewClientFactory(config.HTTPConfig{Cookies: scope})
+	for i := range steps {
+		steps[i].Method = "GET"
+		steps[i].URL = server.URL + steps[i].URL
+	}
+	return &Workflow{
+		Config:  config.WorkflowConfig{Steps: steps},
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		Clients: clients,
+	}
+}
+
+func TestWorkflow_CookieScopes(t *testing.T) {
+	tests := []struct {
+		scope string
+		want  []string
+	}{
+		{config.CookiesIteration, []string{
+			"/login: ", "/me: SESSIONID=s-1",
+			"/login: ", "/me: SESSIONID=s-1",
+		}},
+		{config.CookiesActor, []string{
+			"/login: ", "/me: SESSIONID=s-1",
+			"/login: SESSIONID=s-1", "/me: SESSIONID=s-1",
+		}},
+		{config.CookiesDisabled, []string{
+			"/login: ", "/me: ",
+			"/login: ", "/me: ",
+		}},
+	}
+	for _, tt := range tests {
+		server, seen := sessionServer(t)
+		workflow := cookieWorkflow(server, tt.scope,
+			config.StepConfig{Name: "login", URL: "/login?user=1"},
+			config.StepConfig{Name: "me", URL: "/me"},
+		)
+		runActors(t, workflow, 1, 2)
+
+		if got := seen(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: expected %q, got %q", tt.scope, tt.want, got)
+		}
+	}
+}
+
+func TestWorkflow_CookieVariablesAndStepCookies(t *testing.T) {
+	server, seen := sessionServer(t)
+	workflow := cookieWorkflow(server, config.CookiesIteration,
+		config.StepConfig{Name: "login", URL: "/login?user=7"},
+		config.StepConfig{
+			Name:    "echo",
+			URL:     "/echo",
+			Headers: map[string]string{"X-Session": "${cookie.SESSIONID}"},
+			Cookies: map[string]string{"theme": "dark", "actor": "${__actor_id}"},
+		},
+		config.StepConfig{Name: "logout", URL: "/logout"},
+		config.StepConfig{
+			Name:    "after",
+			URL:     "/after",
+			Headers: map[string]string{"X-Session": "${cookie.SESSIONID | default: 'gone'}"},
+		},
+	)
+
+	var buf bytes.Buffer
+	workflow.Debug = NewDebugLogger(&buf)
+	runActors(t, workflow, 1, 1)
+
+	got := seen()
+	if want := "/echo: actor=1; theme=dark; SESSIONID=s-7"; got[1] != want {
+		t.Errorf("expected %q, got %q", want, got[1])
+	}
+	if got[3] != "/after: " {
+		t.Errorf("expected the expired cookie to be dropped, got %q", got[3])
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"[Actor 1] --- COOKIES: login (1 in jar)",
+		"  SESSIONID=****** (127.0.0.1/)",
+		"[Actor 1] --- COOKIES: logout (0 in jar)",
+		"X-Session: s-7",
+		"X-Session: gone", // the variable went with the cookie
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected verbose output containing %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "  SESSIONID=s-7 (") {
+		t.Errorf("expected the jar's cookie values to be redacted, got:\n%s", out)
+	}
+	if strings.Contains(out, "COOKIES: echo") {
+		t.Errorf("expected jar logged only when it changes, got:\n%s", out)
+	}
+}
+
+func TestCookieJar(t *testing.T) {
+	jar := newCookieJar()
+	u, _ := url.Parse("https://shop.example.com/cart/items")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "b", Value: "2"},
+		{Name: "a", Value: "1", Domain: "example.com", Path: "/"},
+		{Name: "old", Value: "x", Expires: time.Now().Add(-time.Hour)},
+		{Name: "foreign", Value: "f", Domain: "other.test"},
+	})
+	if !jar.takeChanged() || jar.takeChanged() {
+		t.Error("expected one change to be reported once")
+	}
+
+	var names []string
+	for _, c := range jar.all() {
+		names = append(names, describeCookie(c))
+	}
+	want := []string{"a=****** (example.com/)", "b=****** (shop.example.com/cart)"}
+	if strings.Join(names, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, names)
+	}
+	if sent := jar.Cookies(u); len(sent) != 2 {
+		t.Errorf("expected 2 cookies sent to %s, got %v", u, sent)
+	}
+
+	vars := core.NewVariables()
+	jar.setVariables(vars)
+	if v, _ := vars.Get("cookie.a"); v != "1" {
+		t.Errorf("expected cookie.a = 1, got %v", v)
+	}
+	if v, ok := vars.Get("cookie.foreign"); ok {
+		t.Errorf("expected the rejected cookie to have no variable, got %v", v)
+	}
+
+	// A deletion for another domain is rejected too and changes nothing
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Domain: "other.test", MaxAge: -1}})
+	if jar.takeChanged() || len(jar.all()) != 2 {
+		t.Errorf("expected the foreign deletion to be ignored, got %v", jar.all())
+	}
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Domain: "example.com", Path: "/", MaxAge: -1}})
+	jar.setVariables(vars)
+	if v, ok := vars.Get("cookie.a"); ok {
+		t.Errorf("expected cookie.a to be removed with the cookie, got %v", v)
+	}
+	if v, _ := vars.Get("cookie.b"); v != "2" {
+		t.Errorf("expected cookie.b = 2, got %v", v)
+	}
+
+	// Max-Age expires the cookie like Expires does
+	jar.SetCookies(u, []*http.Cookie{{Name: "short", Value: "s", MaxAge: 1}})
+	jar.setVariables(vars)
+	if v, _ := vars.Get("cookie.short"); v != "s" {
+		t.Errorf("expected cookie.short = s, got %v", v)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	jar.setVariables(vars)
+	if v, ok := vars.Get("cookie.short"); ok || len(jar.all()) != 1 {
+		t.Errorf("expected the Max-Age cookie to expire, got %v and %v", v, jar.all())
+	}
+	if sent := jar.Cookies(u); len(sent) != 1 {
+		t.Errorf("expected 1 cookie sent to %s, got %v", u, sent)
+	}
+}
@@ -8,9 +8,18 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/schema"
+)
+
+const (
+	maxBodyLogSize = 1024
+	redacted       = "******"
 )
 
-const maxBodyLogSize = 1024
+// secretNameParts mark variable names whose values are redacted in logs.
+var secretNameParts = []string{"password", "passwd", "secret", "token", "key", "auth", "credential", "private", "cookie", "session"}
 
 type DebugLogger struct {
 	out io.Writer
@@ -83,6 +92,73 @@ func (d *DebugLogger) LogError(actorID int, stepName string, errMsg string, dura
 		actorID, stepName, duration.Round(time.Millisecond), errMsg)
 }
 
+// LogSchemaViolations prints every location where a response body did not
+// match the step's JSON Schema.
+func (d *DebugLogger) LogSchemaViolations(actorID int, stepName string, violations []schema.Violation) {
+	if d == nil || len(violations) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("[Actor %d] !!! SCHEMA: %s (%d violations)\n", actorID, stepName, len(violations)))
+	for _, v := range violations {
+		buf.WriteString(fmt.Sprintf("  %s\n", v))
+	}
+	fmt.Fprint(d.out, buf.String())
+}
+
+// LogCookies prints the contents of an actor's cookie jar after a step
+// changed it.
+func (d *DebugLogger) LogCookies(actorID int, stepName string, cookies []*http.Cookie) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("[Actor %d] --- COOKIES: %s (%d in jar)\n", actorID, stepName, len(cookies)))
+	for _, c := range cookies {
+		buf.WriteString(fmt.Sprintf("  %s\n", describeCookie(c)))
+	}
+	fmt.Fprint(d.out, buf.String())
+}
+
+// LogVariables prints the resolved workflow variables and where each came from.
+// Values of variables whose names look like secrets are redacted.
+func (d *DebugLogger) LogVariables(vars []config.ResolvedVariable) {
+	if d == nil || len(vars) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("Variables (precedence: %s > %s > %s):\n",
+		config.SourceCLI, config.SourceVarFile, config.SourceConfig))
+	for _, v := range vars {
+		value := fmt.Sprintf("%v", v.Value)
+		if isSecretName(v.Name) {
+			value = redacted
+		}
+		buf.WriteString(fmt.Sprintf("  %s = %s (%s)\n", v.Name, value, v.Source))
+	}
+	fmt.Fprint(d.out, buf.String())
+}
+
+// isSecretName reports whether a variable name suggests a sensitive value.
+func isSecretName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range secretNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
 func truncateBody(body []byte) string {
 	if len(body) <= maxBodyLogSize {
 		return string(body)
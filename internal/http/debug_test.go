@@ -6,6 +6,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/schema"
 )
 
 func TestDebugLogger_LogRequest(t *testing.T) {
@@ -107,6 +110,28 @@ func TestDebugLogger_LogError(t *testing.T) {
 	}
 }
 
+func TestDebugLogger_LogSchemaViolations(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDebugLogger(&buf)
+
+	logger.LogSchemaViolations(2, "get_order", []schema.Violation{
+		{Path: "$.id", Message: "expected integer, got null"},
+		{Path: "$.items[1]", Message: `missing required property "sku"`},
+	})
+
+	output := buf.String()
+
+	if !strings.Contains(output, "[Actor 2] !!! SCHEMA: get_order (2 violations)") {
+		t.Errorf("expected schema header in output, got: %s", output)
+	}
+	if !strings.Contains(output, "$.id: expected integer, got null") {
+		t.Errorf("expected violation path in output, got: %s", output)
+	}
+	if !strings.Contains(output, `$.items[1]: missing required property "sku"`) {
+		t.Errorf("expected nested violation path in output, got: %s", output)
+	}
+}
+
 func TestDebugLogger_TruncatesLongBodies(t *testing.T) {
 	var buf bytes.Buffer
 	logger := NewDebugLogger(&buf)
@@ -168,3 +193,30 @@ func TestDebugLogger_ResponseWithNoBody(t *testing.T) {
 		t.Errorf("expected status code in output, got: %s", output)
 	}
 }
+
+func TestDebugLogger_LogVariables_RedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDebugLogger(&buf)
+
+	logger.LogVariables([]config.ResolvedVariable{
+		{Name: "api_token", Value: "s3cr3t", Source: config.SourceCLI},
+		{Name: "base_url", Value: "https://dev.example.com", Source: config.SourceConfig},
+		{Name: "DB_PASSWORD", Value: "hunter2", Source: config.SourceVarFile},
+	})
+
+	output := buf.String()
+	if !strings.Contains(output, "base_url = https://dev.example.com (config)") {
+		t.Errorf("expected base_url with source, got: %s", output)
+	}
+	if !strings.Contains(output, "api_token = ****** (cli)") {
+		t.Errorf("expected redacted api_token, got: %s", output)
+	}
+	if strings.Contains(output, "s3cr3t") || strings.Contains(output, "hunter2") {
+		t.Errorf("secret value leaked: %s", output)
+	}
+}
+
+func TestDebugLogger_LogVariables_Nil(t *testing.T) {
+	var logger *DebugLogger
+	logger.LogVariables([]config.ResolvedVariable{{Name: "a", Value: 1}})
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"maestro/internal/config"
+	"maestro/internal/template"
+)
+
+// extractor is a compiled extraction rule for one variable.
+type extractor struct {
+	config.ExtractConfig
+	name     string
+	kind     string
+	arg      string
+	re       *regexp.Regexp
+	path     *template.JSONPath
+	xpath    *template.XPath
+	selector *template.Selector
+}
+
+// compileExtractors prepares extraction rules in variable name order.
+// Patterns are validated at config load, so compile errors are not expected here.
+func compileExtractors(cfgs map[string]config.ExtractConfig) []extractor {
+	names := make([]string, 0, len(cfgs))
+	for name := range cfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	extractors := make([]extractor, len(names))
+	for i, name := range names {
+		cfg := cfgs[name]
+		kind, arg := cfg.Source()
+		extractors[i] = extractor{ExtractConfig: cfg, name: name, kind: kind, arg: arg}
+		switch kind {
+		case config.ExtractRegex:
+			extractors[i].re, _ = regexp.Compile(arg)
+		case config.ExtractJSONPath:
+			extractors[i].path, _ = template.ParseJSONPath(arg)
+		case config.ExtractXPath:
+			extractors[i].xpath, _ = template.ParseXPath(arg)
+		case config.ExtractCSS:
+			extractors[i].selector, _ = template.ParseSelector(arg)
+		}
+	}
+	return extractors
+}
+
+// extractNeedsBody reports whether any rule reads the response body.
+func extractNeedsBody(extractors []extractor) bool {
+	for _, e := range extractors {
+		switch e.kind {
+		case config.ExtractJSONPath, config.ExtractRegex, config.ExtractBody, config.ExtractXPath, config.ExtractCSS:
+			return true
+		}
+	}
+	return false
+}
+
+// runExtractors evaluates all rules. Missing values take their default,
+// are skipped when optional, and are otherwise reported together.
+func runExtractors(extractors []extractor, resp response) (map[string]any, error) {
+	if len(extractors) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]any, len(extractors))
+	var errs []error
+	for _, e := range extractors {
+		value, ok := e.eval(resp)
+		switch {
+		case ok:
+			result[e.name] = value
+		case e.Default != nil:
+			result[e.name] = e.Default
+		case !e.Optional:
+			errs = append(errs, fmt.Errorf("%s not found for variable %q", e.describe(), e.name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+func (e *extractor) eval(resp response) (any, bool) {
+	switch e.kind {
+	case config.ExtractStatus:
+		return resp.status, true
+
+	case config.ExtractBody:
+		return string(resp.body), true
+
+	case config.ExtractHeader:
+		values := resp.header.Values(e.arg)
+		if len(values) == 0 {
+			return nil, false
+		}
+		return values[0], true
+
+	case config.ExtractCookie:
+		cookies := (&http.Response{Header: resp.header}).Cookies()
+		for _, c := range cookies {
+			if c.Name == e.arg {
+				return c.Value, true
+			}
+		}
+		return nil, false
+
+	case config.ExtractRegex:
+		match := e.re.FindSubmatch(resp.body)
+		if match == nil {
+			return nil, false
+		}
+		return string(match[1]), true
+
+	case config.ExtractXPath, config.ExtractCSS:
+		doc, ok := resp.document(e.format(resp))
+		if !ok {
+			return nil, false
+		}
+		if e.xpath != nil {
+			return e.xpath.Select(doc)
+		}
+		return e.selector.Select(doc)
+	}
+	if e.path == nil {
+		return nil, false
+	}
+	return resp.query(e.path)
+}
+
+// format picks the parser for an xpath or css rule: the rule's as
+// override, then the Content-Type, then a look at the start of the body.
+// Bodies that give no hint are XML for xpath and HTML for css.
+func (e *extractor) format(resp response) string {
+	if e.As != "" {
+		return e.As
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.header.Get("Content-Type"))
+	switch {
+	case strings.Contains(mediaType, "html"):
+		return config.AsHTML
+	case strings.Contains(mediaType, "xml"):
+		return config.AsXML
+	}
+
+	head := bytes.ToLower(bytes.TrimSpace(resp.body[:min(len(resp.body), 512)]))
+	switch {
+	case bytes.HasPrefix(head, []byte("<!doctype html")), bytes.HasPrefix(head, []byte("<html")):
+		return config.AsHTML
+	case bytes.HasPrefix(head, []byte("<?xml")):
+		return config.AsXML
+	}
+	if e.kind == config.ExtractCSS {
+		return config.AsHTML
+	}
+	return config.AsXML
+}
+
+// describe names the rule's source for error messages.
+func (e *extractor) describe() string {
+	switch e.kind {
+	case config.ExtractHeader, config.ExtractCookie:
+		return fmt.Sprintf("%s %q", e.kind, e.arg)
+	case config.ExtractRegex:
+		return fmt.Sprintf("regex %q match", e.arg)
+	case config.ExtractXPath:
+		return fmt.Sprintf("xpath %q", e.arg)
+	case config.ExtractCSS:
+		return fmt.Sprintf("css selector %q", e.arg)
+	}
+	return fmt.Sprintf("path %q", e.arg)
+}
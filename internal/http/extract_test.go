@@ -0,0 +1,184 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+)
+
+func TestExtractor_Eval(t *testing.T) {
+	resp := response{
+		status: 302,
+		header: http.Header{
+			"Location":   []string{"/welcome?code=xyz"},
+			"Set-Cookie": []string{"SESSIONID=s3cr3t; Path=/; HttpOnly", "theme=dark"},
+		},
+		body: []byte(`<form><input type="hidden" name="csrf" value="tok-42"></form>`),
+	}
+
+	tests := []struct {
+		from   string
+		want   any
+		wantOK bool
+	}{
+		{"status", 302, true},
+		{"body", string(resp.body), true},
+		{"header:Location", "/welcome?code=xyz", true},
+		{"header:location", "/welcome?code=xyz", true},
+		{"header:X-Missing", nil, false},
+		{"cookie:SESSIONID", "s3cr3t", true},
+		{"cookie:theme", "dark", true},
+		{"cookie:missing", nil, false},
+		{`regex:name="csrf" value="([^"]+)"`, "tok-42", true},
+		{`regex:name="other" value="([^"]+)"`, nil, false},
+		{"$.id", nil, false}, // not JSON
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			e := compileExtractors(map[string]config.ExtractConfig{"v": {From: tt.from}})[0]
+			got, ok := e.eval(resp)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestRunExtractors_MissingValues(t *testing.T) {
+	resp := response{status: 200, body: []byte(`{"id": 7}`)}
+	extractors := compileExtractors(map[string]config.ExtractConfig{
+		"id":       {From: "$.id"},
+		"page":     {From: "$.page", Default: 1},
+		"cursor":   {From: "$.cursor", Optional: true},
+		"redirect": {From: "header:Location", Optional: true, Default: "/"},
+	})
+
+	got, err := runExtractors(extractors, resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["id"] != float64(7) || got["page"] != 1 || got["redirect"] != "/" {
+		t.Errorf("unexpected values %v", got)
+	}
+	if _, ok := got["cursor"]; ok {
+		t.Errorf("optional missing value should not be set, got %v", got["cursor"])
+	}
+
+	extractors = compileExtractors(map[string]config.ExtractConfig{
+		"token":   {From: "header:X-Token"},
+		"session": {From: "cookie:SID"},
+	})
+	_, err = runExtractors(extractors, resp)
+	if err == nil {
+		t.Fatal("expected error for required values")
+	}
+	for _, want := range []string{`cookie "SID" not found for variable "session"`, `header "X-Token" not found for variable "token"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err.Error())
+		}
+	}
+}
+
+func TestExtractor_Markup(t *testing.T) {
+	soap := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<s:Body><LoginResponse><Token>x-1</Token></LoginResponse></s:Body></s:Envelope>`
+	page := `<html><body><form><input type="hidden" name="csrf" value="tok-42"><p>Welcome<p>Back</form></body></html>`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		rule        config.ExtractConfig
+		want        any
+	}{
+		{"xpath on xml", "text/xml; charset=utf-8", soap, config.ExtractConfig{From: "xpath://Body/LoginResponse/Token"}, "x-1"},
+		{"xpath on soap+xml", "application/soap+xml", soap, config.ExtractConfig{From: "xpath:count(//Token)"}, float64(1)},
+		{"xpath on html", "text/html", page, config.ExtractConfig{From: "xpath://input[@name='csrf']/@value"}, "tok-42"},
+		{"css on html", "text/html", page, config.ExtractConfig{From: "css:input[name=csrf]@value"}, "tok-42"},
+		{"css on xml", "application/xml", soap, config.ExtractConfig{From: "css:LoginResponse > Token"}, "x-1"},
+		{"html sniffed", "", "<!DOCTYPE html>" + page, config.ExtractConfig{From: "xpath://p[2]"}, "Back"},
+		{"xml sniffed", "", soap, config.ExtractConfig{From: "css:Token"}, "x-1"},
+		{"css defaults to html", "", page, config.ExtractConfig{From: "css:p"}, "Welcome"},
+		{"override", "text/plain", page, config.ExtractConfig{From: "xpath://p[2]", As: config.AsHTML}, "Back"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := response{
+				header:  http.Header{"Content-Type": []string{tt.contentType}},
+				body:    []byte(tt.body),
+				decoded: &decodedBody{},
+			}
+			got, err := runExtractors(compileExtractors(map[string]config.ExtractConfig{"v": tt.rule}), resp)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got["v"] != tt.want {
+				t.Errorf("expected %#v, got %#v", tt.want, got["v"])
+			}
+		})
+	}
+}
+
+func TestExtractor_MarkupNotFound(t *testing.T) {
+	resp := response{
+		header:  http.Header{"Content-Type": []string{"application/xml"}},
+		body:    []byte(`<a><b>unclosed</a>`),
+		decoded: &decodedBody{},
+	}
+	extractors := compileExtractors(map[string]config.ExtractConfig{
+		"broken":  {From: "xpath://b"},
+		"lenient": {From: "xpath://b", As: config.AsHTML},
+	})
+	got, err := runExtractors(extractors, resp)
+	if err == nil || !strings.Contains(err.Error(), `xpath "//b" not found for variable "broken"`) {
+		t.Fatalf("expected not found error for invalid XML, got %v", err)
+	}
+	if got != nil {
+		t.Errorf("expected no values, got %v", got)
+	}
+
+	got, err = runExtractors(extractors[1:], resp)
+	if err != nil || got["lenient"] != "unclosed" {
+		t.Errorf("expected the HTML parser to recover, got %v, %v", got, err)
+	}
+}
+
+func TestStep_ExtractFromRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Value: "abc"})
+		w.Header().Set("Location", "/next")
+		w.WriteHeader(http.StatusFound)
+	}))
+	defer server.Close()
+
+	step := NewStep(config.StepConfig{
+		Name:   "login",
+		Method: "POST",
+		URL:    server.URL,
+		Expect: config.StatusCodes{{Min: 302, Max: 302}},
+		Extract: map[string]config.ExtractConfig{
+			"next":    {From: "header:Location"},
+			"session": {From: "cookie:SESSIONID"},
+			"code":    {From: "status"},
+		},
+	}, &http.Client{Timeout: 5 * time.Second}, nil)
+
+	result, err := step.Execute(context.Background(), core.NewVariables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Error)
+	}
+	if result.Extract["next"] != "/next" || result.Extract["session"] != "abc" || result.Extract["code"] != 302 {
+		t.Errorf("unexpected extracted values %v", result.Extract)
+	}
+}
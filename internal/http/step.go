@@ -2,13 +2,17 @@ package http
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
 	"maestro/internal/config"
 	"maestro/internal/core"
+	"maestro/internal/schema"
 	"maestro/internal/template"
 )
 
@@ -21,17 +25,97 @@ const (
 )
 
 type Step struct {
-	config config.StepConfig
-	client *http.Client
-	debug  *DebugLogger
+	config  config.StepConfig
+	client  *http.Client
+	debug   *DebugLogger
+	checks  []check
+	extract []extractor
+	schema  *schema.Schema
+	// schemaErr is set when the schema file could not be loaded. Config
+	// validation normally catches this before any step is built.
+	schemaErr error
+
+	// The URL, body, headers, query and cookies are compiled once.
+	// templateErr holds their syntax errors and unreadable body files,
+	// which config validation also reports at load time.
+	url         *template.Template
+	baseURL     *template.Template
+	query       []field
+	cookies     []field
+	body        requestBody
+	headers     map[string]*template.Template
+	templateErr error
 }
 
 func NewStep(cfg config.StepConfig, client *http.Client, debug *DebugLogger) *Step {
-	return &Step{
-		config: cfg,
-		client: client,
-		debug:  debug,
+	s := &Step{
+		config:  cfg,
+		client:  client,
+		debug:   debug,
+		checks:  compileChecks(cfg.Checks),
+		extract: compileExtractors(cfg.Extract),
+	}
+	if cfg.Schema != "" {
+		s.schema, s.schemaErr = schema.Load(cfg.Schema)
+	}
+	s.compileTemplates()
+	if !followRedirects(cfg) {
+		noFollow := *client
+		noFollow.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		s.client = &noFollow
 	}
+	return s
+}
+
+// compileTemplates parses the URL, body, header, query and cookie
+// templates and reads body files.
+func (s *Step) compileTemplates() {
+	var errs []error
+	compile := func(field, text string) *template.Template {
+		t, err := template.Compile(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		return t
+	}
+	s.url = compile("url", s.config.URL)
+	if s.config.BaseURL != "" {
+		s.baseURL = compile("baseURL", s.config.BaseURL)
+	}
+	query, err := compileFields("query", s.config.Query)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	s.query = query
+	cookies, err := compileFields("cookie", s.config.Cookies)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	s.cookies = cookies
+	body, err := compileBody(s.config)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	s.body = body
+	s.headers = make(map[string]*template.Template, len(s.config.Headers))
+	for name, text := range s.config.Headers {
+		s.headers[name] = compile(fmt.Sprintf("header %q", name), text)
+	}
+	s.templateErr = errors.Join(errs...)
+}
+
+// clientFor returns the client of the actor running ctx, with the step's
+// redirect policy, or the shared client.
+func (s *Step) clientFor(ctx context.Context) *http.Client {
+	actor := clientFromContext(ctx)
+	if actor == nil {
+		return s.client
+	}
+	client := *s.client
+	client.Transport, client.Jar = actor.Transport, actor.Jar
+	return &client
 }
 
 func (s *Step) Name() string {
@@ -42,92 +126,146 @@ func (s *Step) Execute(ctx context.Context, vars core.Variables) (core.Result, e
 	actorID := core.ActorIDFromContext(ctx)
 	start := time.Now()
 
-	// Substitute variables in URL
-	url, err := template.Substitute(s.config.URL, vars)
-	if err != nil {
-		duration := time.Since(start)
-		s.debug.LogError(actorID, s.config.Name, err.Error(), duration)
-		return core.Result{
-			Duration: duration,
-			Success:  false,
-			Error:    err.Error(),
-		}, err
+	if s.schemaErr != nil {
+		return s.fail(actorID, start, core.ErrorTypeSchema, s.schemaErr)
+	}
+	if s.templateErr != nil {
+		return s.fail(actorID, start, core.ErrorTypeRequest, s.templateErr)
 	}
 
-	// Substitute variables in body
-	body, err := template.Substitute(s.config.Body, vars)
+	// Per-request timeout covers connecting, sending and reading the body.
+	// The parent context is kept to tell timeouts from test shutdown.
+	parent := ctx
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	// Substitute variables in URL, then apply the base URL and query
+	url, err := s.requestURL(vars)
 	if err != nil {
-		duration := time.Since(start)
-		s.debug.LogError(actorID, s.config.Name, err.Error(), duration)
-		return core.Result{
-			Duration: duration,
-			Success:  false,
-			Error:    err.Error(),
-		}, err
+		return s.fail(actorID, start, core.ErrorTypeRequest, err)
 	}
 
-	// Substitute variables in headers
-	headers, err := template.SubstituteMap(s.config.Headers, vars)
+	// Substitute variables in body
+	body, contentType, err := s.body.render(vars)
 	if err != nil {
-		duration := time.Since(start)
-		s.debug.LogError(actorID, s.config.Name, err.Error(), duration)
-		return core.Result{
-			Duration: duration,
-			Success:  false,
-			Error:    err.Error(),
-		}, err
+		return s.fail(actorID, start, core.ErrorTypeRequest, err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, s.config.Method, url, strings.NewReader(body))
 	if err != nil {
-		duration := time.Since(start)
-		s.debug.LogError(actorID, s.config.Name, err.Error(), duration)
-		return core.Result{
-			Duration: duration,
-			Success:  false,
-			Error:    err.Error(),
-		}, err
+		return s.fail(actorID, start, core.ErrorTypeRequest, err)
+	}
+	// The body's Content-Type is a default the step's headers can override
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	// Substitute variables in headers, which can sign the resolved request
+	reqVars := newRequestVars(vars, req, body)
+	var headerErrs []error
+	for name, t := range s.headers {
+		value, err := t.Execute(reqVars)
+		if err != nil {
+			headerErrs = append(headerErrs, fmt.Errorf("header %q: %w", name, err))
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if err := errors.Join(headerErrs...); err != nil {
+		return s.fail(actorID, start, core.ErrorTypeRequest, err)
+	}
+	// A multipart Content-Type carries the boundary, so it always wins
+	if _, ok := s.body.(multipartBody); ok {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	// Step cookies are sent besides those the jar adds
+	for _, c := range s.cookies {
+		value, err := c.value.Execute(vars)
+		if err != nil {
+			return s.fail(actorID, start, core.ErrorTypeRequest, fmt.Errorf("cookie %q: %w", c.name, err))
+		}
+		req.AddCookie(&http.Cookie{Name: c.name, Value: value})
 	}
 
 	s.debug.LogRequest(actorID, s.config.Name, req)
 
-	resp, err := s.client.Do(req)
+	resp, err := s.clientFor(ctx).Do(req)
 	duration := time.Since(start)
 
 	if err != nil {
+		if s.isTimeout(parent, err) {
+			return s.timeout(actorID, duration), nil
+		}
+		errType := core.ErrorTypeNetwork
+		if parent.Err() != nil {
+			errType = core.ErrorTypeCanceled
+		}
 		s.debug.LogError(actorID, s.config.Name, err.Error(), duration)
 		return core.Result{
-			Duration: duration,
-			Success:  false,
-			Error:    err.Error(),
+			Duration:  duration,
+			Success:   false,
+			Error:     err.Error(),
+			ErrorType: errType,
 		}, err
 	}
 	defer resp.Body.Close()
 
-	// Read body if needed for debug OR extraction
-	needsExtract := len(s.config.Extract) > 0
+	// Read body if needed for debug, extraction or checks
+	needsFullBody := extractNeedsBody(s.extract) || needsBody(s.checks) || s.schema != nil
 	needsDebug := s.debug != nil
 	var respBody []byte
-	if needsExtract || needsDebug {
+	var drained int64
+	var readErr error
+	if needsFullBody || needsDebug {
 		// Use larger limit when extraction is needed
 		limit := int64(maxDebugBodySize)
-		if needsExtract {
+		if needsFullBody {
 			limit = maxExtractBodySize
 		}
-		respBody, _ = io.ReadAll(io.LimitReader(resp.Body, limit))
-		_, _ = io.Copy(io.Discard, resp.Body) // drain remaining body
+		respBody, readErr = io.ReadAll(io.LimitReader(resp.Body, limit))
+		if readErr == nil {
+			drained, readErr = io.Copy(io.Discard, resp.Body) // drain remaining body
+		}
 	} else {
-		_, _ = io.Copy(io.Discard, resp.Body) // drain errors are ignorable
+		drained, readErr = io.Copy(io.Discard, resp.Body)
 	}
 
-	success := resp.StatusCode < 400
+	// A body cut short by the timeout is a timeout; other drain errors are ignorable
+	if readErr != nil && s.isTimeout(parent, readErr) {
+		result := s.timeout(actorID, time.Since(start))
+		result.StatusCode = resp.StatusCode
+		return result, nil
+	}
+
+	statusOK := s.config.Expect.Match(resp.StatusCode)
+	success := statusOK
 	errStr := ""
+	errType := ""
 	if !success {
 		errStr = resp.Status
+		if len(s.config.Expect) > 0 {
+			errStr = fmt.Sprintf("unexpected status %s (expected %s)", resp.Status, s.config.Expect)
+		}
+		errType = core.ErrorTypeStatus
+	}
+
+	inspected := response{
+		status:   resp.StatusCode,
+		header:   resp.Header,
+		body:     respBody,
+		size:     int64(len(respBody)) + drained,
+		duration: duration,
+		decoded:  &decodedBody{},
+	}
+	checks, failedCheck := runChecks(s.checks, inspected)
+	if success && failedCheck != "" {
+		success = false
+		errStr = "check failed: " + failedCheck
+		errType = core.ErrorTypeCheck
 	}
 
 	// For debug logging, truncate body if needed
@@ -137,23 +275,117 @@ func (s *Step) Execute(ctx context.Context, vars core.Variables) (core.Result, e
 	}
 	s.debug.LogResponse(actorID, s.config.Name, resp, debugBody, duration)
 
+	// Validate the contract of expected responses only; error bodies
+	// usually follow a different shape.
+	var violations []schema.Violation
+	if statusOK && s.schema != nil {
+		violations = s.schema.ValidateJSON(respBody)
+		s.debug.LogSchemaViolations(actorID, s.config.Name, violations)
+		if success && len(violations) > 0 {
+			success = false
+			errStr = "schema violation: " + violations[0].String()
+			if len(violations) > 1 {
+				errStr += fmt.Sprintf(" (and %d more)", len(violations)-1)
+			}
+			errType = core.ErrorTypeSchema
+		}
+	}
+
 	// Extract variables from response (if extract rules defined and request succeeded)
 	var extracted map[string]any
-	if success && len(s.config.Extract) > 0 {
-		extracted, err = template.Extract(respBody, s.config.Extract)
+	if success && len(s.extract) > 0 {
+		extracted, err = runExtractors(s.extract, inspected)
 		if err != nil {
 			success = false
 			errStr = err.Error()
+			errType = core.ErrorTypeExtract
 		}
 	}
 
 	return core.Result{
-		Duration:   duration,
-		Success:    success,
-		Error:      errStr,
-		StatusCode: resp.StatusCode,
-		BytesSent:  int64(len(body)),
-		BytesRecv:  int64(len(respBody)),
-		Extract:    extracted,
+		Duration:         duration,
+		Success:          success,
+		Error:            errStr,
+		ErrorType:        errType,
+		StatusCode:       resp.StatusCode,
+		BytesSent:        int64(len(body)),
+		BytesRecv:        int64(len(respBody)) + drained,
+		Extract:          extracted,
+		Checks:           checks,
+		SchemaViolations: len(violations),
 	}, nil
 }
+
+// followRedirects reports whether the step should follow redirects. Steps
+// expecting a 3xx status need to see the redirect itself.
+func followRedirects(cfg config.StepConfig) bool {
+	if cfg.FollowRedirects != nil {
+		return *cfg.FollowRedirects
+	}
+	return !cfg.Expect.ExpectsRedirect()
+}
+
+// fail builds the result for a step that failed before a response was received.
+func (s *Step) fail(actorID int, start time.Time, errType string, err error) (core.Result, error) {
+	duration := time.Since(start)
+	s.debug.LogError(actorID, s.config.Name, err.Error(), duration)
+	return core.Result{
+		Duration:  duration,
+		Success:   false,
+		Error:     err.Error(),
+		ErrorType: errType,
+	}, err
+}
+
+// isTimeout reports whether err was caused by the step's own timeout (or the
+// client's), as opposed to the parent context ending the test.
+func (s *Step) isTimeout(parent context.Context, err error) bool {
+	if parent.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// timeout builds the result for a timed-out request. Timeouts are expected
+// under load, so they are reported as failed events without stopping the actor.
+func (s *Step) timeout(actorID int, duration time.Duration) core.Result {
+	msg := "request timed out"
+	if s.config.Timeout > 0 {
+		msg = fmt.Sprintf("request timed out after %s", s.config.Timeout)
+	}
+	s.debug.LogError(actorID, s.config.Name, msg, duration)
+	return core.Result{
+		Duration:  duration,
+		Success:   false,
+		Error:     msg,
+		ErrorType: core.ErrorTypeTimeout,
+	}
+}
+
+// requestVars exposes the resolved request to header templates as
+// request.body, request.method, request.url and request.path, so a header
+// can sign exactly what is sent: ${hmac_sha256(env:SECRET, request.body)}.
+type requestVars struct {
+	core.Variables
+	request map[string]any
+}
+
+func newRequestVars(vars core.Variables, req *http.Request, body string) requestVars {
+	return requestVars{Variables: vars, request: map[string]any{
+		"request.body":   body,
+		"request.method": req.Method,
+		"request.url":    req.URL.String(),
+		"request.path":   req.URL.RequestURI(),
+	}}
+}
+
+func (v requestVars) Get(key string) (any, bool) {
+	if val, ok := v.request[key]; ok {
+		return val, true
+	}
+	return v.Variables.Get(key)
+}
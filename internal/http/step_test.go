@@ -2,6 +2,10 @@ package http
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -203,6 +207,51 @@ func TestStep_Headers(t *testing.T) {
 	}
 }
 
+func TestStep_SignedHeaders(t *testing.T) {
+	const secret = "s3cr3t"
+	var valid bool
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n"))
+		mac.Write(body)
+		valid = hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(r.Header.Get("X-Signature")))
+		path = r.Header.Get("X-Path")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{
+			Name:   "signed",
+			Method: "POST",
+			URL:    server.URL + "/orders?id=${order_id}",
+			Body:   `{"order": ${order_id}, "note": "a, b"}`,
+			Headers: map[string]string{
+				"X-Signature": `${hmac_sha256(key, request.method + "\n" + request.path + "\n" + request.body)}`,
+				"X-Path":      "${request.path}",
+			},
+		},
+		&http.Client{Timeout: 5 * time.Second},
+		nil,
+	)
+
+	vars := core.NewVariables()
+	vars.Set("key", secret)
+	vars.Set("order_id", 42)
+	result, err := step.Execute(context.Background(), vars)
+	if err != nil || !result.Success {
+		t.Fatalf("unexpected failure: %v %s", err, result.Error)
+	}
+	if !valid {
+		t.Error("expected the signature to cover the resolved request")
+	}
+	if path != "/orders?id=42" {
+		t.Errorf("unexpected request.path %q", path)
+	}
+}
+
 func TestStep_ContextCancellation(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(5 * time.Second) // Slow response
@@ -330,8 +379,8 @@ func TestStep_LargeBodyExtraction(t *testing.T) {
 			Name:   "test",
 			Method: "GET",
 			URL:    server.URL,
-			Extract: map[string]string{
-				"result": "$.target",
+			Extract: map[string]config.ExtractConfig{
+				"result": {From: "$.target"},
 			},
 		},
 		&http.Client{Timeout: 5 * time.Second},
@@ -354,3 +403,194 @@ func TestStep_LargeBodyExtraction(t *testing.T) {
 		t.Errorf("expected 'extracted_value', got %v", result.Extract["result"])
 	}
 }
+
+func TestStep_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{Name: "slow", Method: "GET", URL: server.URL, Timeout: 50 * time.Millisecond},
+		&http.Client{},
+		nil,
+	)
+
+	ctx := core.ContextWithActorID(context.Background(), 1)
+	result, err := step.Execute(ctx, core.NewVariables())
+
+	// Timeouts are recorded as failures but do not stop the actor
+	if err != nil {
+		t.Fatalf("expected no error for timeout, got %v", err)
+	}
+	if result.Success {
+		t.Error("expected failure for timeout")
+	}
+	if result.ErrorType != core.ErrorTypeTimeout {
+		t.Errorf("expected error type %q, got %q", core.ErrorTypeTimeout, result.ErrorType)
+	}
+	if result.Error != "request timed out after 50ms" {
+		t.Errorf("unexpected error message: %q", result.Error)
+	}
+	if result.Duration > time.Second {
+		t.Errorf("expected request to be cut short, took %v", result.Duration)
+	}
+}
+
+func TestStep_TimeoutNotExceeded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{Name: "ok", Method: "GET", URL: server.URL, Timeout: 2 * time.Second},
+		&http.Client{},
+		nil,
+	)
+
+	result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("expected success, got %q", result.Error)
+	}
+}
+
+func TestStep_ParentCancellationIsNotTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{Name: "test", Method: "GET", URL: server.URL, Timeout: 5 * time.Second},
+		&http.Client{},
+		nil,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := step.Execute(core.ContextWithActorID(ctx, 1), core.NewVariables())
+	if err == nil {
+		t.Error("expected error when the test context ends")
+	}
+	if result.ErrorType != core.ErrorTypeCanceled {
+		t.Errorf("expected error type %q, got %q", core.ErrorTypeCanceled, result.ErrorType)
+	}
+}
+
+func TestStep_ErrorTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		cfg  config.StepConfig
+		want string
+	}{
+		{"status", config.StepConfig{Name: "s", Method: "GET", URL: server.URL}, core.ErrorTypeStatus},
+		{"network", config.StepConfig{Name: "s", Method: "GET", URL: "http://localhost:99999"}, core.ErrorTypeNetwork},
+		{"request", config.StepConfig{Name: "s", Method: "GET", URL: "${missing}"}, core.ErrorTypeRequest},
+		{"template syntax", config.StepConfig{Name: "s", Method: "GET", URL: server.URL, Headers: map[string]string{"X": "${id | bogus}"}}, core.ErrorTypeRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := NewStep(tt.cfg, &http.Client{Timeout: time.Second}, nil)
+			result, _ := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+			if result.ErrorType != tt.want {
+				t.Errorf("expected error type %q, got %q", tt.want, result.ErrorType)
+			}
+		})
+	}
+}
+
+func TestStep_ExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/account":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	follow, noFollow := true, false
+	tests := []struct {
+		name        string
+		path        string
+		expect      config.StatusCodes
+		follow      *bool
+		wantSuccess bool
+		wantStatus  int
+		wantError   string
+	}{
+		{"expected 404", "/missing", config.StatusCodes{{Min: 404, Max: 404}}, nil, true, 404, ""},
+		{"unexpected 200", "/login", config.StatusCodes{{Min: 404, Max: 404}}, nil, false, 200, "unexpected status 200 OK (expected 404)"},
+		{"redirect followed by default", "/account", nil, nil, true, 200, ""},
+		{"redirect detected with 2xx", "/account", config.StatusCodes{{Min: 200, Max: 299}}, &noFollow, false, 302, "unexpected status 302 Found (expected 2xx)"},
+		{"expected redirect not followed", "/account", config.StatusCodes{{Min: 302, Max: 302}}, nil, true, 302, ""},
+		{"explicit follow overrides", "/account", config.StatusCodes{{Min: 302, Max: 302}}, &follow, false, 200, "unexpected status 200 OK (expected 302)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := NewStep(
+				config.StepConfig{Name: "test", Method: "GET", URL: server.URL + tt.path, Expect: tt.expect, FollowRedirects: tt.follow},
+				&http.Client{Timeout: 5 * time.Second},
+				nil,
+			)
+
+			result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("expected success=%v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if result.StatusCode != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, result.StatusCode)
+			}
+			if result.Error != tt.wantError {
+				t.Errorf("expected error %q, got %q", tt.wantError, result.Error)
+			}
+		})
+	}
+}
+
+func TestStep_BytesRecvCountsUnreadBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 3000))
+	}))
+	defer server.Close()
+
+	step := NewStep(
+		config.StepConfig{Name: "test", Method: "GET", URL: server.URL},
+		&http.Client{Timeout: 5 * time.Second},
+		nil,
+	)
+
+	result, err := step.Execute(core.ContextWithActorID(context.Background(), 1), core.NewVariables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.BytesRecv != 3000 {
+		t.Errorf("expected BytesRecv 3000, got %d", result.BytesRecv)
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"maestro/internal/config"
+)
+
+// defaultMaxIdleConnsPerHost replaces the http package default of 2,
+// which makes many actors hitting one host open and close connections
+// constantly.
+const defaultMaxIdleConnsPerHost = 100
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ClientFactory builds transports and clients from the http config. CA
+// and client certificate files are read once, when the factory is created,
+// so a bad file fails before the test starts.
+type ClientFactory struct {
+	cfg config.HTTPConfig
+	tls *tls.Config
+}
+
+func NewClientFactory(cfg config.HTTPConfig) (*ClientFactory, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientFactory{cfg: cfg, tls: tlsConfig}, nil
+}
+
+// Connections returns the connection mode: shared, actor or iteration.
+func (f *ClientFactory) Connections() string {
+	if f.cfg.Connections == "" {
+		return config.ConnectionsShared
+	}
+	return f.cfg.Connections
+}
+
+// Cookies returns the cookie jar scope: iteration, actor or disabled.
+func (f *ClientFactory) Cookies() string {
+	if f.cfg.Cookies == "" {
+		return config.CookiesIteration
+	}
+	return f.cfg.Cookies
+}
+
+// Transport returns a new transport with its own connection pool.
+func (f *ClientFactory) Transport() *http.Transport {
+	cfg := f.cfg
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	t.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	t.DisableKeepAlives = cfg.DisableKeepAlives
+	t.TLSClientConfig = f.tls.Clone()
+
+	if cfg.HTTP2 != nil && !*cfg.HTTP2 {
+		// A non-nil, empty map turns off HTTP/2 negotiation
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else {
+		// Needed for HTTP/2 with a custom TLS config
+		t.ForceAttemptHTTP2 = true
+	}
+	return t
+}
+
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tc := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.MinVersion != "" {
+		v, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown minVersion %q", cfg.MinVersion)
+		}
+		tc.MinVersion = v
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: reading caFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: caFile %s: no PEM certificates found", cfg.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
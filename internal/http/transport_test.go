@@ -0,0 +1,322 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"maestro/internal/config"
+	"maestro/internal/core"
+)
+
+func newTestTransport(t *testing.T, cfg config.HTTPConfig) *http.Transport {
+	t.Helper()
+	f, err := NewClientFactory(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f.Transport()
+}
+
+func newTestClient(t *testing.T, cfg config.HTTPConfig) *http.Client {
+	t.Helper()
+	transport := newTestTransport(t, cfg)
+	t.Cleanup(transport.CloseIdleConnections)
+	return &http.Client{Transport: transport, Timeout: 5 * time.Second}
+}
+
+// get requests url and returns the body, the protocol the server saw.
+func get(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func newTLSServer(t *testing.T, configure func(*httptest.Server)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	if configure != nil {
+		configure(server)
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeServerCA writes the certificate of a test server as a PEM bundle.
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeClientCert creates a self-signed client certificate and returns its
+// parsed form and the paths of its PEM certificate and key.
+func writeClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "maestro test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return cert, certPath, keyPath
+}
+
+func TestTransport_PoolSettings(t *testing.T) {
+	transport := newTestTransport(t, config.HTTPConfig{})
+	if transport.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost || transport.DisableKeepAlives {
+		t.Errorf("unexpected defaults: MaxIdleConnsPerHost=%d DisableKeepAlives=%v",
+			transport.MaxIdleConnsPerHost, transport.DisableKeepAlives)
+	}
+
+	transport = newTestTransport(t, config.HTTPConfig{
+		MaxIdleConns:        500,
+		MaxIdleConnsPerHost: 250,
+		MaxConnsPerHost:     300,
+		IdleConnTimeout:     15 * time.Second,
+	})
+	if transport.MaxIdleConns != 500 || transport.MaxIdleConnsPerHost != 250 ||
+		transport.MaxConnsPerHost != 300 || transport.IdleConnTimeout != 15*time.Second {
+		t.Errorf("pool settings not applied: %+v", transport)
+	}
+}
+
+func TestTransport_KeepAlive(t *testing.T) {
+	for _, disable := range []bool{false, true} {
+		var conns atomic.Int32
+		server := newTLSServer(t, func(s *httptest.Server) {
+			s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+		})
+		client := newTestClient(t, config.HTTPConfig{
+			DisableKeepAlives: disable,
+			TLS:               config.TLSConfig{InsecureSkipVerify: true},
+		})
+		for i := 0; i < 3; i++ {
+			if _, err := get(client, server.URL); err != nil {
+				t.Fatalf("request %d: %v", i, err)
+			}
+		}
+
+		want := int32(1)
+		if disable {
+			want = 3
+		}
+		if got := conns.Load(); got != want {
+			t.Errorf("disableKeepAlives=%v: expected %d connections, got %d", disable, want, got)
+		}
+	}
+}
+
+func TestTransport_ServerVerification(t *testing.T) {
+	server := newTLSServer(t, nil)
+
+	if _, err := get(newTestClient(t, config.HTTPConfig{}), server.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("expected certificate error without a trusted CA, got %v", err)
+	}
+
+	insecure := newTestClient(t, config.HTTPConfig{TLS: config.TLSConfig{InsecureSkipVerify: true}})
+	if _, err := get(insecure, server.URL); err != nil {
+		t.Errorf("insecureSkipVerify: unexpected error: %v", err)
+	}
+
+	withCA := newTestClient(t, config.HTTPConfig{TLS: config.TLSConfig{CAFile: writeServerCA(t, server)}})
+	if _, err := get(withCA, server.URL); err != nil {
+		t.Errorf("caFile: unexpected error: %v", err)
+	}
+}
+
+func TestTransport_ClientCertificate(t *testing.T) {
+	cert, certPath, keyPath := writeClientCert(t)
+	server := newTLSServer(t, func(s *httptest.Server) {
+		pool := x509.NewCertPool()
+		pool.AddCert(cert)
+		s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	})
+	ca := writeServerCA(t, server)
+
+	if _, err := get(newTestClient(t, config.HTTPConfig{TLS: config.TLSConfig{CAFile: ca}}), server.URL); err == nil {
+		t.Error("expected the server to reject a client without certificate")
+	}
+
+	mtls := newTestClient(t, config.HTTPConfig{TLS: config.TLSConfig{CAFile: ca, CertFile: certPath, KeyFile: keyPath}})
+	if _, err := get(mtls, server.URL); err != nil {
+		t.Errorf("client certificate: unexpected error: %v", err)
+	}
+}
+
+func TestTransport_MinVersion(t *testing.T) {
+	server := newTLSServer(t, func(s *httptest.Server) {
+		s.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	})
+
+	tls12 := newTestClient(t, config.HTTPConfig{TLS: config.TLSConfig{InsecureSkipVerify: true, MinVersion: "1.2"}})
+	if _, err := get(tls12, server.URL); err != nil {
+		t.Errorf("minVersion 1.2: unexpected error: %v", err)
+	}
+
+	tls13 := newTestClient(t, config.HTTPConfig{TLS: config.TLSConfig{InsecureSkipVerify: true, MinVersion: "1.3"}})
+	if _, err := get(tls13, server.URL); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("minVersion 1.3: expected protocol version error, got %v", err)
+	}
+}
+
+func TestTransport_HTTP2(t *testing.T) {
+	server := newTLSServer(t, func(s *httptest.Server) { s.EnableHTTP2 = true })
+
+	enabled, disabled := true, false
+	tests := []struct {
+		http2 *bool
+		want  string
+	}{
+		{nil, "HTTP/2.0"},
+		{&enabled, "HTTP/2.0"},
+		{&disabled, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, config.HTTPConfig{HTTP2: tt.http2, TLS: config.TLSConfig{InsecureSkipVerify: true}})
+		proto, err := get(client, server.URL)
+		if err != nil || proto != tt.want {
+			t.Errorf("http2=%v: expected %s, got %q (%v)", tt.http2 != nil && *tt.http2, tt.want, proto, err)
+		}
+	}
+}
+
+func TestTransport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0644)
+
+	tests := []struct {
+		tls  config.TLSConfig
+		want string
+	}{
+		{config.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}, "reading caFile"},
+		{config.TLSConfig{CAFile: notPEM}, "no PEM certificates found"},
+		{config.TLSConfig{CertFile: notPEM, KeyFile: notPEM}, "loading client certificate"},
+		{config.TLSConfig{MinVersion: "1.4"}, "unknown minVersion"},
+	}
+	for _, tt := range tests {
+		_, err := NewClientFactory(config.HTTPConfig{TLS: tt.tls})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.tls, tt.want, err)
+		}
+	}
+}
+
+func TestWorkflow_Connections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		connections string
+		want        int32
+	}{
+		// 2 actors with 2 iterations each
+		{config.ConnectionsShared, 1},
+		{config.ConnectionsActor, 2},
+		{config.ConnectionsIteration, 4},
+	}
+	for _, tt := range tests {
+		conns.Store(0)
+		shared := &http.Client{Transport: &http.Transport{}}
+		clients, err := NewClientFactory(config.HTTPConfig{Connections: tt.connections})
+		if err != nil {
+			t.Fatal(err)
+		}
+		workflow := &Workflow{
+			Config: config.WorkflowConfig{
+				Steps: []config.StepConfig{{Name: "get", Method: "GET", URL: server.URL}},
+			},
+			Client:  shared,
+			Clients: clients,
+		}
+		runActors(t, workflow, 2, 2)
+		shared.CloseIdleConnections()
+
+		if got := conns.Load(); got != tt.want {
+			t.Errorf("%s: expected %d connections, got %d", tt.connections, tt.want, got)
+		}
+	}
+}
+
+// runActors runs the workflow for actors one after another, each with its
+// own actor state.
+func runActors(t *testing.T, workflow *Workflow, actors, iterations int) {
+	t.Helper()
+	for actorID := 1; actorID <= actors; actorID++ {
+		actor := core.NewActorState()
+		ctx := core.ContextWithActorState(context.Background(), actor)
+		for i := 0; i < iterations; i++ {
+			if err := workflow.Run(ctx, actorID, nil, core.NullReporter); err != nil {
+				t.Fatalf("actor %d: unexpected error: %v", actorID, err)
+			}
+		}
+		actor.Close()
+	}
+}
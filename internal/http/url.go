@@ -0,0 +1,71 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"maestro/internal/core"
+)
+
+// requestURL renders the step URL. A URL without a scheme and host is
+// joined to the base URL, and query parameters the URL does not set itself
+// are appended.
+func (s *Step) requestURL(vars core.Variables) (string, error) {
+	raw, err := s.url.Execute(vars)
+	if err != nil {
+		return "", err
+	}
+	if s.baseURL != nil && !isAbsoluteURL(raw) {
+		base, err := s.baseURL.Execute(vars)
+		if err != nil {
+			return "", fmt.Errorf("baseURL: %w", err)
+		}
+		raw = joinURL(base, raw)
+	}
+	if len(s.query) == 0 {
+		return raw, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	existing := u.Query()
+	extra := make(url.Values)
+	for _, f := range s.query {
+		if existing.Has(f.name) {
+			continue
+		}
+		v, err := f.value.Execute(vars)
+		if err != nil {
+			return "", fmt.Errorf("query %q: %w", f.name, err)
+		}
+		extra.Set(f.name, v)
+	}
+	// Append rather than re-encode, so the URL's own query is sent as written
+	if len(extra) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += extra.Encode()
+	}
+	return u.String(), nil
+}
+
+// isAbsoluteURL reports whether raw names its own scheme and host. A URL
+// in the query, as in /login?next=https://app.example.com, does not count.
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
+
+// joinURL appends a relative path to base, keeping the base path:
+// https://api.example.com/v1 and /users/me give
+// https://api.example.com/v1/users/me.
+func joinURL(base, path string) string {
+	if path == "" || strings.HasPrefix(path, "?") {
+		return base + path
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"maestro/internal/collector"
+	"maestro/internal/config"
+	"maestro/internal/core"
+)
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		base, path, want string
+	}{
+		{"https://api.example.com", "/users/me", "https://api.example.com/users/me"},
+		{"https://api.example.com/", "/users/me", "https://api.example.com/users/me"},
+		{"https://api.example.com/v1", "users/me", "https://api.example.com/v1/users/me"},
+		{"https://api.example.com/v1/", "/users?page=2", "https://api.example.com/v1/users?page=2"},
+		{"https://api.example.com/search", "?q=x", "https://api.example.com/search?q=x"},
+		{"https://api.example.com", "", "https://api.example.com"},
+	}
+	for _, tt := range tests {
+		if got := joinURL(tt.base, tt.path); got != tt.want {
+			t.Errorf("joinURL(%q, %q): expected %q, got %q", tt.base, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestStep_RequestURL(t *testing.T) {
+	vars := core.NewVariables()
+	vars.Set("host", "https://api.example.com")
+	vars.Set("id", "u 7")
+	vars.Set("tenant", "acme")
+
+	tests := []struct {
+		name string
+		cfg  config.StepConfig
+		want string
+	}{
+		{"relative", config.StepConfig{URL: "/users/${id}", BaseURL: "${host}/v1"}, "https://api.example.com/v1/users/u 7"},
+		{"absolute ignores base", config.StepConfig{URL: "http://other.test/x", BaseURL: "${host}"}, "http://other.test/x"},
+		{"no base", config.StepConfig{URL: "${host}/x"}, "https://api.example.com/x"},
+		{"url in query", config.StepConfig{URL: "/login?next=https://app.example.com", BaseURL: "${host}"},
+			"https://api.example.com/login?next=https://app.example.com"},
+		{"query appended", config.StepConfig{
+			URL:   "${host}/search?q=a%20b",
+			Query: map[string]string{"tenant": "${tenant}", "tag": "x&y"},
+		}, "https://api.example.com/search?q=a%20b&tag=x%26y&tenant=acme"},
+		{"url query wins", config.StepConfig{
+			URL:   "${host}/search?tenant=globex",
+			Query: map[string]string{"tenant": "${tenant}"},
+		}, "https://api.example.com/search?tenant=globex"},
+	}
+	for _, tt := range tests {
+		tt.cfg.Name, tt.cfg.Method = "test", "GET"
+		step := NewStep(tt.cfg, http.DefaultClient, nil)
+		got, err := step.requestURL(vars)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+func TestHTTPWorkflow_Defaults(t *testing.T) {
+	var paths, auths, versions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+		versions = append(versions, r.URL.Query().Get("api_version"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := collector.NewCollector()
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name:      "Test",
+			Variables: map[string]any{"token": "t-1"},
+			Defaults: config.DefaultsConfig{
+				BaseURL: server.URL + "/v1",
+				Headers: map[string]string{"Authorization": "Bearer ${token}"},
+				Query:   map[string]string{"api_version": "2"},
+				Expect:  config.StatusCodes{{Min: 404, Max: 404}},
+			},
+			Steps: []config.StepConfig{
+				{Name: "me", Method: "GET", URL: "/users/me"},
+				{Name: "override", Method: "GET", URL: "/items?api_version=3",
+					Headers: map[string]string{"authorization": "Basic x"}},
+			},
+		},
+		Client: http.DefaultClient,
+	}
+
+	if err := workflow.Run(context.Background(), 1, nil, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Close()
+
+	if strings.Join(paths, ",") != "/v1/users/me,/v1/items" {
+		t.Errorf("unexpected paths %v", paths)
+	}
+	if auths[0] != "Bearer t-1" || auths[1] != "Basic x" {
+		t.Errorf("unexpected Authorization headers %v", auths)
+	}
+	if versions[0] != "2" || versions[1] != "3" {
+		t.Errorf("unexpected api_version params %v", versions)
+	}
+	for _, e := range c.Events() {
+		if !e.Success {
+			t.Errorf("%s: expected the default expected status to apply, got %s", e.Step, e.Error)
+		}
+	}
+}
@@ -19,11 +19,28 @@ type Workflow struct {
 	RateLimiter *ratelimit.RateLimiter
 	Debug       *DebugLogger
 	DataSources data.Sources
+	// Clients builds the actors' own clients when the connection mode is
+	// actor or iteration. When nil, or in shared mode, all use Client.
+	Clients *ClientFactory
 
 	steps     []core.Step
 	stepsOnce sync.Once
+	// started is when the first iteration ran; ${__elapsed_ms} counts from
+	// it when the context carries no run state.
+	started time.Time
 }
 
+// Built-in variables describing where an iteration runs. They are set
+// before every iteration, and __step and __elapsed_ms before every step.
+const (
+	varActorID   = "__actor_id"
+	varIteration = "__iteration"
+	varPhase     = "__phase"
+	varScenario  = "__scenario"
+	varStep      = "__step"
+	varElapsedMs = "__elapsed_ms"
+)
+
 func (w *Workflow) Run(ctx context.Context, actorID int, coord core.Coordinator, rep core.Reporter) error {
 	if w.RateLimiter != nil {
 		if err := w.RateLimiter.Wait(ctx); err != nil {
@@ -34,32 +51,90 @@ func (w *Workflow) Run(ctx context.Context, actorID int, coord core.Coordinator,
 	w.stepsOnce.Do(func() {
 		w.steps = make([]core.Step, len(w.Config.Steps))
 		for i, cfg := range w.Config.Steps {
+			cfg = w.Config.Defaults.Apply(cfg)
 			w.steps[i] = NewStep(cfg, w.Client, w.Debug)
 		}
+		w.started = time.Now()
 	})
 
 	ctx = core.ContextWithActorID(ctx, actorID)
+	client, jar, release := w.actorClient(ctx)
+	if release != nil {
+		defer release()
+	}
+	if client != nil {
+		ctx = contextWithClient(ctx, client)
+	}
 	vars := core.NewVariables()
 
+	// Workflow-level variables (already merged with CLI overrides)
+	for k, v := range w.Config.Variables {
+		vars.Set(k, v)
+	}
+
 	// Inject data from data sources (each call advances to next row)
 	if w.DataSources != nil {
 		w.DataSources.InjectVariables(vars)
 	}
 
-	for _, step := range w.steps {
+	run := core.RunStateFromContext(ctx)
+	vars.Set(varActorID, actorID)
+	vars.Set(varIteration, core.IterationFromContext(ctx))
+	vars.Set(varScenario, w.Config.Name)
+	phase := ""
+	if run != nil {
+		phase = run.Phase()
+	}
+	vars.Set(varPhase, phase)
+	if jar != nil {
+		// An actor-scoped jar keeps cookies from earlier iterations
+		jar.setVariables(vars)
+	}
+
+	iterationStart := time.Now()
+	for i, step := range w.steps {
+		elapsed := time.Since(w.started)
+		if run != nil {
+			elapsed = run.Elapsed()
+		}
+		vars.Set(varStep, step.Name())
+		vars.Set(varElapsedMs, elapsed.Milliseconds())
+
 		result, err := step.Execute(ctx, vars)
+		if jar != nil {
+			// Also after unchanged steps, since cookies may have expired
+			changed := jar.takeChanged()
+			jar.setVariables(vars)
+			if changed {
+				w.Debug.LogCookies(actorID, step.Name(), jar.all())
+			}
+		}
+		stepCfg := w.Config.Steps[i]
+
+		// The iteration ends after the last step or the first step error
+		var iterationDuration time.Duration
+		if err != nil || i == len(w.steps)-1 {
+			iterationDuration = time.Since(iterationStart)
+		}
 
 		rep.Report(core.Event{
-			ActorID:    actorID,
-			Timestamp:  time.Now(),
-			Step:       step.Name(),
-			Protocol:   "http",
-			Duration:   result.Duration,
-			Success:    result.Success,
-			Error:      result.Error,
-			StatusCode: result.StatusCode,
-			BytesSent:  result.BytesSent,
-			BytesRecv:  result.BytesRecv,
+			ActorID:           actorID,
+			Timestamp:         time.Now(),
+			Step:              step.Name(),
+			Group:             stepCfg.Group,
+			Scenario:          w.Config.Name,
+			Tags:              stepCfg.Tags,
+			Protocol:          "http",
+			Duration:          result.Duration,
+			Success:           result.Success,
+			Error:             result.Error,
+			ErrorType:         result.ErrorType,
+			StatusCode:        result.StatusCode,
+			BytesSent:         result.BytesSent,
+			BytesRecv:         result.BytesRecv,
+			Checks:            result.Checks,
+			SchemaViolations:  result.SchemaViolations,
+			IterationDuration: iterationDuration,
 		})
 
 		if result.Extract != nil {
@@ -75,3 +150,75 @@ func (w *Workflow) Run(ctx context.Context, actorID int, coord core.Coordinator,
 
 	return nil
 }
+
+// actorClient returns the client of the actor running ctx and its cookie
+// jar, or nil when the actor uses the shared client without cookies.
+//
+// In connections mode actor, the transport is kept in the actor's state
+// and closed when the actor stops. In mode iteration, and outside an
+// actor, a new transport is built and release closes it at the end of the
+// iteration. Cookie jars follow the same rule for their scope.
+func (w *Workflow) actorClient(ctx context.Context) (client *http.Client, jar *cookieJar, release func()) {
+	if w.Clients == nil {
+		return nil, nil, nil
+	}
+	actor := core.ActorStateFromContext(ctx)
+
+	var transport http.RoundTripper
+	switch mode := w.Clients.Connections(); {
+	case mode == config.ConnectionsActor && actor != nil:
+		t, ok := actor.Value(transportKey{}).(*http.Transport)
+		if !ok {
+			t = w.Clients.Transport()
+			actor.SetValue(transportKey{}, t)
+			actor.Cleanup(t.CloseIdleConnections)
+		}
+		transport = t
+	case mode != config.ConnectionsShared:
+		t := w.Clients.Transport()
+		transport, release = t, t.CloseIdleConnections
+	}
+
+	switch scope := w.Clients.Cookies(); {
+	case scope == config.CookiesActor && actor != nil:
+		var ok bool
+		if jar, ok = actor.Value(jarKey{}).(*cookieJar); !ok {
+			jar = newCookieJar()
+			actor.SetValue(jarKey{}, jar)
+		}
+	case scope != config.CookiesDisabled:
+		jar = newCookieJar()
+	}
+
+	if transport == nil && jar == nil {
+		return nil, nil, release
+	}
+	client = &http.Client{Transport: transport}
+	if transport == nil && w.Client != nil {
+		client.Transport = w.Client.Transport
+	}
+	if jar != nil {
+		client.Jar = jar
+	}
+	return client, jar, release
+}
+
+// Keys of an actor's own transport and cookie jar in its core.ActorState.
+type (
+	transportKey struct{}
+	jarKey       struct{}
+)
+
+// clientKey stores an actor's own client in the context of its requests.
+type clientKey struct{}
+
+func contextWithClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// clientFromContext returns the client of the actor running ctx, or nil
+// when actors share the workflow's client.
+func clientFromContext(ctx context.Context) *http.Client {
+	client, _ := ctx.Value(clientKey{}).(*http.Client)
+	return client
+}
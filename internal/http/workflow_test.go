@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -476,3 +477,270 @@ func TestHTTPWorkflow_VerboseMode(t *testing.T) {
 		t.Error("expected debug output, got empty string")
 	}
 }
+
+func TestHTTPWorkflow_WorkflowVariables(t *testing.T) {
+	var receivedPath, receivedTenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.RequestURI()
+		receivedTenant = r.Header.Get("X-Tenant")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := collector.NewCollector()
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name: "Test",
+			Variables: map[string]any{
+				"base_url":  server.URL,
+				"tenant":    "acme",
+				"page_size": 50,
+			},
+			Steps: []config.StepConfig{
+				{
+					Name:    "list",
+					Method:  "GET",
+					URL:     "${base_url}/items?size=${page_size}",
+					Headers: map[string]string{"X-Tenant": "${tenant}"},
+				},
+			},
+		},
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	err := workflow.Run(context.Background(), 1, nil, c)
+	c.Close()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receivedPath != "/items?size=50" {
+		t.Errorf("expected /items?size=50, got %q", receivedPath)
+	}
+	if receivedTenant != "acme" {
+		t.Errorf("expected tenant acme, got %q", receivedTenant)
+	}
+}
+
+func TestHTTPWorkflow_TimeoutInheritance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := collector.NewCollector()
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name:     "Test",
+			Defaults: config.DefaultsConfig{Timeout: 50 * time.Millisecond},
+			Steps: []config.StepConfig{
+				{Name: "inherits", Method: "GET", URL: server.URL},
+				{Name: "overrides", Method: "GET", URL: server.URL, Timeout: 2 * time.Second},
+			},
+		},
+		Client: &http.Client{},
+	}
+
+	err := workflow.Run(context.Background(), 1, nil, c)
+	c.Close()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events := c.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Success || events[0].ErrorType != core.ErrorTypeTimeout {
+		t.Errorf("expected first step to time out, got success=%v type=%q", events[0].Success, events[0].ErrorType)
+	}
+	if !events[1].Success {
+		t.Errorf("expected second step to succeed, got %q", events[1].Error)
+	}
+}
+
+func TestHTTPWorkflow_ExpectStatusInheritance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	c := collector.NewCollector()
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name:     "Test",
+			Defaults: config.DefaultsConfig{Expect: config.StatusCodes{{Min: 200, Max: 200}}},
+			Steps: []config.StepConfig{
+				{Name: "inherits", Method: "GET", URL: server.URL},
+				{Name: "overrides", Method: "GET", URL: server.URL, Expect: config.StatusCodes{{Min: 200, Max: 299}}},
+			},
+		},
+		Client: &http.Client{},
+	}
+
+	err := workflow.Run(context.Background(), 1, nil, c)
+	c.Close()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events := c.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Success || events[0].ErrorType != core.ErrorTypeStatus {
+		t.Errorf("expected 201 to be unexpected for first step, got success=%v type=%q", events[0].Success, events[0].ErrorType)
+	}
+	if !events[1].Success {
+		t.Errorf("expected second step to accept 201, got %q", events[1].Error)
+	}
+}
+
+func TestHTTPWorkflow_EventLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := collector.NewCollector()
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name: "Checkout Flow",
+			Steps: []config.StepConfig{
+				{Name: "login", Method: "GET", URL: server.URL, Group: "auth"},
+				{Name: "pay", Method: "GET", URL: server.URL, Tags: map[string]string{"tier": "critical"}},
+			},
+		},
+		Client: &http.Client{},
+	}
+
+	if err := workflow.Run(context.Background(), 1, nil, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Close()
+
+	events := c.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Group != "auth" || events[0].Scenario != "Checkout Flow" {
+		t.Errorf("unexpected labels for login: group=%q scenario=%q", events[0].Group, events[0].Scenario)
+	}
+	if events[1].Tags["tier"] != "critical" {
+		t.Errorf("expected tier tag on pay, got %v", events[1].Tags)
+	}
+}
+
+func TestHTTPWorkflow_IterationDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := collector.NewCollector()
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name: "Test",
+			Steps: []config.StepConfig{
+				{Name: "first", Method: "GET", URL: server.URL},
+				{Name: "second", Method: "GET", URL: server.URL},
+			},
+		},
+		Client: &http.Client{},
+	}
+
+	if err := workflow.Run(context.Background(), 1, nil, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Close()
+
+	events := c.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].IterationDuration != 0 {
+		t.Errorf("expected no iteration duration on first step, got %v", events[0].IterationDuration)
+	}
+	if events[1].IterationDuration < 20*time.Millisecond {
+		t.Errorf("expected iteration duration to cover both steps, got %v", events[1].IterationDuration)
+	}
+}
+
+func TestHTTPWorkflow_BuiltinVariables(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	url := server.URL + "/${__scenario}/${__step}?actor=${__actor_id}&iter=${__iteration}&phase=${__phase}&shard=${__actor_id % 2}"
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name: "checkout",
+			Steps: []config.StepConfig{
+				{Name: "cart", Method: "GET", URL: url},
+				{Name: "pay", Method: "GET", URL: url},
+			},
+		},
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	run := core.NewRunState(core.RealClock{})
+	run.SetPhase("steady")
+	ctx := core.ContextWithIteration(core.ContextWithRunState(context.Background(), run), 4)
+	if err := workflow.Run(ctx, 3, nil, core.NullReporter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"/checkout/cart?actor=3&iter=4&phase=steady&shard=1",
+		"/checkout/pay?actor=3&iter=4&phase=steady&shard=1",
+	}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestHTTPWorkflow_ElapsedAndSeq(t *testing.T) {
+	var values []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values = append(values, r.URL.Query().Get("elapsed"), r.URL.Query().Get("seq"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	workflow := &Workflow{
+		Config: config.WorkflowConfig{
+			Name: "Test",
+			Steps: []config.StepConfig{
+				{Name: "get", Method: "GET", URL: server.URL + "?elapsed=${__elapsed_ms}&seq=${seq()}"},
+			},
+		},
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	clock := core.NewFakeClock(time.Unix(1700000000, 0))
+	run := core.NewRunState(clock)
+	clock.Advance(2500 * time.Millisecond)
+	ctx := core.ContextWithRunState(context.Background(), run)
+	for i := 0; i < 2; i++ {
+		if err := workflow.Run(ctx, 1, nil, core.NullReporter); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if values[0] != "2500" || values[2] != "2500" {
+		t.Errorf("expected elapsed 2500ms from the run clock, got %v", values)
+	}
+	first, _ := strconv.Atoi(values[1])
+	second, _ := strconv.Atoi(values[3])
+	if first < 1 || second != first+1 {
+		t.Errorf("expected consecutive seq values, got %v", values)
+	}
+}
@@ -0,0 +1,704 @@
+// Package schema validates JSON documents against a subset of JSON Schema
+// draft 2020-12.
+//
+// Supported keywords:
+//   - any type: type, enum, const, allOf, anyOf, oneOf, not, if/then/else, $ref, $defs
+//   - objects: properties, required, additionalProperties, patternProperties,
+//     minProperties, maxProperties
+//   - arrays: items, prefixItems, minItems, maxItems, uniqueItems, contains,
+//     minContains, maxContains
+//   - strings: minLength, maxLength, pattern
+//   - numbers: minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+//
+// $ref must point into the same document ("#" or "#/json/pointer").
+// Annotation keywords such as title, description and format are ignored.
+// Keywords whose semantics are not implemented are rejected at compile time
+// so a schema never silently validates less than its author intended.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// unsupported lists draft 2020-12 keywords that Compile rejects.
+var unsupported = []string{
+	"$dynamicRef", "$dynamicAnchor", "$recursiveRef",
+	"unevaluatedProperties", "unevaluatedItems",
+	"dependentSchemas", "propertyNames",
+}
+
+// Schema is a compiled JSON Schema, safe for concurrent use.
+type Schema struct {
+	root *node
+}
+
+// Violation describes one place where a document does not match the schema.
+type Violation struct {
+	Path    string // JSONPath-style location, e.g. $.items[2].price
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Load reads and compiles a schema file.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+	s, err := Compile(data)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	c := &compiler{doc: doc, nodes: make(map[string]*node)}
+	root, err := c.compile(doc, "#")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// ValidateJSON validates a raw JSON document.
+func (s *Schema) ValidateJSON(data []byte) []Violation {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []Violation{{Path: "$", Message: "invalid JSON: " + err.Error()}}
+	}
+	return s.Validate(v)
+}
+
+// Validate validates a decoded JSON value (as produced by encoding/json).
+func (s *Schema) Validate(v any) []Violation {
+	var out []Violation
+	s.root.validate(v, "$", &out)
+	return out
+}
+
+// node is one compiled (sub)schema. Pointer fields are nil when the keyword is absent.
+type node struct {
+	always *bool // boolean schema: true accepts everything, false nothing
+
+	ref   *node
+	types []string
+	enum  []any
+	cnst  *any
+
+	allOf, anyOf, oneOf []*node
+	not                 *node
+	ifN, thenN, elseN   *node
+
+	properties   map[string]*node
+	required     []string
+	additional   *node
+	patternProps []patternProp
+	minProps     *int
+	maxProps     *int
+
+	items       *node
+	prefixItems []*node
+	contains    *node
+	minContains *int
+	maxContains *int
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum    *float64
+	maximum    *float64
+	exclMin    *float64
+	exclMax    *float64
+	multipleOf *float64
+}
+
+type patternProp struct {
+	re     *regexp.Regexp
+	schema *node
+}
+
+// compiler turns the decoded document into nodes. Nodes are memoized by
+// JSON pointer so recursive $refs compile to a cyclic graph.
+type compiler struct {
+	doc   any
+	nodes map[string]*node
+}
+
+func (c *compiler) compile(raw any, ptr string) (*node, error) {
+	if n, ok := c.nodes[ptr]; ok {
+		return n, nil
+	}
+	n := &node{}
+	c.nodes[ptr] = n
+
+	switch s := raw.(type) {
+	case bool:
+		n.always = &s
+		return n, nil
+	case map[string]any:
+		if err := c.fill(n, s, ptr); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or boolean", ptr)
+	}
+}
+
+func (c *compiler) fill(n *node, s map[string]any, ptr string) error {
+	for _, kw := range unsupported {
+		if _, ok := s[kw]; ok {
+			return fmt.Errorf("%s: keyword %q is not supported", ptr, kw)
+		}
+	}
+
+	var err error
+	sub := func(key string) (*node, error) {
+		raw, ok := s[key]
+		if !ok {
+			return nil, nil
+		}
+		return c.compile(raw, ptr+"/"+escape(key))
+	}
+	list := func(key string) ([]*node, error) {
+		raw, ok := s[key]
+		if !ok {
+			return nil, nil
+		}
+		arr, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s/%s: must be an array", ptr, key)
+		}
+		nodes := make([]*node, len(arr))
+		for i, item := range arr {
+			if nodes[i], err = c.compile(item, fmt.Sprintf("%s/%s/%d", ptr, key, i)); err != nil {
+				return nil, err
+			}
+		}
+		return nodes, nil
+	}
+
+	if raw, ok := s["$ref"]; ok {
+		ref, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s/$ref: must be a string", ptr)
+		}
+		if n.ref, err = c.resolve(ref, ptr); err != nil {
+			return err
+		}
+	}
+
+	if raw, ok := s["type"]; ok {
+		switch t := raw.(type) {
+		case string:
+			n.types = []string{t}
+		case []any:
+			for _, v := range t {
+				name, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("%s/type: must be a string or array of strings", ptr)
+				}
+				n.types = append(n.types, name)
+			}
+		default:
+			return fmt.Errorf("%s/type: must be a string or array of strings", ptr)
+		}
+		for _, t := range n.types {
+			switch t {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return fmt.Errorf("%s/type: unknown type %q", ptr, t)
+			}
+		}
+	}
+
+	if raw, ok := s["enum"]; ok {
+		arr, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("%s/enum: must be an array", ptr)
+		}
+		n.enum = arr
+	}
+	if raw, ok := s["const"]; ok {
+		n.cnst = &raw
+	}
+
+	if n.allOf, err = list("allOf"); err != nil {
+		return err
+	}
+	if n.anyOf, err = list("anyOf"); err != nil {
+		return err
+	}
+	if n.oneOf, err = list("oneOf"); err != nil {
+		return err
+	}
+	if n.not, err = sub("not"); err != nil {
+		return err
+	}
+	if n.ifN, err = sub("if"); err != nil {
+		return err
+	}
+	if n.thenN, err = sub("then"); err != nil {
+		return err
+	}
+	if n.elseN, err = sub("else"); err != nil {
+		return err
+	}
+
+	if raw, ok := s["properties"]; ok {
+		props, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s/properties: must be an object", ptr)
+		}
+		n.properties = make(map[string]*node, len(props))
+		for name, p := range props {
+			if n.properties[name], err = c.compile(p, ptr+"/properties/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	}
+	if raw, ok := s["required"]; ok {
+		arr, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("%s/required: must be an array of strings", ptr)
+		}
+		for _, v := range arr {
+			name, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("%s/required: must be an array of strings", ptr)
+			}
+			n.required = append(n.required, name)
+		}
+	}
+	if n.additional, err = sub("additionalProperties"); err != nil {
+		return err
+	}
+	if raw, ok := s["patternProperties"]; ok {
+		props, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s/patternProperties: must be an object", ptr)
+		}
+		patterns := make([]string, 0, len(props))
+		for p := range props {
+			patterns = append(patterns, p)
+		}
+		sort.Strings(patterns)
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("%s/patternProperties: invalid pattern %q: %w", ptr, p, err)
+			}
+			schema, err := c.compile(props[p], ptr+"/patternProperties/"+escape(p))
+			if err != nil {
+				return err
+			}
+			n.patternProps = append(n.patternProps, patternProp{re: re, schema: schema})
+		}
+	}
+
+	if n.items, err = sub("items"); err != nil {
+		return err
+	}
+	if n.prefixItems, err = list("prefixItems"); err != nil {
+		return err
+	}
+	if n.contains, err = sub("contains"); err != nil {
+		return err
+	}
+	if raw, ok := s["uniqueItems"]; ok {
+		if n.uniqueItems, ok = raw.(bool); !ok {
+			return fmt.Errorf("%s/uniqueItems: must be a boolean", ptr)
+		}
+	}
+
+	if raw, ok := s["pattern"]; ok {
+		p, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s/pattern: must be a string", ptr)
+		}
+		if n.pattern, err = regexp.Compile(p); err != nil {
+			return fmt.Errorf("%s/pattern: %w", ptr, err)
+		}
+	}
+
+	ints := map[string]**int{
+		"minProperties": &n.minProps, "maxProperties": &n.maxProps,
+		"minItems": &n.minItems, "maxItems": &n.maxItems,
+		"minContains": &n.minContains, "maxContains": &n.maxContains,
+		"minLength": &n.minLength, "maxLength": &n.maxLength,
+	}
+	for key, dst := range ints {
+		raw, ok := s[key]
+		if !ok {
+			continue
+		}
+		f, ok := raw.(float64)
+		if !ok || f < 0 || f != math.Trunc(f) {
+			return fmt.Errorf("%s/%s: must be a non-negative integer", ptr, key)
+		}
+		v := int(f)
+		*dst = &v
+	}
+
+	floats := map[string]**float64{
+		"minimum": &n.minimum, "maximum": &n.maximum,
+		"exclusiveMinimum": &n.exclMin, "exclusiveMaximum": &n.exclMax,
+		"multipleOf": &n.multipleOf,
+	}
+	for key, dst := range floats {
+		raw, ok := s[key]
+		if !ok {
+			continue
+		}
+		f, ok := raw.(float64)
+		if !ok {
+			return fmt.Errorf("%s/%s: must be a number", ptr, key)
+		}
+		*dst = &f
+	}
+	if n.multipleOf != nil && *n.multipleOf <= 0 {
+		return fmt.Errorf("%s/multipleOf: must be greater than 0", ptr)
+	}
+
+	// $defs is only reachable through $ref, but compile it eagerly so
+	// errors in unused definitions are still reported.
+	if raw, ok := s["$defs"]; ok {
+		defs, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s/$defs: must be an object", ptr)
+		}
+		for name, d := range defs {
+			if _, err := c.compile(d, ptr+"/$defs/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve compiles the target of a same-document $ref.
+func (c *compiler) resolve(ref, ptr string) (*node, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("%s/$ref: only local references (#/...) are supported, got %q", ptr, ref)
+	}
+	target := c.doc
+	if ref != "#" {
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+			switch t := target.(type) {
+			case map[string]any:
+				v, ok := t[part]
+				if !ok {
+					return nil, fmt.Errorf("%s/$ref: %q not found", ptr, ref)
+				}
+				target = v
+			case []any:
+				i, err := strconv.Atoi(part)
+				if err != nil || i < 0 || i >= len(t) {
+					return nil, fmt.Errorf("%s/$ref: %q not found", ptr, ref)
+				}
+				target = t[i]
+			default:
+				return nil, fmt.Errorf("%s/$ref: %q not found", ptr, ref)
+			}
+		}
+	}
+	return c.compile(target, ref)
+}
+
+// escape encodes a key as a JSON pointer token.
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// matches reports whether v is valid against n without collecting violations.
+func (n *node) matches(v any) bool {
+	var out []Violation
+	n.validate(v, "$", &out)
+	return len(out) == 0
+}
+
+func (n *node) validate(v any, path string, out *[]Violation) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if n.always != nil {
+		if !*n.always {
+			fail("no value is allowed here")
+		}
+		return
+	}
+
+	if n.ref != nil {
+		n.ref.validate(v, path, out)
+	}
+
+	if len(n.types) > 0 && !typeMatches(n.types, v) {
+		fail("expected %s, got %s", strings.Join(n.types, " or "), typeOf(v))
+		return // other keywords would only repeat the type mismatch
+	}
+
+	if n.enum != nil && !containsValue(n.enum, v) {
+		fail("value %s is not one of the allowed values", describe(v))
+	}
+	if n.cnst != nil && !equal(*n.cnst, v) {
+		fail("expected %s, got %s", describe(*n.cnst), describe(v))
+	}
+
+	for _, s := range n.allOf {
+		s.validate(v, path, out)
+	}
+	if len(n.anyOf) > 0 {
+		matched := false
+		for _, s := range n.anyOf {
+			if s.matches(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("does not match any schema in anyOf")
+		}
+	}
+	if len(n.oneOf) > 0 {
+		matched := 0
+		for _, s := range n.oneOf {
+			if s.matches(v) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("must match exactly one schema in oneOf (matched %d)", matched)
+		}
+	}
+	if n.not != nil && n.not.matches(v) {
+		fail("must not match the schema in not")
+	}
+	if n.ifN != nil {
+		if n.ifN.matches(v) {
+			if n.thenN != nil {
+				n.thenN.validate(v, path, out)
+			}
+		} else if n.elseN != nil {
+			n.elseN.validate(v, path, out)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		n.validateObject(val, path, out)
+	case []any:
+		n.validateArray(val, path, out)
+	case string:
+		length := utf8.RuneCountInString(val)
+		if n.minLength != nil && length < *n.minLength {
+			fail("length %d is less than minLength %d", length, *n.minLength)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			fail("length %d is greater than maxLength %d", length, *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(val) {
+			fail("does not match pattern %q", n.pattern.String())
+		}
+	case float64:
+		if n.minimum != nil && val < *n.minimum {
+			fail("%s is less than minimum %s", describe(val), describe(*n.minimum))
+		}
+		if n.maximum != nil && val > *n.maximum {
+			fail("%s is greater than maximum %s", describe(val), describe(*n.maximum))
+		}
+		if n.exclMin != nil && val <= *n.exclMin {
+			fail("%s must be greater than %s", describe(val), describe(*n.exclMin))
+		}
+		if n.exclMax != nil && val >= *n.exclMax {
+			fail("%s must be less than %s", describe(val), describe(*n.exclMax))
+		}
+		if n.multipleOf != nil {
+			q := val / *n.multipleOf
+			if math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("%s is not a multiple of %s", describe(val), describe(*n.multipleOf))
+			}
+		}
+	}
+}
+
+func (n *node) validateObject(obj map[string]any, path string, out *[]Violation) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+	if n.minProps != nil && len(obj) < *n.minProps {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("has %d properties, fewer than minProperties %d", len(obj), *n.minProps)})
+	}
+	if n.maxProps != nil && len(obj) > *n.maxProps {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("has %d properties, more than maxProperties %d", len(obj), *n.maxProps)})
+	}
+
+	// Sorted so violations are reported in a stable order
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := childPath(path, k)
+		evaluated := false
+		if s, ok := n.properties[k]; ok {
+			s.validate(obj[k], child, out)
+			evaluated = true
+		}
+		for _, pp := range n.patternProps {
+			if pp.re.MatchString(k) {
+				pp.schema.validate(obj[k], child, out)
+				evaluated = true
+			}
+		}
+		if !evaluated && n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("additional property %q is not allowed", k)})
+				continue
+			}
+			n.additional.validate(obj[k], child, out)
+		}
+	}
+}
+
+func (n *node) validateArray(arr []any, path string, out *[]Violation) {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("has %d items, fewer than minItems %d", len(arr), *n.minItems)})
+	}
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("has %d items, more than maxItems %d", len(arr), *n.maxItems)})
+	}
+
+	for i, item := range arr {
+		child := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(n.prefixItems) {
+			n.prefixItems[i].validate(item, child, out)
+		} else if n.items != nil {
+			n.items.validate(item, child, out)
+		}
+	}
+
+	if n.uniqueItems {
+		for i := 1; i < len(arr); i++ {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("items %d and %d are equal, items must be unique", j, i)})
+				}
+			}
+		}
+	}
+
+	if n.contains != nil {
+		count := 0
+		for _, item := range arr {
+			if n.contains.matches(item) {
+				count++
+			}
+		}
+		min := 1
+		if n.minContains != nil {
+			min = *n.minContains
+		}
+		if count < min {
+			*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("contains %d matching items, expected at least %d", count, min)})
+		}
+		if n.maxContains != nil && count > *n.maxContains {
+			*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("contains %d matching items, expected at most %d", count, *n.maxContains)})
+		}
+	}
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// childPath appends an object key to a JSONPath-style path.
+func childPath(path, key string) string {
+	if identRe.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+func typeMatches(types []string, v any) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a decoded value.
+// Whole numbers report "integer", which also satisfies "number".
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+func containsValue(values []any, v any) bool {
+	for _, candidate := range values {
+		if equal(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// describe renders a value for violation messages.
+func describe(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
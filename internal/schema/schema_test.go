@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const orderSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["id", "status", "items"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "status": {"enum": ["pending", "paid", "shipped"]},
+    "note": {"type": ["string", "null"], "maxLength": 10},
+    "items": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"$ref": "#/$defs/item"}
+    }
+  },
+  "$defs": {
+    "item": {
+      "type": "object",
+      "required": ["sku", "price"],
+      "properties": {
+        "sku": {"type": "string", "pattern": "^[A-Z]{3}-\\d+$"},
+        "price": {"type": "number", "exclusiveMinimum": 0}
+      }
+    }
+  }
+}`
+
+func TestValidate_Order(t *testing.T) {
+	s, err := Compile([]byte(orderSchema))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"id": 1, "status": "paid", "note": null, "items": [{"sku": "ABC-1", "price": 9.5}]}`,
+		},
+		{
+			name: "null under contention",
+			doc:  `{"id": null, "status": "paid", "items": [{"sku": "ABC-1", "price": 9.5}]}`,
+			want: []string{"$.id: expected integer, got null"},
+		},
+		{
+			name: "missing required and extra property",
+			doc:  `{"id": 2, "items": [], "extra": true}`,
+			want: []string{
+				`$: missing required property "status"`,
+				`$: additional property "extra" is not allowed`,
+				"$.items: has 0 items, fewer than minItems 1",
+			},
+		},
+		{
+			name: "nested item violations",
+			doc:  `{"id": 3, "status": "lost", "items": [{"sku": "abc", "price": 0}, {"price": 1}]}`,
+			want: []string{
+				`$.items[0].price: 0 must be greater than 0`,
+				`$.items[0].sku: does not match pattern "^[A-Z]{3}-\\d+$"`,
+				`$.items[1]: missing required property "sku"`,
+				`$.status: value "lost" is not one of the allowed values`,
+			},
+		},
+		{
+			name: "not an object",
+			doc:  `[1, 2]`,
+			want: []string{"$: expected object, got array"},
+		},
+		{
+			name: "invalid json",
+			doc:  `{"id":`,
+			want: []string{"$: invalid JSON"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := s.ValidateJSON([]byte(tt.doc))
+			if len(violations) != len(tt.want) {
+				t.Fatalf("expected %d violations, got %d: %v", len(tt.want), len(violations), violations)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(violations[i].String(), want) {
+					t.Errorf("violation %d: expected %q, got %q", i, want, violations[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_Keywords(t *testing.T) {
+	tests := []struct {
+		schema string
+		doc    string
+		valid  bool
+	}{
+		{`{"type": "integer"}`, `3`, true},
+		{`{"type": "integer"}`, `3.5`, false},
+		{`{"type": "number"}`, `3`, true},
+		{`{"const": {"a": [1]}}`, `{"a": [1]}`, true},
+		{`{"const": "x"}`, `"y"`, false},
+		{`{"minLength": 2, "maxLength": 3}`, `"héé"`, true},
+		{`{"minLength": 2}`, `"a"`, false},
+		{`{"multipleOf": 0.1}`, `0.3`, true},
+		{`{"multipleOf": 2}`, `3`, false},
+		{`{"maximum": 5}`, `5`, true},
+		{`{"exclusiveMaximum": 5}`, `5`, false},
+		{`{"anyOf": [{"type": "string"}, {"type": "null"}]}`, `null`, true},
+		{`{"anyOf": [{"type": "string"}, {"type": "null"}]}`, `1`, false},
+		{`{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, false},
+		{`{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1.5`, true},
+		{`{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, `3`, false},
+		{`{"not": {"type": "null"}}`, `null`, false},
+		{`{"if": {"properties": {"kind": {"const": "card"}}}, "then": {"required": ["last4"]}}`, `{"kind": "card"}`, false},
+		{`{"if": {"properties": {"kind": {"const": "card"}}}, "then": {"required": ["last4"]}}`, `{"kind": "cash"}`, true},
+		{`{"prefixItems": [{"type": "string"}], "items": {"type": "number"}}`, `["a", 1, 2]`, true},
+		{`{"prefixItems": [{"type": "string"}], "items": {"type": "number"}}`, `["a", "b"]`, false},
+		{`{"uniqueItems": true}`, `[1, 2, 1]`, false},
+		{`{"contains": {"const": 2}}`, `[1, 3]`, false},
+		{`{"contains": {"const": 2}, "maxContains": 1}`, `[2, 2]`, false},
+		{`{"minProperties": 1}`, `{}`, false},
+		{`{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, `{"x-a": "ok"}`, true},
+		{`{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, `{"y": "no"}`, false},
+		{`{"additionalProperties": {"type": "integer"}}`, `{"a": "1"}`, false},
+		{`false`, `1`, false},
+		{`true`, `1`, true},
+		{`{"type": "object", "properties": {"next": {"$ref": "#"}}}`, `{"next": {"next": {}}}`, true},
+		{`{"type": "object", "properties": {"next": {"$ref": "#"}}}`, `{"next": {"next": 1}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema+" "+tt.doc, func(t *testing.T) {
+			s, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			violations := s.ValidateJSON([]byte(tt.doc))
+			if valid := len(violations) == 0; valid != tt.valid {
+				t.Errorf("expected valid=%v, got violations %v", tt.valid, violations)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		schema  string
+		wantErr string
+	}{
+		{`{`, "parsing schema"},
+		{`"string"`, "must be an object or boolean"},
+		{`{"type": "decimal"}`, `unknown type "decimal"`},
+		{`{"pattern": "["}`, "#/pattern"},
+		{`{"$ref": "other.json#/x"}`, "only local references"},
+		{`{"$ref": "#/$defs/missing"}`, "not found"},
+		{`{"unevaluatedProperties": false}`, `keyword "unevaluatedProperties" is not supported`},
+		{`{"minItems": -1}`, "non-negative integer"},
+		{`{"multipleOf": 0}`, "greater than 0"},
+		{`{"$defs": {"bad": {"type": 1}}}`, "#/$defs/bad/type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "order.json")
+	if err := os.WriteFile(path, []byte(orderSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,188 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"maestro/internal/core"
+)
+
+// Template is text whose placeholders were parsed once, so rendering it
+// for every request only evaluates. A Template is immutable and safe for
+// concurrent use.
+type Template struct {
+	text     string
+	segments []segment
+}
+
+// segment is literal text or, when ph is set, a placeholder; text then
+// holds the whole ${...}, which is kept in the output if it fails.
+type segment struct {
+	text string
+	ph   *placeholder
+}
+
+// placeholder is a compiled ${value | filter...}.
+type placeholder struct {
+	value   valueRef
+	filters []filterStep
+	// err is a syntax error in the filters, returned on every render.
+	err error
+}
+
+// valueRef is the compiled value part of a placeholder: an environment
+// variable, a built-in function call or a variable or expression.
+type valueRef interface {
+	eval(vars core.Variables) (any, error)
+}
+
+// Compile parses the placeholders in text. It reports syntax errors, such
+// as unknown filters or functions and malformed expressions, that
+// Substitute would only hit at render time.
+func Compile(text string) (*Template, error) {
+	t, errs := compile(text)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return t, nil
+}
+
+// compile parses text and collects syntax errors. The template is usable
+// even with errors: a placeholder that does not parse may still name a
+// variable, so it only fails at render time when no such variable exists.
+func compile(text string) (*Template, []error) {
+	t := &Template{text: text}
+	if !strings.Contains(text, "${") {
+		t.segments = []segment{{text: text}}
+		return t, nil
+	}
+
+	var errs []error
+	last := 0
+	for _, m := range varPattern.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > last {
+			t.segments = append(t.segments, segment{text: text[last:m[0]]})
+		}
+		match := text[m[0]:m[1]]
+		ph, err := compilePlaceholder(text[m[2]:m[3]])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", match, err))
+		}
+		t.segments = append(t.segments, segment{text: match, ph: ph})
+		last = m[1]
+	}
+	if last < len(text) {
+		t.segments = append(t.segments, segment{text: text[last:]})
+	}
+	return t, errs
+}
+
+func compilePlaceholder(expr string) (*placeholder, error) {
+	pipes := splitPipes(expr)
+	value, err := compileValue(pipes[0])
+	ph := &placeholder{value: value}
+	if len(pipes) > 1 {
+		if ph.filters, ph.err = compileFilters(pipes[1:]); ph.err != nil {
+			return ph, ph.err
+		}
+	}
+	return ph, err
+}
+
+// compileValue compiles the value part of a placeholder, checked in the
+// order ${env:NAME}, ${func(args)}, then a variable or expression.
+func compileValue(expr string) (valueRef, error) {
+	if name, ok := strings.CutPrefix(expr, "env:"); ok {
+		return envRef(name), nil
+	}
+	if call, ok := compileCall(expr); ok {
+		return call, call.err
+	}
+	node, err := parseExpr(expr)
+	return &exprRef{text: expr, node: node}, err
+}
+
+// String returns the text the template was compiled from.
+func (t *Template) String() string {
+	return t.text
+}
+
+// Execute renders the template. Like Substitute, it returns the errors of
+// all failed placeholders joined.
+func (t *Template) Execute(vars core.Variables) (string, error) {
+	if len(t.segments) == 1 && t.segments[0].ph == nil {
+		return t.text, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(t.text))
+	var errs []error
+	for _, seg := range t.segments {
+		if seg.ph == nil {
+			b.WriteString(seg.text)
+			continue
+		}
+		v, err := seg.ph.render(vars)
+		if err != nil {
+			errs = append(errs, err)
+			b.WriteString(seg.text)
+			continue
+		}
+		b.WriteString(formatValue(v))
+	}
+	if len(errs) > 0 {
+		return "", errors.Join(errs...)
+	}
+	return b.String(), nil
+}
+
+// Value renders the template like Execute, except that a template that is
+// exactly one placeholder returns the placeholder's value unformatted, so
+// numbers, booleans, maps and arrays keep their type.
+func (t *Template) Value(vars core.Variables) (any, error) {
+	if len(t.segments) == 1 && t.segments[0].ph != nil {
+		return t.segments[0].ph.render(vars)
+	}
+	return t.Execute(vars)
+}
+
+func (p *placeholder) render(vars core.Variables) (any, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	v, err := p.value.eval(vars)
+	if len(p.filters) > 0 {
+		v, err = applyFilters(v, err, p.filters)
+	}
+	return v, err
+}
+
+// envRef is ${env:NAME}. The variable is read at render time.
+type envRef string
+
+func (r envRef) eval(core.Variables) (any, error) {
+	if val, ok := os.LookupEnv(string(r)); ok {
+		return val, nil
+	}
+	return nil, fmt.Errorf("env var %q not set", string(r))
+}
+
+// exprRef is a variable or expression. A variable whose name is the whole
+// text wins, so names such as data.users.id or my-var resolve directly.
+type exprRef struct {
+	text string
+	node exprNode // nil when text does not parse
+}
+
+func (r *exprRef) eval(vars core.Variables) (any, error) {
+	if val, ok := vars.Get(r.text); ok {
+		return val, nil
+	}
+	if r.node == nil {
+		// Not an expression: report it as the variable name it most likely is
+		return nil, fmt.Errorf("variable %q not found", r.text)
+	}
+	return r.node.eval(vars)
+}
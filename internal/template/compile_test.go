@@ -0,0 +1,136 @@
+package template
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"maestro/internal/core"
+)
+
+func TestCompile_MatchesSubstitute(t *testing.T) {
+	os.Setenv("TEST_COMPILE_REGION", "eu-west")
+	defer os.Unsetenv("TEST_COMPILE_REGION")
+
+	vars := core.NewVariables()
+	vars.Set("base", "https://api.example.com")
+	vars.Set("user", map[string]any{"id": "u-7", "tags": []any{"a", "b"}})
+	vars.Set("price", 2.5)
+	vars.Set("qty", 4)
+	vars.Set("data.users.name", "alice")
+
+	texts := []string{
+		"",
+		"static text",
+		"${base}/users/${user.id}",
+		"${price * qty} items, ${user.tags | json}",
+		"${env:TEST_COMPILE_REGION | upper}",
+		"${sha256(user.id)}:${hmac_sha256('k', base + '/x', base64)}",
+		"${data.users.name}",
+		"${missing | default: 'none'}",
+		`{"id": "${user.id}", "tags": ${user.tags}}`,
+		"${date(2006)}",
+		"${}",
+	}
+	for _, text := range texts {
+		want, wantErr := Substitute(text, vars)
+		tmpl, err := Compile(text)
+		if err != nil {
+			t.Fatalf("%q: unexpected compile error: %v", text, err)
+		}
+		got, gotErr := tmpl.Execute(vars)
+		if got != want || (gotErr == nil) != (wantErr == nil) {
+			t.Errorf("%q: expected %q (%v), got %q (%v)", text, want, wantErr, got, gotErr)
+		}
+		if tmpl.String() != text {
+			t.Errorf("%q: String() returned %q", text, tmpl.String())
+		}
+	}
+}
+
+func TestCompile_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"${name | reverse}", `${name | reverse}: unknown filter "reverse"`},
+		{"${name | upper: 1}", "filter upper takes no argument"},
+		{"${name | default}", "filter default requires a value"},
+		{"${nope(1)}", `unknown function "nope"`},
+		{"${price *}", "unexpected end of expression"},
+		{"${sha256(x}", "expression"},
+		{"${'unterminated}", "expression"},
+		{"ok ${a} then ${b +} and ${c | bogus}", "${b +}"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.text)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.text, tt.want, err)
+		}
+	}
+
+	_, err := Compile("ok ${a} then ${b +} and ${c | bogus}")
+	if !strings.Contains(err.Error(), `unknown filter "bogus"`) {
+		t.Errorf("expected every syntax error to be reported, got %v", err)
+	}
+}
+
+func TestTemplate_RenderErrors(t *testing.T) {
+	tmpl, err := Compile("${a}-${b}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := core.NewVariables()
+	vars.Set("a", "x")
+
+	_, err = tmpl.Execute(vars)
+	if err == nil || !strings.Contains(err.Error(), `variable "b" not found`) {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+
+	vars.Set("b", "y")
+	if got, err := tmpl.Execute(vars); err != nil || got != "x-y" {
+		t.Errorf("expected x-y once b is set, got %q (%v)", got, err)
+	}
+}
+
+func TestSubstitute_UnparsedNameIsVariable(t *testing.T) {
+	// Substitute stays lenient: a placeholder that is no valid expression
+	// still resolves when a variable has exactly that name
+	vars := core.NewVariables()
+	vars.Set("first name", "Ada")
+
+	if got, err := Substitute("${first name}", vars); err != nil || got != "Ada" {
+		t.Errorf("expected Ada, got %q (%v)", got, err)
+	}
+	if _, err := Substitute("${last name}", vars); err == nil || !strings.Contains(err.Error(), `variable "last name" not found`) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestTemplate_ConcurrentExecute(t *testing.T) {
+	tmpl, err := Compile("${prefix}-${seq()}-${n * 2}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			vars := core.NewVariables()
+			vars.Set("prefix", "p")
+			vars.Set("n", n)
+			for j := 0; j < 100; j++ {
+				got, err := tmpl.Execute(vars)
+				if err != nil || !strings.HasPrefix(got, "p-") {
+					t.Errorf("unexpected result %q (%v)", got, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,117 @@
+package template
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// Digest functions return lowercase hex by default. An optional last
+// argument selects another encoding of the raw digest: hex, base64 or
+// base64url (unpadded, as used by JWTs).
+//
+//   - sha256(request.body) -> e3b0c442...
+//   - hmac_sha256(env:SECRET, request.body, base64) -> 9r7r...=
+
+// fnSHA256 hashes its argument with SHA-256.
+// Usage: sha256(message[, encoding])
+func fnSHA256(args []string) (string, error) {
+	return digest("sha256", sha256.New, args)
+}
+
+// fnSHA1 hashes its argument with SHA-1.
+// Usage: sha1(message[, encoding])
+func fnSHA1(args []string) (string, error) {
+	return digest("sha1", sha1.New, args)
+}
+
+// fnMD5 hashes its argument with MD5.
+// Usage: md5(message[, encoding])
+func fnMD5(args []string) (string, error) {
+	return digest("md5", md5.New, args)
+}
+
+// fnHMACSHA256 signs a message with HMAC-SHA256.
+// Usage: hmac_sha256(key, message[, encoding])
+func fnHMACSHA256(args []string) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", fmt.Errorf("hmac_sha256(key, message[, encoding]) requires 2 or 3 arguments")
+	}
+	mac := hmac.New(sha256.New, []byte(args[0]))
+	mac.Write([]byte(args[1]))
+	return encodeDigest(mac.Sum(nil), args[2:])
+}
+
+func digest(name string, newHash func() hash.Hash, args []string) (string, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return "", fmt.Errorf("%s(message[, encoding]) requires 1 or 2 arguments", name)
+	}
+	h := newHash()
+	h.Write([]byte(args[0]))
+	return encodeDigest(h.Sum(nil), args[1:])
+}
+
+// encodeDigest renders a digest in the encoding named by the optional
+// argument.
+func encodeDigest(sum []byte, encoding []string) (string, error) {
+	if len(encoding) == 0 {
+		return hex.EncodeToString(sum), nil
+	}
+	switch encoding[0] {
+	case "hex":
+		return hex.EncodeToString(sum), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(sum), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	}
+	return "", fmt.Errorf("unknown encoding %q (want hex, base64 or base64url)", encoding[0])
+}
+
+// fnBase64Encode encodes its argument with standard, padded base64.
+// Usage: base64_encode(text)
+func fnBase64Encode(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("base64_encode(text) requires exactly 1 argument")
+	}
+	return base64.StdEncoding.EncodeToString([]byte(args[0])), nil
+}
+
+// fnBase64Decode decodes standard or URL-safe base64, padded or not.
+// Usage: base64_decode(text)
+func fnBase64Decode(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("base64_decode(text) requires exactly 1 argument")
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if b, err := enc.DecodeString(args[0]); err == nil {
+			return string(b), nil
+		}
+	}
+	return "", fmt.Errorf("invalid base64 %q", args[0])
+}
+
+// fnURLBase64 encodes its argument with unpadded, URL-safe base64.
+// Usage: url_base64(text)
+func fnURLBase64(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("url_base64(text) requires exactly 1 argument")
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(args[0])), nil
+}
+
+// fnHex encodes the bytes of its argument as lowercase hex.
+// Usage: hex(text)
+func fnHex(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("hex(text) requires exactly 1 argument")
+	}
+	return hex.EncodeToString([]byte(args[0])), nil
+}
@@ -0,0 +1,101 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"maestro/internal/core"
+)
+
+func TestCryptoFunctions(t *testing.T) {
+	vars := core.NewVariables()
+	vars.Set("secret", "key")
+	vars.Set("message", "The quick brown fox jumps over the lazy dog")
+	vars.Set("body", `{"a": 1, "b": 2}`)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${sha256('abc')}", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"${sha1('abc')}", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"${md5('abc')}", "900150983cd24fb0d6963f7d28e17f72"},
+		{"${sha256(body)}", "d8497d9d82770a70729261095aa98f7ef5154d7af499f8037b6ca250296785a6"},
+		{"${sha256(md5('abc'))}", "2c89b7e560fb8c30d1c61408e91e4a84934ff0d24e68e51a6fdb744a1bb717fe"},
+		{"${hmac_sha256(secret, message)}", "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{"${hmac_sha256(secret, message, base64)}", "97yD9DBThCSxMpjmqm+xQ+9NWaFJRhdZl0edvC0aPNg="},
+		{"${hmac_sha256(secret, message, base64url)}", "97yD9DBThCSxMpjmqm-xQ-9NWaFJRhdZl0edvC0aPNg"},
+		{"${base64_encode('user:pass')}", "dXNlcjpwYXNz"},
+		{"${base64_decode(dXNlcjpwYXNz)}", "user:pass"},
+		{"${base64_decode(dXNlcjpwYXNz) | upper}", "USER:PASS"},
+		{"${base64_decode('PDw_Pz4-')}", "<<??>>"}, // URL-safe alphabet, unpadded
+		{"${url_base64('<<??>>')}", "PDw_Pz4-"},
+		{"${hex('hi')}", "6869"},
+		{"${'sig=' + sha1('abc')}", "sig=a9993e364706816aba3e25717850c26c9cd0d89d"},
+	}
+	for _, tt := range tests {
+		got, err := Substitute(tt.input, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestCryptoFunctions_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${sha256()}", "requires 1 or 2 arguments"},
+		{"${sha256('a', b64)}", `unknown encoding "b64"`},
+		{"${hmac_sha256('key')}", "requires 2 or 3 arguments"},
+		{"${base64_decode('***')}", "invalid base64"},
+		{"${hex('a', 'b')}", "requires exactly 1 argument"},
+		{"${hmac_sha256(key, body)}", `argument 1: variable "key" not found`},
+		{"${sha256(usr_id)}", `argument 1: variable "usr_id" not found`},
+		{"${sha256(user.id)}", `argument 1: variable "user.id" not found`},
+		{"${jwt_sign(HS256, 'k.pem', sub=usr_id)}", `argument 3: variable "usr_id" not found`},
+		{"${base64_encode(api_kye)}", `argument 1: variable "api_kye" not found`},
+		{"${hex(user.nme)}", `argument 1: variable "user.nme" not found`},
+	}
+	for _, tt := range tests {
+		_, err := Substitute(tt.input, core.NewVariables())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestCryptoFunctions_UnquotedLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${sha256(42)}", `argument 1: "42" must be quoted`},
+		{"${md5(2024-01-31)}", `argument 1: "2024-01-31" must be quoted`},
+		{"${hmac_sha256(env:SECRET, a/b)}", `argument 2: "a/b" must be quoted`},
+		{"${base64_encode(sha256(42))}", `function sha256: argument 1: "42" must be quoted`},
+		{"${url_base64(a/b)}", `argument 1: "a/b" must be quoted`},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected compile error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+
+	// Encodings and JWT algorithms, key files and relative times stay unquoted
+	for _, input := range []string{
+		"${sha256('abc', base64)}",
+		"${hmac_sha256(env:SECRET, request.body, base64url)}",
+		"${jwt_sign(RS256, keys/private.pem, sub=user.id, exp=+1h)}",
+	} {
+		if _, err := Compile(input); err != nil {
+			t.Errorf("%s: unexpected error: %v", input, err)
+		}
+	}
+}
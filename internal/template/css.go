@@ -0,0 +1,420 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled CSS selector.
+//
+// Supported syntax: type (input) and universal (*) selectors, #id and
+// .class, attribute selectors ([name], [name=v], [name="v"] and the ~=
+// |= ^= $= *= operators), the :first-child, :last-child, :nth-child(n),
+// :nth-child(odd|even) and :not(simple) pseudo-classes, the descendant,
+// child (>), adjacent (+) and sibling (~) combinators, and comma-separated
+// groups.
+//
+// A selector yields the trimmed text of the first matching element in
+// document order. A trailing @name yields that attribute instead, from the
+// first matching element that has it: input[name=csrf]@value.
+type Selector struct {
+	src    string
+	groups [][]cssCompound
+	attr   string
+}
+
+type cssCompound struct {
+	combinator byte // how it relates to the previous compound: ' ', '>', '+', '~'
+	tag        string
+	conds      []cssCond
+}
+
+// cssCond is a condition on one element besides its tag: an attribute
+// test (#id and .class are attribute tests too), a structural
+// pseudo-class or a negation.
+type cssCond struct {
+	attr, op, value string
+	nth             int // :nth-child(n); -1 odd, -2 even, -3 last-child
+	not             *cssCompound
+}
+
+// ParseSelector compiles a CSS selector, rejecting syntax it does not
+// support.
+func ParseSelector(src string) (*Selector, error) {
+	s := strings.TrimSpace(src)
+	if s == "" {
+		return nil, errors.New("empty CSS selector")
+	}
+	p := &cssParser{jpParser{src: s}}
+	sel, err := p.parseSelector()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSS selector %q: %w", src, err)
+	}
+	sel.src = src
+	return sel, nil
+}
+
+func (s *Selector) String() string { return s.src }
+
+// Select evaluates the selector against a document and reports whether
+// anything was selected.
+func (s *Selector) Select(doc *Document) (any, bool) {
+	for _, el := range doc.elements() {
+		if !s.matches(doc, el) {
+			continue
+		}
+		if s.attr == "" {
+			return strings.TrimSpace(el.stringValue()), true
+		}
+		if v, ok := doc.attr(el, s.attr); ok {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return nil, false
+}
+
+func (s *Selector) matches(doc *Document, el *node) bool {
+	for _, group := range s.groups {
+		if matchComplex(doc, el, group, len(group)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchComplex matches compounds[:i+1] right to left, el being matched
+// against compounds[i].
+func matchComplex(doc *Document, el *node, compounds []cssCompound, i int) bool {
+	c := &compounds[i]
+	if !c.matches(doc, el) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c.combinator {
+	case '>':
+		p := el.parent
+		return p.kind == elementNode && matchComplex(doc, p, compounds, i-1)
+	case '+':
+		prev := previousElement(el)
+		return prev != nil && matchComplex(doc, prev, compounds, i-1)
+	case '~':
+		for prev := previousElement(el); prev != nil; prev = previousElement(prev) {
+			if matchComplex(doc, prev, compounds, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+	for p := el.parent; p.kind == elementNode; p = p.parent {
+		if matchComplex(doc, p, compounds, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cssCompound) matches(doc *Document, el *node) bool {
+	if c.tag != "*" && !doc.nameMatches(el.name, c.tag) {
+		return false
+	}
+	for _, cond := range c.conds {
+		if !cond.matches(doc, el) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *cssCond) matches(doc *Document, el *node) bool {
+	switch {
+	case c.not != nil:
+		return !c.not.matches(doc, el)
+	case c.nth != 0:
+		return matchNth(c.nth, el)
+	}
+
+	v, ok := doc.attr(el, c.attr)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "":
+		return true
+	case "=":
+		return v == c.value
+	case "~=":
+		return containsString(strings.Fields(v), c.value)
+	case "|=":
+		return v == c.value || strings.HasPrefix(v, c.value+"-")
+	case "^=":
+		return c.value != "" && strings.HasPrefix(v, c.value)
+	case "$=":
+		return c.value != "" && strings.HasSuffix(v, c.value)
+	}
+	return c.value != "" && strings.Contains(v, c.value) // *=
+}
+
+// matchNth checks an element's position among its element siblings.
+func matchNth(nth int, el *node) bool {
+	pos, count := 0, 0
+	for _, sibling := range el.parent.children {
+		if sibling.kind != elementNode {
+			continue
+		}
+		count++
+		if sibling == el {
+			pos = count
+		}
+	}
+	switch nth {
+	case -1:
+		return pos%2 == 1
+	case -2:
+		return pos%2 == 0
+	case -3:
+		return pos == count
+	}
+	return pos == nth
+}
+
+func previousElement(el *node) *node {
+	var prev *node
+	for _, sibling := range el.parent.children {
+		if sibling == el {
+			return prev
+		}
+		if sibling.kind == elementNode {
+			prev = sibling
+		}
+	}
+	return nil
+}
+
+// Parsing
+
+type cssParser struct {
+	jpParser
+}
+
+func (p *cssParser) parseSelector() (*Selector, error) {
+	sel := &Selector{}
+	for {
+		group, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		sel.groups = append(sel.groups, group)
+		p.skipSpace()
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+		p.skipSpace()
+	}
+
+	if p.peek() == '@' {
+		p.pos++
+		sel.attr = p.ident()
+		if sel.attr == "" {
+			return nil, p.errorf("missing attribute name after @")
+		}
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return sel, nil
+}
+
+// parseComplex parses compounds joined by combinators, up to a comma, an
+// @attribute or the end.
+func (p *cssParser) parseComplex() ([]cssCompound, error) {
+	var compounds []cssCompound
+	combinator := byte(0)
+	for {
+		c, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		c.combinator = combinator
+		compounds = append(compounds, c)
+
+		start := p.pos
+		p.skipSpace()
+		switch next := p.peek(); {
+		case next == '>' || next == '+' || next == '~':
+			combinator = next
+			p.pos++
+			p.skipSpace()
+		case next == 0 || next == ',' || next == '@':
+			return compounds, nil
+		case p.pos > start:
+			combinator = ' '
+		default:
+			return nil, p.errorf("unexpected %q", next)
+		}
+	}
+}
+
+func (p *cssParser) parseCompound() (cssCompound, error) {
+	c := cssCompound{tag: "*"}
+	empty := false
+	switch {
+	case p.peek() == '*':
+		p.pos++
+	case isCSSIdentChar(p.peek()):
+		c.tag = p.ident()
+	default:
+		empty = true
+	}
+
+	for {
+		var cond cssCond
+		switch p.peek() {
+		case '#':
+			p.pos++
+			cond = cssCond{attr: "id", op: "=", value: p.ident()}
+			if cond.value == "" {
+				return c, p.errorf("missing id after #")
+			}
+		case '.':
+			p.pos++
+			cond = cssCond{attr: "class", op: "~=", value: p.ident()}
+			if cond.value == "" {
+				return c, p.errorf("missing class after .")
+			}
+		case '[':
+			var err error
+			if cond, err = p.parseAttribute(); err != nil {
+				return c, err
+			}
+		case ':':
+			var err error
+			if cond, err = p.parsePseudo(); err != nil {
+				return c, err
+			}
+		default:
+			if empty && len(c.conds) == 0 {
+				if p.pos >= len(p.src) {
+					return c, p.errorf("missing selector")
+				}
+				return c, p.errorf("unexpected %q", p.src[p.pos])
+			}
+			return c, nil
+		}
+		c.conds = append(c.conds, cond)
+	}
+}
+
+var cssAttrOps = []string{"~=", "|=", "^=", "$=", "*=", "="}
+
+func (p *cssParser) parseAttribute() (cssCond, error) {
+	p.pos++ // [
+	p.skipSpace()
+	cond := cssCond{attr: p.ident()}
+	if cond.attr == "" {
+		return cond, p.errorf("missing attribute name")
+	}
+	p.skipSpace()
+	for _, op := range cssAttrOps {
+		if p.hasPrefix(op) {
+			cond.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if cond.op != "" {
+		p.skipSpace()
+		if c := p.peek(); c == '"' || c == '\'' {
+			value, err := p.parseString()
+			if err != nil {
+				return cond, err
+			}
+			cond.value = value
+		} else if cond.value = p.ident(); cond.value == "" {
+			return cond, p.errorf("missing attribute value")
+		}
+		p.skipSpace()
+	}
+	if p.peek() != ']' {
+		return cond, p.errorf("missing ]")
+	}
+	p.pos++
+	return cond, nil
+}
+
+func (p *cssParser) parsePseudo() (cssCond, error) {
+	p.pos++ // :
+	name := strings.ToLower(p.ident())
+	switch name {
+	case "first-child":
+		return cssCond{nth: 1}, nil
+	case "last-child":
+		return cssCond{nth: -3}, nil
+	case "nth-child", "not":
+	default:
+		return cssCond{}, p.errorf("unsupported pseudo-class :%s", name)
+	}
+
+	if p.peek() != '(' {
+		return cssCond{}, p.errorf("missing ( after :%s", name)
+	}
+	p.pos++
+	p.skipSpace()
+	var cond cssCond
+	if name == "not" {
+		if err := p.enter(); err != nil {
+			return cond, err
+		}
+		inner, err := p.parseCompound()
+		p.leave()
+		if err != nil {
+			return cond, err
+		}
+		cond.not = &inner
+	} else {
+		arg := strings.ToLower(p.ident())
+		switch arg {
+		case "odd":
+			cond.nth = -1
+		case "even":
+			cond.nth = -2
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return cond, p.errorf("unsupported :nth-child argument %q", arg)
+			}
+			cond.nth = n
+		}
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return cond, p.errorf("missing )")
+	}
+	p.pos++
+	return cond, nil
+}
+
+// ident reads a CSS identifier, taking backslash escapes literally.
+func (p *cssParser) ident() string {
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			sb.WriteByte(p.src[p.pos])
+		case isCSSIdentChar(c):
+			sb.WriteByte(c)
+		default:
+			return sb.String()
+		}
+		p.pos++
+	}
+	return sb.String()
+}
+
+func isCSSIdentChar(c byte) bool { return isIdentChar(c) || c == '-' || c >= 0x80 }
@@ -0,0 +1,136 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+const loginHTML = `<!DOCTYPE html>
+<html>
+<head><title>Sign in &amp; continue</title>
+<script>if (a < b && c > d) { document.write("<div id=fake>") }</script>
+</head>
+<body class="page login">
+  <nav><ul class="menu"><li><a href="/">Home</a><li class="active"><a href="/login">Login</a><li><a href="/help">Help</a></ul></nav>
+  <form id="login-form" action="/session" method=post>
+    <input type="hidden" name="csrf" value="tok-42">
+    <input type="text" name="user" data-role="user-name" required>
+    <input type=hidden name=nonce value='n&#39;1'>
+    <button class="btn btn-primary">Sign in</button>
+  </form>
+  <p class="notice">First<p class="notice warning">Second</p>
+  <!-- <input name="csrf" value="commented-out"> -->
+</body>
+</html>`
+
+func TestSelector_Select(t *testing.T) {
+	doc := ParseHTML([]byte(loginHTML))
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"title", "Sign in & continue"},
+		{"input[name=csrf]@value", "tok-42"},
+		{`input[name="csrf"]@value`, "tok-42"},
+		{"form#login-form input[type=hidden]@name", "csrf"},
+		{"input[name=nonce]@value", "n'1"},
+		{"#login-form@action", "/session"},
+		{"form@method", "post"},
+		{"input@value", "tok-42"}, // first input that has the attribute
+		{"input[required]@name", "user"},
+		{"[data-role^=user]@name", "user"},
+		{"[data-role$=name]@name", "user"},
+		{"[data-role*=r-n]@name", "user"},
+		{"[data-role|=user]@name", "user"},
+		{"button.btn-primary", "Sign in"},
+		{".btn.btn-primary", "Sign in"},
+		{"body.login > form > button", "Sign in"},
+		{"ul.menu li.active a@href", "/login"},
+		{"li:first-child a", "Home"},
+		{"li:last-child a", "Help"},
+		{"li:nth-child(2) a", "Login"},
+		{"li:nth-child(even) a", "Login"},
+		{"li:not(.active) + li a", "Login"},
+		{"li.active ~ li a", "Help"},
+		{"p.notice", "First"},
+		{"p.warning", "Second"},
+		{"nav a, form button", "Home"},
+		{"missing, button", "Sign in"},
+		{"INPUT[NAME=user]@Data-Role", "user-name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			s, err := ParseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, ok := s.Select(doc)
+			if !ok || got != tt.want {
+				t.Errorf("expected %q, got %#v (ok=%v)", tt.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestSelector_NotFound(t *testing.T) {
+	doc := ParseHTML([]byte(loginHTML))
+	for _, selector := range []string{
+		"div#fake", // inside a script
+		"input[name=csrf]@missing",
+		"form > li",
+		"li:nth-child(4)",
+		"[data-role^='']",
+		"input[value=commented-out]",
+	} {
+		s, err := ParseSelector(selector)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", selector, err)
+		}
+		if got, ok := s.Select(doc); ok {
+			t.Errorf("%s: expected no match, got %#v", selector, got)
+		}
+	}
+}
+
+func TestSelector_XML(t *testing.T) {
+	doc, err := ParseXML([]byte(soapXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := ParseSelector("Order[status=closed] > Item")
+	if got, ok := s.Select(doc); !ok || got != "ink" {
+		t.Errorf("expected ink, got %#v", got)
+	}
+	// XML names are case-sensitive
+	s, _ = ParseSelector("order")
+	if got, ok := s.Select(doc); ok {
+		t.Errorf("expected no match, got %#v", got)
+	}
+}
+
+func TestParseSelector_Errors(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"", "empty CSS selector"},
+		{"input[name=csrf", "missing ]"},
+		{"input[=x]", "missing attribute name"},
+		{"input[name=]", "missing attribute value"},
+		{"input@", "missing attribute name after @"},
+		{"input@value x", "unexpected ' '"},
+		{"a:hover", "unsupported pseudo-class :hover"},
+		{"li:nth-child(2n+1)", "unsupported :nth-child argument"},
+		{"a >", "missing selector"},
+		{"a, ,b", "unexpected ','"},
+		{"#", "missing id"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSelector(tt.selector)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.selector, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package template
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// Embedded zone data makes tz= work on hosts without zoneinfo
+	_ "time/tzdata"
+
+	"maestro/internal/core"
+)
+
+// Date functions share one clock and a small set of options. Layouts are
+// Go reference layouts (2006-01-02) or one of unix, unix_ms, rfc3339 and
+// rfc1123. Options are named arguments:
+//
+//   - tz="Europe/Berlin" renders (and truncates days and weeks) in a zone
+//   - from=created_at starts from a given time instead of now
+//   - in="02/01/2006" parses a value with a specific layout
+//
+//   - date_add(+72h, 2006-01-02) -> 2024-01-18
+//   - start_of_week(rfc3339, tz=UTC) -> 2024-01-15T00:00:00Z
+//   - date_format(created_at, unix) -> 1705312200
+
+// clock supplies "now" to every time function.
+var clock core.Clock = core.RealClock{}
+
+// SetClock replaces the clock behind the time functions, such as with a
+// core.FakeClock in tests. It must not be called while templates are
+// being substituted.
+func SetClock(c core.Clock) {
+	clock = c
+}
+
+// timeOptions are the named arguments accepted by date functions. Without
+// tz, now is local time and parsed times keep their own offset.
+type timeOptions struct {
+	loc  *time.Location
+	from string
+	in   string
+}
+
+// splitTimeArgs separates positional arguments from the named options in
+// allowed.
+func splitTimeArgs(args []string, allowed ...string) ([]string, timeOptions, error) {
+	var opts timeOptions
+	var positional []string
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || !containsString(allowed, name) {
+			positional = append(positional, arg)
+			continue
+		}
+		switch name {
+		case "tz":
+			loc, err := time.LoadLocation(value)
+			if err != nil {
+				return nil, opts, fmt.Errorf("unknown time zone %q", value)
+			}
+			opts.loc = loc
+		case "from":
+			opts.from = value
+		case "in":
+			opts.in = value
+		}
+	}
+	return positional, opts, nil
+}
+
+// base returns the time a function starts from: from= or now.
+func (o timeOptions) base() (time.Time, error) {
+	t := clock.Now()
+	if o.from != "" {
+		var err error
+		if t, err = parseTime(o.from, o.in); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if o.loc != nil {
+		t = t.In(o.loc)
+	}
+	return t, nil
+}
+
+// parseLayouts are tried in order when parsing a time without a layout.
+var parseLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// parseTime reads a Unix time (seconds or, for values beyond year 5000,
+// milliseconds), a time in one of parseLayouts, or a value in layout.
+func parseTime(value, layout string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if layout != "" {
+		switch layout {
+		case "unix", "unix_ms":
+		default:
+			t, err := time.Parse(resolveLayout(layout), value)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("parsing %q as %q: %w", value, layout, err)
+			}
+			return t, nil
+		}
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		if layout == "unix_ms" || (layout == "" && math.Abs(f) >= 1e11) {
+			return time.UnixMilli(int64(f)), nil
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	for _, l := range parseLayouts {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want a Unix time, RFC 3339 or 2006-01-02)", value)
+}
+
+// resolveLayout maps layout aliases to Go layouts.
+func resolveLayout(layout string) string {
+	switch layout {
+	case "", "rfc3339", "iso":
+		return time.RFC3339
+	case "rfc1123", "http":
+		return time.RFC1123
+	}
+	return layout
+}
+
+// formatTime renders t in layout, which may also be unix or unix_ms.
+func formatTime(t time.Time, layout string) string {
+	switch layout {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "rfc1123", "http":
+		return t.UTC().Format(http1123)
+	}
+	return t.Format(resolveLayout(layout))
+}
+
+// http1123 is RFC 1123 with the GMT zone HTTP dates require.
+const http1123 = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// offsetPattern matches one component of an offset such as +1d12h.
+var offsetPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+
+var offsetUnits = map[string]time.Duration{
+	"ns": time.Nanosecond, "us": time.Microsecond, "µs": time.Microsecond,
+	"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour,
+	"d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+}
+
+// parseOffset reads a signed duration that, unlike time.ParseDuration,
+// also accepts days (d) and weeks (w): +72h, -30m, +1d12h, +2w.
+func parseOffset(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	rest := strings.TrimLeft(s, "+-")
+	if rest == "" || len(s)-len(rest) > 1 {
+		return 0, fmt.Errorf("invalid offset %q (want a duration such as +72h or -1d)", s)
+	}
+	var total time.Duration
+	matched := 0
+	for _, m := range offsetPattern.FindAllStringSubmatch(rest, -1) {
+		matched += len(m[0])
+		n, _ := strconv.ParseFloat(m[1], 64)
+		total += time.Duration(n * float64(offsetUnits[m[2]]))
+	}
+	if matched != len(rest) {
+		return 0, fmt.Errorf("invalid offset %q (want a duration such as +72h or -1d)", s)
+	}
+	if s[0] == '-' {
+		total = -total
+	}
+	return total, nil
+}
+
+// fnDateAdd shifts now (or from=) by an offset and formats the result.
+// Usage: date_add(offset[, layout][, tz=zone][, from=time])
+func fnDateAdd(args []string) (string, error) {
+	pos, opts, err := splitTimeArgs(args, "tz", "from", "in")
+	if err != nil {
+		return "", err
+	}
+	if len(pos) != 1 && len(pos) != 2 {
+		return "", fmt.Errorf("date_add(offset[, layout]) requires 1 or 2 positional arguments")
+	}
+	offset, err := parseOffset(pos[0])
+	if err != nil {
+		return "", err
+	}
+	t, err := opts.base()
+	if err != nil {
+		return "", err
+	}
+	return formatTime(t.Add(offset), layoutArg(pos[1:])), nil
+}
+
+// fnDateFormat parses a time and formats it in another layout or zone.
+// Usage: date_format(value[, layout][, tz=zone][, in=layout])
+func fnDateFormat(args []string) (string, error) {
+	pos, opts, err := splitTimeArgs(args, "tz", "in")
+	if err != nil {
+		return "", err
+	}
+	if len(pos) != 1 && len(pos) != 2 {
+		return "", fmt.Errorf("date_format(value[, layout]) requires 1 or 2 positional arguments")
+	}
+	opts.from = pos[0]
+	if opts.from == "" {
+		return "", fmt.Errorf("empty time value")
+	}
+	t, err := opts.base()
+	if err != nil {
+		return "", err
+	}
+	return formatTime(t, layoutArg(pos[1:])), nil
+}
+
+// boundary returns a function for start_of_day and its relatives, which
+// truncate now (or from=) in the tz= zone.
+func boundary(name string, bound func(t time.Time) time.Time) func(args []string) (string, error) {
+	return func(args []string) (string, error) {
+		pos, opts, err := splitTimeArgs(args, "tz", "from", "in")
+		if err != nil {
+			return "", err
+		}
+		if len(pos) > 1 {
+			return "", fmt.Errorf("%s([layout]) takes at most 1 positional argument", name)
+		}
+		t, err := opts.base()
+		if err != nil {
+			return "", err
+		}
+		return formatTime(bound(t), layoutArg(pos)), nil
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func endOfDay(t time.Time) time.Time {
+	return startOfDay(t).AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// startOfWeek returns the start of the ISO week, which begins on Monday.
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -daysSinceMonday)
+}
+
+func endOfWeek(t time.Time) time.Time {
+	return startOfWeek(t).AddDate(0, 0, 7).Add(-time.Nanosecond)
+}
+
+func layoutArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
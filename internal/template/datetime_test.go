@@ -0,0 +1,136 @@
+package template
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/core"
+)
+
+// useFakeClock points the time functions at a fake clock for one test.
+func useFakeClock(t *testing.T, now time.Time) *core.FakeClock {
+	fake := core.NewFakeClock(now)
+	SetClock(fake)
+	t.Cleanup(func() { SetClock(core.RealClock{}) })
+	return fake
+}
+
+func TestDateFunctions(t *testing.T) {
+	// Wednesday, 17 January 2024, 14:30:00 UTC
+	useFakeClock(t, time.Date(2024, 1, 17, 14, 30, 0, 0, time.UTC))
+
+	vars := core.NewVariables()
+	vars.Set("created_at", "2024-03-10T08:15:00+02:00")
+	vars.Set("created_unix", 1710051300)
+	vars.Set("created_ms", int64(1710051300123))
+	vars.Set("us_date", "03/10/2024")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`${timestamp()}`, "1705501800"},
+		{`${timestamp(+1h)}`, "1705505400"},
+		{`${timestamp_ms(-1s)}`, "1705501799000"},
+		{`${date(2006-01-02 15:04)}`, "2024-01-17 14:30"},
+
+		{`${date_add(+72h, 2006-01-02)}`, "2024-01-20"},
+		{`${date_add("+72h", "2006-01-02")}`, "2024-01-20"},
+		{`${date_add(-1d12h, rfc3339, tz=UTC)}`, "2024-01-16T02:30:00Z"},
+		{`${date_add(+2w, unix)}`, "1706711400"},
+		{`${date_add(+30m, '15:04', tz="America/New_York")}`, "10:00"},
+		{`${date_add(+30m, '15:04', tz=America/New_York)}`, "10:00"}, // unquoted zone names are not division
+		{`${date_add(+1d, 2006-01-02, from=created_at)}`, "2024-03-11"},
+
+		{`${start_of_day(rfc3339, tz=UTC)}`, "2024-01-17T00:00:00Z"},
+		{`${end_of_day('15:04:05', tz=UTC)}`, "23:59:59"},
+		{`${start_of_week(2006-01-02, tz=UTC)}`, "2024-01-15"},
+		{`${end_of_week(rfc3339, tz=UTC)}`, "2024-01-21T23:59:59Z"},
+		{`${start_of_day(rfc3339, tz='Asia/Tokyo')}`, "2024-01-17T00:00:00+09:00"},
+		{`${start_of_week(2006-01-02, from=2024-01-14)}`, "2024-01-08"},
+		{`${start_of_day(unix, from=created_at)}`, "1710021600"},
+
+		{`${date_format(created_at, unix)}`, "1710051300"},
+		{`${date_format(created_at, 2006-01-02 15:04)}`, "2024-03-10 08:15"},
+		{`${date_format(created_at, rfc3339, tz=UTC)}`, "2024-03-10T06:15:00Z"},
+		{`${date_format(created_unix, rfc3339, tz=UTC)}`, "2024-03-10T06:15:00Z"},
+		{`${date_format(created_ms, unix_ms)}`, "1710051300123"},
+		{`${date_format(created_unix, rfc1123)}`, "Sun, 10 Mar 2024 06:15:00 GMT"},
+		{`${date_format('Sun, 10 Mar 2024 06:15:00 GMT', unix)}`, "1710051300"},
+		{`${date_format(us_date, 2006-01-02, in='01/02/2006')}`, "2024-03-10"},
+	}
+	for _, tt := range tests {
+		got, err := Substitute(tt.input, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestDateFunctions_FollowClock(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 17, 14, 30, 0, 0, time.UTC))
+	vars := core.NewVariables()
+
+	before, _ := Substitute("${date_add(+1h, rfc3339, tz=UTC)}", vars)
+	fake.Advance(24 * time.Hour)
+	after, _ := Substitute("${date_add(+1h, rfc3339, tz=UTC)}", vars)
+
+	if before != "2024-01-17T15:30:00Z" || after != "2024-01-18T15:30:00Z" {
+		t.Errorf("expected times to follow the clock, got %q then %q", before, after)
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"+72h", 72 * time.Hour},
+		{"-30m", -30 * time.Minute},
+		{"1d12h", 36 * time.Hour},
+		{"+2w", 14 * 24 * time.Hour},
+		{"1.5h", 90 * time.Minute},
+		{"+500ms", 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := parseOffset(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.input, tt.want, got, err)
+		}
+	}
+
+	for _, bad := range []string{"", "+", "72", "+3 days", "++1h", "1y"} {
+		if _, err := parseOffset(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestDateFunctions_Errors(t *testing.T) {
+	useFakeClock(t, time.Date(2024, 1, 17, 14, 30, 0, 0, time.UTC))
+	tests := []struct {
+		fn   func([]string) (string, error)
+		args []string
+		want string
+	}{
+		{fnDateAdd, nil, "requires 1 or 2 positional arguments"},
+		{fnDateAdd, []string{"tomorrow"}, "invalid offset"},
+		{fnDateAdd, []string{"+1h", "rfc3339", "tz=Mars/Olympus"}, "unknown time zone"},
+		{fnDateAdd, []string{"+1h", "rfc3339", "from=soon"}, "invalid time"},
+		{fnDateFormat, []string{""}, "empty time value"},
+		{fnDateFormat, []string{"10/03/2024", "unix", "in=2006-01-02"}, "parsing"},
+		{fnTimestamp, []string{"+1h", "+2h"}, "at most 1 argument"},
+		{boundary("start_of_day", startOfDay), []string{"unix", "rfc3339"}, "at most 1 positional argument"},
+	}
+	for _, tt := range tests {
+		_, err := tt.fn(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
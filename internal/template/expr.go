@@ -0,0 +1,876 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"maestro/internal/core"
+)
+
+const (
+	// maxExprLength bounds the size of an expression to keep parsing cheap.
+	maxExprLength = 4096
+	// maxExprDepth bounds nesting so hostile input cannot exhaust the stack.
+	maxExprDepth = 64
+)
+
+// Evaluate parses and evaluates an expression against vars.
+//
+// The language is deliberately small and side-effect free: literals
+// (numbers, 'strings', "strings", true, false, null), variables (including
+// dotted names such as data.users.id), member and index access (a.b, a[0],
+// a["key"]), arithmetic (+ - * / %), comparison (== != < <= > >=), logic
+// (&& || !), ternaries (c ? a : b) and calls to built-in functions. There is
+// no assignment, no loops and no access to Go methods.
+//
+// Arithmetic is type-aware: numbers from Extract (float64), integers and
+// numeric strings (as loaded from CSV files) are all treated as numbers.
+// A numeric string only becomes a number next to a non-string number, so
+// "+" and the comparisons treat two strings as text: '12' + '34' is
+// "1234" and '9' < '10' is false, while quantity + 1 adds.
+func Evaluate(expr string, vars core.Variables) (any, error) {
+	e, err := parseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.eval(vars)
+}
+
+// exprNode is a node in a parsed expression tree.
+type exprNode interface {
+	eval(vars core.Variables) (any, error)
+}
+
+type literalNode struct{ value any }
+
+// pathNode is a variable reference, possibly dotted (a.b.c). The longest
+// prefix that names a variable wins; the rest is resolved as member access.
+type pathNode struct{ segments []string }
+
+type memberNode struct {
+	target exprNode
+	name   string
+}
+
+type indexNode struct {
+	target exprNode
+	index  exprNode
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+type ternaryNode struct {
+	cond, then, otherwise exprNode
+}
+
+func (n *literalNode) eval(core.Variables) (any, error) { return n.value, nil }
+
+func (n *pathNode) eval(vars core.Variables) (any, error) {
+	for i := len(n.segments); i > 0; i-- {
+		val, ok := vars.Get(strings.Join(n.segments[:i], "."))
+		if !ok {
+			continue
+		}
+		for _, name := range n.segments[i:] {
+			var err error
+			if val, err = member(val, name); err != nil {
+				return nil, err
+			}
+		}
+		return val, nil
+	}
+	return nil, fmt.Errorf("variable %q not found", strings.Join(n.segments, "."))
+}
+
+func (n *memberNode) eval(vars core.Variables) (any, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return member(target, n.name)
+}
+
+func (n *indexNode) eval(vars core.Variables) (any, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return index(target, idx)
+}
+
+func (n *callNode) eval(vars core.Variables) (any, error) {
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return callFunction(n.name, args)
+}
+
+func (n *unaryNode) eval(vars core.Variables) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		return !truthy(v), nil
+	case "-":
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", describe(v))
+		}
+		return -f, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n *binaryNode) eval(vars core.Variables) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logic operators
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	case "+":
+		if isNumeric(left) || isNumeric(right) {
+			l, lok := toNumber(left)
+			r, rok := toNumber(right)
+			if lok && rok {
+				return l + r, nil
+			}
+		}
+		ls, lok := left.(string)
+		rs, rok := right.(string)
+		if lok || rok {
+			if !lok {
+				ls = formatValue(left)
+			}
+			if !rok {
+				rs = formatValue(right)
+			}
+			return ls + rs, nil
+		}
+		return nil, fmt.Errorf("cannot add %s and %s", describe(left), describe(right))
+	case "-", "*", "/", "%":
+		l, lok := toNumber(left)
+		r, rok := toNumber(right)
+		if !lok || !rok {
+			return nil, fmt.Errorf("operator %s requires numbers, got %s and %s", n.op, describe(left), describe(right))
+		}
+		switch n.op {
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/":
+			if r == 0 {
+				return nil, errors.New("division by zero")
+			}
+			return l / r, nil
+		default:
+			if r == 0 {
+				return nil, errors.New("modulo by zero")
+			}
+			return math.Mod(l, r), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n *ternaryNode) eval(vars core.Variables) (any, error) {
+	cond, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+// member returns the named field of a map value.
+func member(v any, name string) (any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot access field %q of %s", name, describe(v))
+	}
+	val, ok := m[name]
+	if !ok {
+		return nil, fmt.Errorf("field %q not found", name)
+	}
+	return val, nil
+}
+
+// index returns v[idx] for arrays (numeric index) and maps (string key).
+func index(v any, idx any) (any, error) {
+	switch t := v.(type) {
+	case []any:
+		f, ok := toNumber(idx)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("array index must be an integer, got %s", describe(idx))
+		}
+		i := int(f)
+		if i < 0 {
+			i += len(t)
+		}
+		if i < 0 || i >= len(t) {
+			return nil, fmt.Errorf("index %d out of range (length %d)", int(f), len(t))
+		}
+		return t[i], nil
+	case map[string]any:
+		key, ok := idx.(string)
+		if !ok {
+			key = formatValue(idx)
+		}
+		return member(t, key)
+	}
+	return nil, fmt.Errorf("cannot index %s", describe(v))
+}
+
+// toNumber converts numeric values and numeric strings to float64.
+func toNumber(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil && !math.IsInf(f, 0) && !math.IsNaN(f)
+	}
+	return 0, false
+}
+
+// isNumeric reports whether v is a Go numeric type (not a numeric string).
+func isNumeric(v any) bool {
+	if _, ok := v.(string); ok {
+		return false
+	}
+	_, ok := toNumber(v)
+	return ok
+}
+
+// truthy reports the boolean interpretation of v.
+func truthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	}
+	if f, ok := toNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// equal compares values, treating a number and a numeric string as equal
+// when they denote the same value.
+func equal(a, b any) bool {
+	if isNumeric(a) || isNumeric(b) {
+		af, aok := toNumber(a)
+		bf, bok := toNumber(b)
+		if aok && bok {
+			return af == bf
+		}
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders numbers numerically and strings lexicographically. As
+// with "+", a numeric string is compared as a number only against a
+// non-string number.
+func compare(op string, a, b any) (bool, error) {
+	var c int
+	as, aStr := a.(string)
+	bs, bStr := b.(string)
+	if aStr && bStr {
+		c = strings.Compare(as, bs)
+	} else {
+		af, aok := toNumber(a)
+		bf, bok := toNumber(b)
+		if !aok || !bok {
+			return false, fmt.Errorf("cannot compare %s and %s", describe(a), describe(b))
+		}
+		switch {
+		case af < bf:
+			c = -1
+		case af > bf:
+			c = 1
+		}
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// describe names the type of v for error messages.
+func describe(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if isNumeric(v) {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// FormatValue renders a value the way a template inserts it into text, so
+// checks compare extracted values as they would appear in a request.
+func FormatValue(v any) string { return formatValue(v) }
+
+// formatValue renders a value the way Substitute inserts it into text.
+// Maps and arrays become JSON so they can be embedded in JSON bodies.
+func formatValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		if s, err := marshalJSON(v); err == nil {
+			return s
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// exprFuncs are the functions callable from expressions with evaluated
+// arguments. Functions in funcRegistry are also callable; their arguments
+// are formatted as text.
+var exprFuncs = map[string]func(args []any) (any, error){
+	"len":   exprLen,
+	"str":   exprStr,
+	"int":   exprInt,
+	"float": exprFloat,
+}
+
+func callFunction(name string, args []any) (any, error) {
+	if fn, ok := exprFuncs[name]; ok {
+		v, err := fn(args)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", name, err)
+		}
+		return v, nil
+	}
+	if fn, ok := funcRegistry[name]; ok {
+		parts := make([]string, len(args))
+		for i, a := range args {
+			parts[i] = formatValue(a)
+		}
+		v, err := fn(parts)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", name, err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown function %q", name)
+}
+
+func exprLen(args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("len(x) requires exactly 1 argument")
+	}
+	switch t := args[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(t)), nil
+	case []any:
+		return float64(len(t)), nil
+	case map[string]any:
+		return float64(len(t)), nil
+	case nil:
+		return float64(0), nil
+	}
+	return nil, fmt.Errorf("cannot take length of %s", describe(args[0]))
+}
+
+func exprStr(args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("str(x) requires exactly 1 argument")
+	}
+	return formatValue(args[0]), nil
+}
+
+func exprInt(args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("int(x) requires exactly 1 argument")
+	}
+	f, ok := toNumber(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %s to int", describe(args[0]))
+	}
+	return math.Trunc(f), nil
+}
+
+func exprFloat(args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("float(x) requires exactly 1 argument")
+	}
+	f, ok := toNumber(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %s to float", describe(args[0]))
+	}
+	return f, nil
+}
+
+// Parsing
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// lexExpr splits an expression into tokens.
+func lexExpr(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			f, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, num: f, text: src[start:i], pos: start})
+
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(src) {
+				if src[i] == c {
+					closed = true
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					case 'r':
+						sb.WriteByte('\r')
+					default:
+						sb.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+
+		default:
+			op := ""
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("+-*/%<>!?:.,()[]", rune(c)) {
+					return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+				}
+				op = string(c)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isIdentChar(c byte) bool  { return isIdentStart(c) || isDigit(c) }
+
+// exprParser is a recursive-descent parser over lexed tokens.
+type exprParser struct {
+	src    string
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// parseExpr parses src into an expression tree.
+func parseExpr(src string) (exprNode, error) {
+	if len(src) > maxExprLength {
+		return nil, fmt.Errorf("expression too long (%d > %d bytes)", len(src), maxExprLength)
+	}
+	tokens, err := lexExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	p := &exprParser{src: src, tokens: tokens}
+	node, err := p.parseTernary()
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("expression %q: unexpected %q at position %d", src, tok.text, tok.pos)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *exprParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	if p.accept(op) {
+		return nil
+	}
+	tok := p.peek()
+	if tok.kind == tokEOF {
+		return fmt.Errorf("expected %q at end of expression", op)
+	}
+	return fmt.Errorf("expected %q at position %d, got %q", op, tok.pos, tok.text)
+}
+
+func (p *exprParser) enter() error {
+	p.depth++
+	if p.depth > maxExprDepth {
+		return errors.New("expression nested too deeply")
+	}
+	return nil
+}
+
+func (p *exprParser) leave() { p.depth-- }
+
+func (p *exprParser) parseTernary() (exprNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// binaryLevels lists binary operators from lowest to highest precedence.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp || !containsString(binaryLevels[level], tok.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok := p.peek(); tok.kind == tokOp && (tok.text == "!" || tok.text == "-") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: tok.text, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			tok := p.next()
+			if tok.kind != tokIdent {
+				return nil, fmt.Errorf("expected field name at position %d", tok.pos)
+			}
+			node = &memberNode{target: node, name: tok.text}
+		case p.accept("["):
+			idx, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexNode{target: node, index: idx}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &literalNode{value: tok.num}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			return p.parseCall(tok.text)
+		}
+		// Greedily collect dotted segments so that variables with dots in
+		// their names (data.users.email) resolve before member access.
+		segments := []string{tok.text}
+		for p.pos+1 < len(p.tokens) {
+			dot, ident := p.tokens[p.pos], p.tokens[p.pos+1]
+			if dot.kind != tokOp || dot.text != "." || ident.kind != tokIdent {
+				break
+			}
+			segments = append(segments, ident.text)
+			p.pos += 2
+		}
+		return &pathNode{segments: segments}, nil
+	case tokOp:
+		if tok.text == "(" {
+			node, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return nil, errors.New("unexpected end of expression")
+}
+
+func (p *exprParser) parseCall(name string) (exprNode, error) {
+	if _, ok := exprFuncs[name]; !ok {
+		if _, ok := funcRegistry[name]; !ok {
+			return nil, fmt.Errorf("unknown function %q", name)
+		}
+	}
+	call := &callNode{name: name}
+	if p.accept(")") {
+		return call, nil
+	}
+	for {
+		arg, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if p.accept(")") {
+			return call, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
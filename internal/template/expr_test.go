@@ -0,0 +1,202 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"maestro/internal/core"
+)
+
+func exprVars() *core.MapVariables {
+	vars := core.NewVariables()
+	vars.Set("price", float64(12.5))
+	vars.Set("quantity", "4") // CSV values are strings
+	vars.Set("name", "widget")
+	vars.Set("active", true)
+	vars.Set("items", []any{
+		map[string]any{"id": float64(7), "tags": []any{"a", "b"}},
+		map[string]any{"id": float64(9)},
+	})
+	vars.Set("user", map[string]any{"name": "alice", "age": float64(30)})
+	vars.Set("data.users.email", "alice@example.com")
+	return vars
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expr string
+		want any
+	}{
+		// Literals
+		{"42", float64(42)},
+		{"1.5e2", float64(150)},
+		{"'single'", "single"},
+		{`"dou\"ble"`, `dou"ble`},
+		{"true", true},
+		{"null", nil},
+
+		// Arithmetic with type coercion
+		{"price * quantity", float64(50)},
+		{"price + 0.5", float64(13)},
+		{"quantity - 1", float64(3)},
+		{"10 / 4", float64(2.5)},
+		{"10 % 4", float64(2)},
+		{"-price", float64(-12.5)},
+		{"2 + 3 * 4", float64(14)},
+		{"(2 + 3) * 4", float64(20)},
+
+		// String concatenation
+		{"'id-' + name", "id-widget"},
+		{"name + '-' + quantity", "widget-4"},
+		{"'n' + 1", "n1"},
+		{"'12' + '34'", "1234"},
+		{"quantity + quantity", "44"},
+		{"quantity + 1", float64(5)},
+
+		// Access
+		{"items[0].id", float64(7)},
+		{"items[1]['id']", float64(9)},
+		{"items[-1].id", float64(9)},
+		{"items[0].tags[1]", "b"},
+		{"user.name", "alice"},
+		{"data.users.email", "alice@example.com"},
+
+		// Functions
+		{"len(items)", float64(2)},
+		{"len(name)", float64(6)},
+		{"len(user)", float64(2)},
+		{"int(price)", float64(12)},
+		{"str(price)", "12.5"},
+		{"float('3.25')", float64(3.25)},
+
+		// Comparison and logic
+		{"price > 10", true},
+		{"quantity == 4", true},
+		{"name == 'widget'", true},
+		{"name != 'widget'", false},
+		{"'a' < 'b'", true},
+		{"'9' < '10'", false},
+		{"quantity < 10", true},
+		{"10 > quantity", true},
+		{"active && price >= 12.5", true},
+		{"!active || false", false},
+
+		// Ternary
+		{"active ? 'on' : 'off'", "on"},
+		{"price > 100 ? 'big' : price > 10 ? 'medium' : 'small'", "medium"},
+		{"user.age >= 18 ? user.name : 'minor'", "alice"},
+	}
+
+	vars := exprVars()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Evaluate(tt.expr, vars)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v (%T), got %v (%T)", tt.want, tt.want, got, got)
+			}
+		})
+	}
+}
+
+func TestEvaluate_ShortCircuit(t *testing.T) {
+	vars := exprVars()
+
+	// The right-hand side references a missing variable but is never evaluated
+	got, err := Evaluate("false && missing", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != false {
+		t.Errorf("expected false, got %v", got)
+	}
+
+	got, err = Evaluate("true ? 1 : missing", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != float64(1) {
+		t.Errorf("expected 1, got %v", got)
+	}
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"missing + 1", `variable "missing" not found`},
+		{"name * 2", "requires numbers"},
+		{"1 / 0", "division by zero"},
+		{"items[5]", "out of range"},
+		{"items['x']", "array index must be an integer"},
+		{"user.missing", `field "missing" not found`},
+		{"name.length", "cannot access field"},
+		{"exec('rm')", `unknown function "exec"`},
+		{"len(1, 2)", "exactly 1 argument"},
+		{"1 +", "unexpected end"},
+		{"(1 + 2", `expected ")"`},
+		{"a ? b", `expected ":"`},
+		{"'open", "unterminated string"},
+		{"1 ; 2", "unexpected character"},
+		{"1 2", "unexpected"},
+		{strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100), "nested too deeply"},
+		{strings.Repeat("1+", 3000) + "1", "too long"},
+	}
+
+	vars := exprVars()
+	for _, tt := range tests {
+		name := tt.expr
+		if len(name) > 20 {
+			name = name[:20]
+		}
+		t.Run(name, func(t *testing.T) {
+			_, err := Evaluate(tt.expr, vars)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestEvaluate_RegistryFunctions(t *testing.T) {
+	got, err := Evaluate("random(5, 5) * 2", core.NewVariables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != float64(10) {
+		t.Errorf("expected 10, got %v", got)
+	}
+}
+
+func TestSubstitute_Expressions(t *testing.T) {
+	vars := exprVars()
+
+	result, err := Substitute(`{"total": ${price * quantity}, "first": ${items[0].id}, "n": ${len(items)}, "who": "${user.name}"}`, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"total": 50, "first": 7, "n": 2, "who": "alice"}`
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestSubstitute_ExpressionError(t *testing.T) {
+	_, err := Substitute("${price / 0}", exprVars())
+	if err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("expected division by zero error, got %v", err)
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	vars := exprVars()
+	for i := 0; i < b.N; i++ {
+		_, _ = Evaluate("price * quantity + items[0].id", vars)
+	}
+}
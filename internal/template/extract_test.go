@@ -195,31 +195,41 @@ func TestExtract_ArrayWildcard(t *testing.T) {
 	}
 }
 
-func TestConvertJSONPath(t *testing.T) {
+func TestExtract_PathForms(t *testing.T) {
+	body := []byte(`{"foo": {"bar": "x"}, "items": [{"id": 1}], "user": {"name": "u"}}`)
 	tests := []struct {
 		input    string
-		expected string
+		expected any
 	}{
-		{"$.foo.bar", "foo.bar"},
-		{"$foo.bar", "foo.bar"},
-		{"foo.bar", "foo.bar"},
-		{"$.items[0].id", "items.0.id"},
-		{"$.items[10].id", "items.10.id"},
-		{"$.data[*].name", "data.#.name"},
-		{"$", ""},
-		{"$.user", "user"},
+		{"$.foo.bar", "x"},
+		{"$foo.bar", "x"},
+		{"foo.bar", "x"},
+		{"$.items[0].id", float64(1)},
+		{"$.items.0.id", float64(1)},
+		{"$['foo']['bar']", "x"},
+		{"$.user.name", "u"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.input, func(t *testing.T) {
-			result := convertJSONPath(tc.input)
-			if result != tc.expected {
-				t.Errorf("convertJSONPath(%q) = %q, want %q", tc.input, result, tc.expected)
+			result, err := Extract(body, map[string]string{"v": tc.input})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result["v"] != tc.expected {
+				t.Errorf("Extract(%q) = %v, want %v", tc.input, result["v"], tc.expected)
 			}
 		})
 	}
 }
 
+func TestExtract_InvalidPath(t *testing.T) {
+	_, err := Extract([]byte(`{}`), map[string]string{"v": "$.items[(@.length-1)]"})
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected unsupported syntax error, got %v", err)
+	}
+}
+
 // Benchmarks
 
 func BenchmarkExtract_Simple(b *testing.B) {
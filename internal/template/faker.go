@@ -0,0 +1,281 @@
+package template
+
+import (
+	cryptorand "crypto/rand"
+	"embed"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Faker functions generate realistic-looking test data from word lists
+// embedded in the binary, so they work offline:
+//
+//   - name() -> Olivia Martinez
+//   - email() -> olivia.martinez42@example.com
+//   - phone() -> +1-555-013-2749
+//   - address() -> 742 Maple Avenue, Springfield, OR 97403
+//   - company() -> Globex Labs
+//   - lorem(5) -> Dolor sit amet elit tempor.
+//   - ipv4() -> 83.17.201.4
+//   - date_between(2024-01-01, 2024-12-31) -> 2024-07-19
+//   - pick(red, green, blue) -> green
+//   - weighted_pick(gold=1, silver=3, bronze=6) -> bronze
+//
+// Emails use reserved example domains and phone numbers the fictional 555
+// exchange, so generated data never reaches a real person.
+
+//go:embed wordlists/*.txt
+var wordlistFS embed.FS
+
+var (
+	firstNames      = loadWordlist("first_names")
+	lastNames       = loadWordlist("last_names")
+	streets         = loadWordlist("streets")
+	streetSuffixes  = loadWordlist("street_suffixes")
+	cities          = loadWordlist("cities")
+	states          = loadWordlist("states")
+	companies       = loadWordlist("companies")
+	companySuffixes = loadWordlist("company_suffixes")
+	loremWords      = loadWordlist("lorem")
+	emailDomains    = loadWordlist("domains")
+)
+
+func loadWordlist(name string) []string {
+	data, err := wordlistFS.ReadFile("wordlists/" + name + ".txt")
+	if err != nil {
+		panic(err)
+	}
+	// One entry per line; entries such as "Mount Vernon" contain spaces
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			words = append(words, line)
+		}
+	}
+	return words
+}
+
+// seeded is the generator used by all random functions once SetSeed has
+// been called. Until then they draw from crypto/rand.
+var seeded struct {
+	sync.Mutex
+	rng *rand.Rand
+}
+
+// SetSeed makes every random function (random, random_string, uuid and the
+// faker functions) deterministic. With a single actor the same seed
+// reproduces the same payloads; with several actors the values are the
+// same but their interleaving depends on scheduling.
+func SetSeed(seed int64) {
+	seeded.Lock()
+	defer seeded.Unlock()
+	seeded.rng = rand.New(rand.NewSource(seed))
+}
+
+// randInt returns a random integer in [0, n).
+func randInt(n int64) int64 {
+	seeded.Lock()
+	if seeded.rng != nil {
+		defer seeded.Unlock()
+		return seeded.rng.Int63n(n)
+	}
+	seeded.Unlock()
+	v, err := cryptorand.Int(cryptorand.Reader, big.NewInt(n))
+	if err != nil {
+		panic(err)
+	}
+	return v.Int64()
+}
+
+// randBytes fills b with random bytes.
+func randBytes(b []byte) {
+	seeded.Lock()
+	if seeded.rng != nil {
+		defer seeded.Unlock()
+		seeded.rng.Read(b)
+		return
+	}
+	seeded.Unlock()
+	if _, err := cryptorand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// randItem returns a random element of list.
+func randItem(list []string) string {
+	return list[randInt(int64(len(list)))]
+}
+
+// fakerFunc adapts a generator that takes no arguments.
+func fakerFunc(name string, gen func() string) func(args []string) (string, error) {
+	return func(args []string) (string, error) {
+		if len(args) != 0 {
+			return "", fmt.Errorf("%s() takes no arguments", name)
+		}
+		return gen(), nil
+	}
+}
+
+func fakeFirstName() string { return randItem(firstNames) }
+
+func fakeLastName() string { return randItem(lastNames) }
+
+func fakeName() string { return fakeFirstName() + " " + fakeLastName() }
+
+func fakeEmail() string {
+	local := strings.ToLower(fakeFirstName() + "." + fakeLastName())
+	return fmt.Sprintf("%s%d@%s", local, randInt(100), randItem(emailDomains))
+}
+
+func fakePhone() string {
+	return fmt.Sprintf("+1-555-%03d-%04d", randInt(1000), randInt(10000))
+}
+
+func fakeAddress() string {
+	return fmt.Sprintf("%d %s %s, %s, %s %05d",
+		1+randInt(9999), randItem(streets), randItem(streetSuffixes),
+		randItem(cities), randItem(states), 501+randInt(99000))
+}
+
+func fakeCompany() string {
+	return randItem(companies) + " " + randItem(companySuffixes)
+}
+
+// fakeIPv4 returns a unicast address outside 0/8, 10/8 and 127/8.
+func fakeIPv4() string {
+	first := 1 + randInt(223)
+	for first == 10 || first == 127 {
+		first = 1 + randInt(223)
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", first, randInt(256), randInt(256), 1+randInt(254))
+}
+
+// fnLorem returns a sentence of placeholder words.
+// Usage: lorem(words), 8 words when omitted
+func fnLorem(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("lorem([words]) takes at most 1 argument")
+	}
+	n := 8
+	if len(args) == 1 {
+		var err error
+		if n, err = strconv.Atoi(strings.TrimSpace(args[0])); err != nil {
+			return "", fmt.Errorf("invalid word count: %w", err)
+		}
+		if n <= 0 || n > 1000 {
+			return "", fmt.Errorf("word count must be between 1 and 1000")
+		}
+	}
+	words := make([]string, n)
+	for i := range words {
+		words[i] = randItem(loremWords)
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + ".", nil
+}
+
+// dateLayouts are tried in order when parsing date_between bounds.
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// fnDateBetween returns a random time between two dates, inclusive. The
+// result uses the layout of the first bound unless a Go layout is given.
+// Usage: date_between(from, to[, layout])
+func fnDateBetween(args []string) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", fmt.Errorf("date_between(from, to[, layout]) requires 2 or 3 arguments")
+	}
+	from, layout, err := parseDate(args[0])
+	if err != nil {
+		return "", err
+	}
+	to, _, err := parseDate(args[1])
+	if err != nil {
+		return "", err
+	}
+	if to.Before(from) {
+		return "", fmt.Errorf("from (%s) must not be after to (%s)", args[0], args[1])
+	}
+	if len(args) == 3 {
+		layout = args[2]
+	}
+	unit := time.Second
+	if layout == "2006-01-02" {
+		unit = 24 * time.Hour
+	}
+	span := int64(to.Sub(from) / unit)
+	return from.Add(time.Duration(randInt(span+1)) * unit).Format(layout), nil
+}
+
+func parseDate(s string) (time.Time, string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == time.RFC3339Nano {
+				layout = time.RFC3339
+			}
+			return t, layout, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("invalid date %q (want 2006-01-02 or RFC 3339)", s)
+}
+
+// fnPick returns one of its arguments at random.
+// Usage: pick(a, b, c)
+func fnPick(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("pick(values...) requires at least 1 argument")
+	}
+	return args[randInt(int64(len(args)))], nil
+}
+
+// fnWeightedPick returns one of its values with probability proportional
+// to its weight. Values with spaces are quoted: 'New York'=2.
+// Usage: weighted_pick(gold=1, silver=3, bronze=6)
+func fnWeightedPick(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("weighted_pick(value=weight...) requires at least 1 argument")
+	}
+	values := make([]string, len(args))
+	weights := make([]float64, len(args))
+	var total float64
+	for i, arg := range args {
+		value, weight, ok := cutLast(arg, "=")
+		if !ok {
+			return "", fmt.Errorf("%q must be value=weight", arg)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil || w < 0 {
+			return "", fmt.Errorf("invalid weight %q for %q", weight, value)
+		}
+		values[i], weights[i] = value, w
+		total += w
+	}
+	if total == 0 {
+		return "", fmt.Errorf("weights must not all be zero")
+	}
+
+	// Draw on a fine integer grid so the seeded and crypto sources share
+	// one code path
+	const resolution = 1 << 30
+	r := float64(randInt(resolution)) / resolution * total
+	for i, w := range weights {
+		if r < w {
+			return values[i], nil
+		}
+		r -= w
+	}
+	return values[len(values)-1], nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,183 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/core"
+)
+
+// unseed restores crypto/rand once a test that seeds is done.
+func unseed(t *testing.T) {
+	t.Cleanup(func() {
+		seeded.Lock()
+		seeded.rng = nil
+		seeded.Unlock()
+	})
+}
+
+func TestFaker_Formats(t *testing.T) {
+	tests := []struct {
+		input   string
+		pattern string
+	}{
+		{"${name()}", `^[A-Z][a-z]+ [A-Z][a-z]+$`},
+		{"${first_name()}", `^[A-Z][a-z]+$`},
+		{"${email()}", `^[a-z]+\.[a-z]+\d{1,2}@([a-z]+\.)?example\.(com|org|net|test)$`},
+		{"${phone()}", `^\+1-555-\d{3}-\d{4}$`},
+		{"${address()}", `^\d{1,4} [A-Za-z]+ [A-Za-z]+, [A-Za-z ]+, [A-Z]{2} \d{5}$`},
+		{"${company()}", `^[A-Za-z ]+ [A-Za-z]+$`},
+		{"${lorem(5)}", `^[A-Z][a-z]*( [a-z]+){4}\.$`},
+		{"${lorem()}", `^[A-Z][a-z]*( [a-z]+){7}\.$`},
+		{"${ipv4()}", `^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}$`},
+		{"${pick(red, green, blue)}", `^(red|green|blue)$`},
+	}
+	vars := core.NewVariables()
+	for _, tt := range tests {
+		re := regexp.MustCompile(tt.pattern)
+		for i := 0; i < 50; i++ {
+			got, err := Substitute(tt.input, vars)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.input, err)
+			}
+			if !re.MatchString(got) {
+				t.Fatalf("%s: %q does not match %s", tt.input, got, tt.pattern)
+			}
+		}
+	}
+}
+
+func TestSetSeed_Reproducible(t *testing.T) {
+	unseed(t)
+	input := "${name()}|${email()}|${uuid()}|${random(1, 1000000)}|${random_string(12)}|${weighted_pick(a=1, b=1)}"
+	vars := core.NewVariables()
+
+	run := func() []string {
+		SetSeed(42)
+		out := make([]string, 5)
+		for i := range out {
+			s, err := Substitute(input, vars)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			out[i] = s
+		}
+		return out
+	}
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("run %d differs: %q vs %q", i, first[i], second[i])
+		}
+	}
+	if first[0] == first[1] {
+		t.Errorf("expected successive values to differ, got %q twice", first[0])
+	}
+
+	SetSeed(7)
+	if other, _ := Substitute(input, vars); other == first[0] {
+		t.Errorf("expected a different seed to give different values, got %q", other)
+	}
+}
+
+func TestDateBetween(t *testing.T) {
+	vars := core.NewVariables()
+	from, to := time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		got, err := Substitute("${date_between(2024-02-27, 2024-03-02)}", vars)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		d, err := time.Parse("2006-01-02", got)
+		if err != nil || d.Before(from) || d.After(to) {
+			t.Fatalf("date %q outside range", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected all 5 days including both bounds, got %v", seen)
+	}
+
+	got, err := Substitute("${date_between('2024-01-01T10:00:00Z', '2024-01-01T11:00:00Z')}", vars)
+	if err != nil || !strings.HasPrefix(got, "2024-01-01T10:") && got != "2024-01-01T11:00:00Z" {
+		t.Errorf("expected an RFC 3339 time in range, got %q (%v)", got, err)
+	}
+	if got, _ := Substitute("${date_between(2024-05-01, 2024-05-01, 'Jan 2, 2006')}", vars); got != "May 1, 2024" {
+		t.Errorf("expected custom layout, got %q", got)
+	}
+
+	for _, args := range [][]string{
+		{"2024-01-01"},
+		{"yesterday", "2024-01-01"},
+		{"2024-02-01", "2024-01-01"},
+	} {
+		if _, err := fnDateBetween(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestWeightedPick(t *testing.T) {
+	vars := core.NewVariables()
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		got, err := Substitute("${weighted_pick(gold=1, silver=0, 'New York'=3)}", vars)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		counts[got]++
+	}
+	if counts["silver"] != 0 {
+		t.Errorf("zero weight was picked %d times", counts["silver"])
+	}
+	if counts["gold"]+counts["New York"] != 2000 || counts["New York"] < 2*counts["gold"] {
+		t.Errorf("unexpected distribution %v", counts)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "requires at least 1 argument"},
+		{[]string{"gold"}, "must be value=weight"},
+		{[]string{"gold=heavy"}, "invalid weight"},
+		{[]string{"gold=-1"}, "invalid weight"},
+		{[]string{"gold=0", "silver=0"}, "must not all be zero"},
+	}
+	for _, tt := range tests {
+		_, err := fnWeightedPick(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestLoadWordlist_MultiWordEntries(t *testing.T) {
+	for name, want := range map[string]string{"cities": "Mount Vernon", "companies": "Pied Piper"} {
+		words := loadWordlist(name)
+		found := false
+		for _, w := range words {
+			if w == want {
+				found = true
+			}
+			if w == "" || strings.TrimSpace(w) != w {
+				t.Errorf("%s: untrimmed entry %q", name, w)
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected entry %q in %v", name, want, words)
+		}
+	}
+}
+
+func TestFaker_ArgumentErrors(t *testing.T) {
+	vars := core.NewVariables()
+	for _, input := range []string{"${name(x)}", "${lorem(0)}", "${lorem(many)}", "${pick()}"} {
+		if _, err := Substitute(input, vars); err == nil {
+			t.Errorf("%s: expected error", input)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package template
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// filters transform a placeholder's value after a pipe: ${name | upper}.
+// Filters chain left to right. default is handled by applyFilters because
+// it also replaces missing values.
+var filters = map[string]func(v any) (any, error){
+	"base64":    filterBase64,
+	"urlencode": func(v any) (any, error) { return url.QueryEscape(formatValue(v)), nil },
+	"json":      filterJSON,
+	"upper":     func(v any) (any, error) { return strings.ToUpper(formatValue(v)), nil },
+	"lower":     func(v any) (any, error) { return strings.ToLower(formatValue(v)), nil },
+	"trim":      func(v any) (any, error) { return strings.TrimSpace(formatValue(v)), nil },
+}
+
+// splitPipes splits a placeholder into its value expression and filters
+// at each | that is not part of ||, a string or a call's arguments.
+func splitPipes(expr string) []string {
+	parts := splitTopLevel(expr, '|')
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// filterStep is a compiled filter. fn is nil for default, which replaces
+// a missing, null or empty value with fallback.
+type filterStep struct {
+	name     string
+	fn       func(v any) (any, error)
+	fallback any
+}
+
+// compileFilters parses filters such as "upper" or "default: 0".
+func compileFilters(pipes []string) ([]filterStep, error) {
+	steps := make([]filterStep, len(pipes))
+	for i, pipe := range pipes {
+		name, arg, hasArg := strings.Cut(pipe, ":")
+		name = strings.TrimSpace(name)
+		if name == "default" {
+			if !hasArg {
+				return nil, fmt.Errorf("filter default requires a value, as in default: 0")
+			}
+			steps[i] = filterStep{name: name, fallback: parseFilterLiteral(strings.TrimSpace(arg))}
+			continue
+		}
+
+		fn, ok := filters[name]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("unknown filter %q", name)
+		case hasArg:
+			return nil, fmt.Errorf("filter %s takes no argument", name)
+		}
+		steps[i] = filterStep{name: name, fn: fn}
+	}
+	return steps, nil
+}
+
+// applyFilters runs a value through compiled filters. A value that could
+// not be resolved (err != nil) passes through unchanged until a default
+// replaces it.
+func applyFilters(v any, err error, steps []filterStep) (any, error) {
+	for _, step := range steps {
+		if step.fn == nil {
+			if err != nil || v == nil || v == "" {
+				v, err = step.fallback, nil
+			}
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if v, err = step.fn(v); err != nil {
+			return nil, fmt.Errorf("filter %s: %w", step.name, err)
+		}
+	}
+	return v, err
+}
+
+// parseFilterLiteral reads a filter argument: a quoted string, a number,
+// true, false, null, or otherwise the text itself.
+func parseFilterLiteral(s string) any {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+func filterBase64(v any) (any, error) {
+	return base64.StdEncoding.EncodeToString([]byte(formatValue(v))), nil
+}
+
+// filterJSON encodes a value as JSON: strings are quoted and escaped, maps
+// and arrays serialized.
+func filterJSON(v any) (any, error) {
+	return marshalJSON(v)
+}
+
+// marshalJSON encodes v without escaping <, > and &, which are valid in
+// request bodies.
+func marshalJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
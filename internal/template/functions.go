@@ -1,58 +1,397 @@
 package template
 
 import (
-	"crypto/rand"
+	"errors"
 	"fmt"
-	"math/big"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"maestro/internal/core"
 )
 
-// funcPattern matches function calls like uuid(), random(1,100), date(2006-01-02)
-var funcRegistry = map[string]func(args string) (string, error){
+// funcRegistry holds the built-in functions callable as ${name(args)}.
+// Arguments are evaluated by evalArgs before the call.
+var funcRegistry = map[string]func(args []string) (string, error){
 	"uuid":          fnUUID,
 	"timestamp":     fnTimestamp,
 	"timestamp_ms":  fnTimestampMs,
+	"seq":           fnSeq,
 	"random":        fnRandom,
 	"random_string": fnRandomString,
 	"date":          fnDate,
+	"date_add":      fnDateAdd,
+	"date_format":   fnDateFormat,
+	"start_of_day":  boundary("start_of_day", startOfDay),
+	"end_of_day":    boundary("end_of_day", endOfDay),
+	"start_of_week": boundary("start_of_week", startOfWeek),
+	"end_of_week":   boundary("end_of_week", endOfWeek),
+	"sha256":        fnSHA256,
+	"sha1":          fnSHA1,
+	"md5":           fnMD5,
+	"hmac_sha256":   fnHMACSHA256,
+	"base64_encode": fnBase64Encode,
+	"base64_decode": fnBase64Decode,
+	"url_base64":    fnURLBase64,
+	"hex":           fnHex,
+	"jwt_sign":      fnJWTSign,
+	"jwt_claim":     fnJWTClaim,
+	"name":          fakerFunc("name", fakeName),
+	"first_name":    fakerFunc("first_name", fakeFirstName),
+	"last_name":     fakerFunc("last_name", fakeLastName),
+	"email":         fakerFunc("email", fakeEmail),
+	"phone":         fakerFunc("phone", fakePhone),
+	"address":       fakerFunc("address", fakeAddress),
+	"company":       fakerFunc("company", fakeCompany),
+	"ipv4":          fakerFunc("ipv4", fakeIPv4),
+	"lorem":         fnLorem,
+	"date_between":  fnDateBetween,
+	"pick":          fnPick,
+	"weighted_pick": fnWeightedPick,
+}
+
+// rawArgFuncs take their whole argument text as one argument, so formats
+// such as date(Jan 2, 2006) keep their commas.
+var rawArgFuncs = map[string]bool{"date": true}
+
+// strictArgFuncs are the hashing, signing and encoding functions, which
+// build credentials. A typo in their arguments would silently hash, sign
+// or encode the wrong text, so literals must be quoted and variables must
+// exist. The function reports which arguments keep the lenient rules,
+// such as the digest encoding.
+var strictArgFuncs = map[string]func(i, n int, a argRef) bool{
+	"sha256":        digestEncodingArg(1),
+	"sha1":          digestEncodingArg(1),
+	"md5":           digestEncodingArg(1),
+	"hmac_sha256":   digestEncodingArg(2),
+	"jwt_sign":      jwtLenientArg,
+	"base64_encode": noLenientArg,
+	"url_base64":    noLenientArg,
+	"hex":           noLenientArg,
+}
+
+func noLenientArg(i, n int, a argRef) bool { return false }
+
+// digestEncodingArg allows an unquoted encoding (hex, base64) as the
+// optional last argument at position pos.
+func digestEncodingArg(pos int) func(i, n int, a argRef) bool {
+	return func(i, n int, a argRef) bool { return i == pos && i == n-1 && a.prefix == "" }
 }
 
-// evalFunction evaluates a built-in function call.
-// Returns the result string, or empty string and false if not a function.
-func evalFunction(expr string) (string, bool, error) {
-	// Check if it looks like a function call (contains parentheses)
+// jwtLenientArg allows an unquoted algorithm and relative times such as
+// exp=+1h. The key file is always a literal path.
+func jwtLenientArg(i, n int, a argRef) bool {
+	return i == 0 || jwtTimeClaims[strings.TrimSuffix(a.prefix, "=")]
+}
+
+// callRef is a compiled call to a built-in function such as
+// hmac_sha256(env:SECRET, request.body).
+type callRef struct {
+	name string
+	fn   func(args []string) (string, error)
+	args []argRef
+	// err is a compile error in the arguments, returned on every call.
+	err error
+}
+
+// compileCall compiles expr if it is a single call to a built-in function.
+func compileCall(expr string) (*callRef, bool) {
 	parenIdx := strings.Index(expr, "(")
-	if parenIdx == -1 || !strings.HasSuffix(expr, ")") {
-		return "", false, nil
+	if parenIdx == -1 || closingParen(expr, parenIdx) != len(expr)-1 {
+		return nil, false
 	}
 
-	funcName := expr[:parenIdx]
-	args := expr[parenIdx+1 : len(expr)-1]
-
-	fn, ok := funcRegistry[funcName]
+	name := strings.TrimSpace(expr[:parenIdx])
+	fn, ok := funcRegistry[name]
 	if !ok {
-		return "", false, nil
+		return nil, false
+	}
+	c := &callRef{
+		name: name,
+		fn:   fn,
+		args: compileArgs(expr[parenIdx+1:len(expr)-1], rawArgFuncs[name]),
 	}
 
-	result, err := fn(args)
+	if name == "jwt_sign" && len(c.args) > 1 {
+		// The key file is a path as written, so keys/private.pem is not division
+		raw := splitArgs(expr[parenIdx+1 : len(expr)-1])[1]
+		c.args[1] = argRef{kind: argQuoted, text: keyFilePath(strings.TrimSpace(raw))}
+	}
+
+	var errs []error
+	lenient, strict := strictArgFuncs[name]
+	for i := range c.args {
+		a := &c.args[i]
+		if a.kind == argCall && a.call.err != nil {
+			errs = append(errs, a.call.err)
+		}
+		if !strict || lenient(i, len(c.args), *a) {
+			continue
+		}
+		if a.kind == argText {
+			errs = append(errs, fmt.Errorf("function %s: argument %d: %q must be quoted", name, i+1, a.text))
+		}
+		a.strict = true
+	}
+	c.err = errors.Join(errs...)
+	return c, true
+}
+
+func (c *callRef) eval(vars core.Variables) (any, error) {
+	result, err := c.call(vars)
 	if err != nil {
-		return "", true, fmt.Errorf("function %s: %w", funcName, err)
+		return nil, err
 	}
-	return result, true, nil
+	return result, nil
+}
+
+// call evaluates the arguments and calls the function.
+func (c *callRef) call(vars core.Variables) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	args := make([]string, len(c.args))
+	for i := range c.args {
+		v, err := c.args[i].eval(vars)
+		if err != nil {
+			return "", fmt.Errorf("function %s: argument %d: %w", c.name, i+1, err)
+		}
+		args[i] = v
+	}
+	result, err := c.fn(args)
+	if err != nil {
+		return "", fmt.Errorf("function %s: %w", c.name, err)
+	}
+	return result, nil
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// open, or -1.
+func closingParen(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitArgs splits an argument list at top-level commas.
+func splitArgs(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return splitTopLevel(s, ',')
+}
+
+// splitTopLevel splits s at each sep outside quotes, parentheses and
+// brackets. A doubled || is never a split point.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == '|' && i+1 < len(s) && s[i+1] == '|':
+			i++
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// argKind says how a function argument is evaluated.
+type argKind int
+
+const (
+	argText   argKind = iota // a variable of that name, else the text itself
+	argQuoted                // a quoted string
+	argEnv                   // env:NAME
+	argCall                  // a nested built-in function call
+	argPath                  // a variable path such as user.id, else the text
+	argExpr                  // an expression, whose errors are reported
+)
+
+// argRef is a compiled function argument.
+type argRef struct {
+	kind argKind
+	// prefix is "name=" for named arguments, whose value alone is evaluated
+	prefix string
+	// text is the argument as written, or the decoded quoted string
+	text string
+	call *callRef
+	node exprNode
+	// strict arguments of hashing and signing functions fail when a
+	// variable path does not resolve instead of using the text.
+	strict bool
+}
+
+// compileArgs compiles function arguments: quoted strings, env:NAME,
+// function calls, variable paths (user.id, items[0]) and expressions
+// (request.method + "\n" + request.body). In name=value arguments only the
+// value is evaluated; the name may be quoted. Unquoted text that is not one
+// of these, such as a name that is no variable or a date like 2024-01-31,
+// is taken literally. With raw set, the whole text is one argument and only
+// evaluated when it is quoted, env:, a call or a variable, so
+// date(2006-01-02) is not subtraction.
+func compileArgs(text string, raw bool) []argRef {
+	parts := splitArgs(text)
+	if raw && text != "" {
+		parts = []string{text}
+	}
+	args := make([]argRef, len(parts))
+	for i, part := range parts {
+		prefix, value := "", strings.TrimSpace(part)
+		if m := namedArgPattern.FindStringSubmatch(value); m != nil && !raw {
+			prefix, value = m[1], strings.TrimSpace(m[2])
+			if prefix[0] == '"' || prefix[0] == '\'' {
+				p := &jpParser{src: prefix}
+				prefix, _ = p.parseString()
+			}
+			prefix += "="
+		}
+		args[i] = compileArg(value, raw)
+		args[i].prefix = prefix
+	}
+	return args
+}
+
+// slashPattern matches unquoted slash-separated names such as
+// Europe/Berlin or keys/private.pem, which would otherwise parse as
+// division. Division with spaces around the slash is still an expression.
+var slashPattern = regexp.MustCompile(`^[A-Za-z0-9_.+-]+(/[A-Za-z0-9_.+-]+)+$`)
+
+// namedArgPattern matches name=value arguments, such as jwt_sign claims.
+// Only the value is evaluated. Comparisons like a == b do not match.
+var namedArgPattern = regexp.MustCompile(`(?s)^([A-Za-z_][A-Za-z0-9_.-]*|'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*")\s*=(?:([^=].*)|)$`)
+
+// datePattern matches ISO dates and times, which would otherwise parse as
+// subtraction.
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:\d{2})?)?$`)
+
+func compileArg(arg string, raw bool) argRef {
+	if len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0] {
+		p := &jpParser{src: arg}
+		if s, err := p.parseString(); err == nil && p.pos == len(arg) {
+			return argRef{kind: argQuoted, text: s}
+		}
+	}
+	if name, ok := strings.CutPrefix(arg, "env:"); ok {
+		return argRef{kind: argEnv, text: name}
+	}
+	if call, ok := compileCall(arg); ok {
+		return argRef{kind: argCall, text: arg, call: call}
+	}
+	if datePattern.MatchString(arg) || slashPattern.MatchString(arg) {
+		return argRef{kind: argText, text: arg}
+	}
+
+	node, err := parseExpr(arg)
+	if err != nil {
+		return argRef{kind: argText, text: arg}
+	}
+	switch node.(type) {
+	case *literalNode:
+		return argRef{kind: argText, text: arg}
+	case *pathNode, *memberNode, *indexNode:
+		return argRef{kind: argPath, text: arg, node: node}
+	}
+	if raw {
+		if _, isCall := node.(*callNode); !isCall {
+			return argRef{kind: argText, text: arg}
+		}
+	}
+	return argRef{kind: argExpr, text: arg, node: node}
+}
+
+// eval returns the argument's value as text, with its name= prefix.
+func (a *argRef) eval(vars core.Variables) (string, error) {
+	v, err := a.value(vars)
+	if err != nil || a.prefix == "" {
+		return v, err
+	}
+	return a.prefix + v, nil
+}
+
+func (a *argRef) value(vars core.Variables) (string, error) {
+	switch a.kind {
+	case argQuoted:
+		return a.text, nil
+	case argEnv:
+		if val, ok := os.LookupEnv(a.text); ok {
+			return val, nil
+		}
+		return "", fmt.Errorf("env var %q not set", a.text)
+	case argCall:
+		return a.call.call(vars)
+	}
+
+	// A variable whose name is the whole argument wins
+	if val, ok := vars.Get(a.text); ok {
+		return formatValue(val), nil
+	}
+	switch a.kind {
+	case argPath:
+		val, err := a.node.eval(vars)
+		if err == nil {
+			return formatValue(val), nil
+		}
+		if a.strict {
+			return "", err
+		}
+	case argExpr:
+		val, err := a.node.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		return formatValue(val), nil
+	}
+	return a.text, nil
 }
 
 // fnUUID generates a UUID v4.
-func fnUUID(args string) (string, error) {
-	if args != "" {
+func fnUUID(args []string) (string, error) {
+	if len(args) != 0 {
 		return "", fmt.Errorf("uuid() takes no arguments")
 	}
 
 	uuid := make([]byte, 16)
-	if _, err := rand.Read(uuid); err != nil {
-		return "", err
-	}
+	randBytes(uuid)
 
 	// Set version (4) and variant (RFC 4122)
 	uuid[6] = (uuid[6] & 0x0f) | 0x40
@@ -62,26 +401,57 @@ func fnUUID(args string) (string, error) {
 		uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
 }
 
-// fnTimestamp returns the current Unix timestamp in seconds.
-func fnTimestamp(args string) (string, error) {
-	if args != "" {
-		return "", fmt.Errorf("timestamp() takes no arguments")
+// seqCounter backs seq(). It is shared by all actors.
+var seqCounter atomic.Int64
+
+// fnSeq returns the next value of a counter that is unique across all
+// actors and increases monotonically, starting at 1.
+func fnSeq(args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("seq() takes no arguments")
+	}
+	return strconv.FormatInt(seqCounter.Add(1), 10), nil
+}
+
+// fnTimestamp returns the current Unix timestamp in seconds, optionally
+// shifted by an offset.
+// Usage: timestamp([offset]), such as timestamp(+1h)
+func fnTimestamp(args []string) (string, error) {
+	t, err := shiftedNow("timestamp", args)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(t.Unix(), 10), nil
+}
+
+// fnTimestampMs returns the current Unix timestamp in milliseconds,
+// optionally shifted by an offset.
+// Usage: timestamp_ms([offset])
+func fnTimestampMs(args []string) (string, error) {
+	t, err := shiftedNow("timestamp_ms", args)
+	if err != nil {
+		return "", err
 	}
-	return strconv.FormatInt(time.Now().Unix(), 10), nil
+	return strconv.FormatInt(t.UnixMilli(), 10), nil
 }
 
-// fnTimestampMs returns the current Unix timestamp in milliseconds.
-func fnTimestampMs(args string) (string, error) {
-	if args != "" {
-		return "", fmt.Errorf("timestamp_ms() takes no arguments")
+func shiftedNow(name string, args []string) (time.Time, error) {
+	switch len(args) {
+	case 0:
+		return clock.Now(), nil
+	case 1:
+		offset, err := parseOffset(args[0])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return clock.Now().Add(offset), nil
 	}
-	return strconv.FormatInt(time.Now().UnixMilli(), 10), nil
+	return time.Time{}, fmt.Errorf("%s([offset]) takes at most 1 argument", name)
 }
 
 // fnRandom generates a random integer between min and max (inclusive).
 // Usage: random(min,max)
-func fnRandom(args string) (string, error) {
-	parts := strings.Split(args, ",")
+func fnRandom(parts []string) (string, error) {
 	if len(parts) != 2 {
 		return "", fmt.Errorf("random(min,max) requires exactly 2 arguments")
 	}
@@ -101,19 +471,16 @@ func fnRandom(args string) (string, error) {
 	}
 
 	// Generate random number in range [min, max]
-	rangeSize := max - min + 1
-	n, err := rand.Int(rand.Reader, big.NewInt(rangeSize))
-	if err != nil {
-		return "", err
-	}
-
-	return strconv.FormatInt(min+n.Int64(), 10), nil
+	return strconv.FormatInt(min+randInt(max-min+1), 10), nil
 }
 
 // fnRandomString generates a random alphanumeric string of the specified length.
 // Usage: random_string(length)
-func fnRandomString(args string) (string, error) {
-	length, err := strconv.Atoi(strings.TrimSpace(args))
+func fnRandomString(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("random_string(length) requires exactly 1 argument")
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(args[0]))
 	if err != nil {
 		return "", fmt.Errorf("invalid length: %w", err)
 	}
@@ -127,11 +494,7 @@ func fnRandomString(args string) (string, error) {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
 	for i := range result {
-		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
-		if err != nil {
-			return "", err
-		}
-		result[i] = charset[n.Int64()]
+		result[i] = charset[randInt(int64(len(charset)))]
 	}
 
 	return string(result), nil
@@ -143,10 +506,10 @@ func fnRandomString(args string) (string, error) {
 //   - date(2006-01-02) -> 2024-01-15
 //   - date(15:04:05) -> 14:30:00
 //   - date(2006-01-02T15:04:05Z07:00) -> ISO 8601
-func fnDate(args string) (string, error) {
-	format := strings.TrimSpace(args)
+func fnDate(args []string) (string, error) {
+	format := strings.TrimSpace(strings.Join(args, ","))
 	if format == "" {
 		format = time.RFC3339
 	}
-	return time.Now().Format(format), nil
+	return clock.Now().Format(format), nil
 }
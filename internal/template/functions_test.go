@@ -1,6 +1,7 @@
 package template
 
 import (
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestFnUUID(t *testing.T) {
-	result, err := fnUUID("")
+	result, err := fnUUID(splitArgs(""))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -23,14 +24,14 @@ func TestFnUUID(t *testing.T) {
 	}
 
 	// Generate another UUID, should be different
-	result2, _ := fnUUID("")
+	result2, _ := fnUUID(splitArgs(""))
 	if result == result2 {
 		t.Error("UUIDs should be unique")
 	}
 }
 
 func TestFnUUID_WithArgs(t *testing.T) {
-	_, err := fnUUID("extra")
+	_, err := fnUUID(splitArgs("extra"))
 	if err == nil {
 		t.Error("expected error for uuid() with arguments")
 	}
@@ -38,7 +39,7 @@ func TestFnUUID_WithArgs(t *testing.T) {
 
 func TestFnTimestamp(t *testing.T) {
 	before := time.Now().Unix()
-	result, err := fnTimestamp("")
+	result, err := fnTimestamp(splitArgs(""))
 	after := time.Now().Unix()
 
 	if err != nil {
@@ -57,7 +58,7 @@ func TestFnTimestamp(t *testing.T) {
 
 func TestFnTimestampMs(t *testing.T) {
 	before := time.Now().UnixMilli()
-	result, err := fnTimestampMs("")
+	result, err := fnTimestampMs(splitArgs(""))
 	after := time.Now().UnixMilli()
 
 	if err != nil {
@@ -75,7 +76,7 @@ func TestFnTimestampMs(t *testing.T) {
 }
 
 func TestFnRandom(t *testing.T) {
-	result, err := fnRandom("1,10")
+	result, err := fnRandom(splitArgs("1,10"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -91,7 +92,7 @@ func TestFnRandom(t *testing.T) {
 }
 
 func TestFnRandom_WithSpaces(t *testing.T) {
-	result, err := fnRandom(" 5 , 15 ")
+	result, err := fnRandom(splitArgs(" 5 , 15 "))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -107,7 +108,7 @@ func TestFnRandom_WithSpaces(t *testing.T) {
 }
 
 func TestFnRandom_SingleValue(t *testing.T) {
-	result, err := fnRandom("42,42")
+	result, err := fnRandom(splitArgs("42,42"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -130,7 +131,7 @@ func TestFnRandom_InvalidArgs(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		_, err := fnRandom(tc.args)
+		_, err := fnRandom(splitArgs(tc.args))
 		if err == nil {
 			t.Errorf("expected error for %s: %q", tc.desc, tc.args)
 		}
@@ -138,7 +139,7 @@ func TestFnRandom_InvalidArgs(t *testing.T) {
 }
 
 func TestFnRandomString(t *testing.T) {
-	result, err := fnRandomString("16")
+	result, err := fnRandomString(splitArgs("16"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -168,7 +169,7 @@ func TestFnRandomString_InvalidArgs(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		_, err := fnRandomString(tc.args)
+		_, err := fnRandomString(splitArgs(tc.args))
 		if err == nil {
 			t.Errorf("expected error for %s: %q", tc.desc, tc.args)
 		}
@@ -176,7 +177,7 @@ func TestFnRandomString_InvalidArgs(t *testing.T) {
 }
 
 func TestFnDate(t *testing.T) {
-	result, err := fnDate("2006-01-02")
+	result, err := fnDate(splitArgs("2006-01-02"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -188,7 +189,7 @@ func TestFnDate(t *testing.T) {
 }
 
 func TestFnDate_EmptyFormat(t *testing.T) {
-	result, err := fnDate("")
+	result, err := fnDate(splitArgs(""))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -285,26 +286,73 @@ func TestSubstitute_UnknownFunction(t *testing.T) {
 	}
 }
 
+func TestSubstitute_FunctionArguments(t *testing.T) {
+	os.Setenv("TEST_FN_PREFIX", "pre")
+	defer os.Unsetenv("TEST_FN_PREFIX")
+
+	vars := core.NewVariables()
+	vars.Set("low", 7)
+	vars.Set("user", map[string]any{"name": "ann"})
+	vars.Set("items", []any{"x", "y"})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${random(low, low)}", "7"},
+		{"${random(low, 3 + 4)}", "7"},
+		{"${random(len(items), 2)}", "2"},
+		{"${hex(user.name)}", "616e6e"},
+		{"${hex(items[1])}", "79"},
+		{"${hex(env:TEST_FN_PREFIX)}", "707265"},
+		{"${hex('a, b')}", "612c2062"},
+		{`${hex("say \"hi\"")}`, "7361792022686922"},
+		{"${hex(base64_decode(base64_encode(user.name)))}", "616e6e"},
+		{"${pick(missing)}", "missing"}, // not a variable: literal
+		{"${date(2006)}", time.Now().Format("2006")},
+		{"${random(1,1)}", "1"},
+	}
+	for _, tt := range tests {
+		got, err := Substitute(tt.input, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+
+	_, err := Substitute("${hex(env:TEST_FN_UNSET)}", vars)
+	if err == nil || !strings.Contains(err.Error(), `function hex: argument 1: env var "TEST_FN_UNSET" not set`) {
+		t.Errorf("expected env error, got %v", err)
+	}
+	_, err = Substitute("${hex(random(5, 1))}", vars)
+	if err == nil || !strings.Contains(err.Error(), "function random") {
+		t.Errorf("expected nested function error, got %v", err)
+	}
+}
+
 // Benchmarks
 
 func BenchmarkFnUUID(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		_, _ = fnUUID("")
+		_, _ = fnUUID(nil)
 	}
 }
 
 func BenchmarkFnRandom(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		_, _ = fnRandom("1,1000000")
+		_, _ = fnRandom([]string{"1", "1000000"})
 	}
 }
 
 func BenchmarkFnRandomString(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		_, _ = fnRandomString("32")
+		_, _ = fnRandomString([]string{"32"})
 	}
 }
 
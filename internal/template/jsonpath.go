@@ -0,0 +1,956 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONPath is a compiled JSONPath expression.
+//
+// Supported syntax: the root $ (optional, so a.b means $.a.b), member
+// access (.name, ['name'], numeric .0 on arrays), wildcards (.*, [*]),
+// indexes ([0], [-1]), unions ([0,2], ['a','b']), slices ([1:3], [::2]),
+// recursive descent ($..id, $..[0]), filters ([?(@.price < 10 &&
+// @.status == 'active')]) and a trailing function: length(), first(),
+// last(), min(), max(), sum() or avg().
+//
+// Filter expressions compare with == != < <= > >= and =~ /regex/, combine
+// with && || ! and parentheses, test existence with a bare path
+// (?(@.email)), and can call the same functions (@.tags.length() > 2 or
+// length(@.tags) > 2).
+//
+// Paths that can select several nodes (wildcards, slices, filters,
+// unions, descent) yield an array of the matches. Object members are
+// visited in key order.
+type JSONPath struct {
+	src      string
+	segments []jpSegment
+	fn       string // trailing function name, "" if none
+	definite bool   // selects at most one node
+}
+
+type jpSegment struct {
+	descendant bool
+	selectors  []jpSelector
+}
+
+type jpSelectorKind int
+
+const (
+	selName jpSelectorKind = iota
+	selIndex
+	selWildcard
+	selSlice
+	selFilter
+)
+
+type jpSelector struct {
+	kind   jpSelectorKind
+	name   string
+	index  int
+	slice  [3]*int // start, end, step
+	filter filterNode
+}
+
+// jsonPathFuncs are the functions a path can end with. They receive the
+// selected value, or the array of matches for paths that are not definite.
+var jsonPathFuncs = map[string]func(v any) (any, bool){
+	"length": jpLength,
+	"first":  func(v any) (any, bool) { return jpEnd(v, true) },
+	"last":   func(v any) (any, bool) { return jpEnd(v, false) },
+	"min":    func(v any) (any, bool) { return jpAggregate(v, "min") },
+	"max":    func(v any) (any, bool) { return jpAggregate(v, "max") },
+	"sum":    func(v any) (any, bool) { return jpAggregate(v, "sum") },
+	"avg":    func(v any) (any, bool) { return jpAggregate(v, "avg") },
+}
+
+// ParseJSONPath compiles a JSONPath, rejecting syntax it does not support.
+func ParseJSONPath(src string) (*JSONPath, error) {
+	s := strings.TrimSpace(src)
+	if s == "" {
+		return nil, errors.New("empty JSONPath")
+	}
+	p := &jpParser{src: s}
+	path, err := p.parsePath(true)
+	if err == nil && p.pos < len(p.src) {
+		err = p.errorf("unexpected %q", p.src[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", src, err)
+	}
+	path.src = src
+	return path, nil
+}
+
+func (p *JSONPath) String() string { return p.src }
+
+// Get evaluates the path against a decoded JSON document and reports
+// whether anything was selected.
+func (p *JSONPath) Get(doc any) (any, bool) {
+	return p.get(doc, doc)
+}
+
+// Query evaluates the path against a JSON body. Invalid JSON yields no value.
+func (p *JSONPath) Query(body []byte) (any, bool) {
+	doc, ok := decodeJSON(body)
+	if !ok {
+		return nil, false
+	}
+	return p.Get(doc)
+}
+
+func (p *JSONPath) get(cur, root any) (any, bool) {
+	nodes := p.eval(cur, root)
+
+	var value any
+	switch {
+	case !p.definite:
+		if len(nodes) == 0 && p.fn == "" {
+			return nil, false
+		}
+		value = nodes
+	case len(nodes) == 1:
+		value = nodes[0]
+	default:
+		return nil, false
+	}
+
+	if p.fn != "" {
+		return jsonPathFuncs[p.fn](value)
+	}
+	return value, true
+}
+
+// eval returns the nodes selected from cur; root is the document for $
+// references inside filters.
+func (p *JSONPath) eval(cur, root any) []any {
+	nodes := []any{cur}
+	for _, seg := range p.segments {
+		next := []any{}
+		for _, node := range nodes {
+			if seg.descendant {
+				walk(node, func(n any) {
+					for _, sel := range seg.selectors {
+						next = sel.apply(n, root, next)
+					}
+				})
+				continue
+			}
+			for _, sel := range seg.selectors {
+				next = sel.apply(node, root, next)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// add appends a segment, tracking whether the path stays definite.
+func (p *JSONPath) add(seg jpSegment) {
+	if seg.descendant || len(seg.selectors) != 1 ||
+		(seg.selectors[0].kind != selName && seg.selectors[0].kind != selIndex) {
+		p.definite = false
+	}
+	p.segments = append(p.segments, seg)
+}
+
+// apply appends the children of node matched by the selector to out.
+func (s *jpSelector) apply(node, root any, out []any) []any {
+	switch s.kind {
+	case selName:
+		switch t := node.(type) {
+		case map[string]any:
+			if v, ok := t[s.name]; ok {
+				out = append(out, v)
+			}
+		case []any:
+			// $.items.0 is accepted as $.items[0]
+			if i, err := strconv.Atoi(s.name); err == nil && i >= 0 && i < len(t) {
+				out = append(out, t[i])
+			}
+		}
+
+	case selIndex:
+		if arr, ok := node.([]any); ok {
+			i := s.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				out = append(out, arr[i])
+			}
+		}
+
+	case selWildcard:
+		out = append(out, children(node)...)
+
+	case selSlice:
+		if arr, ok := node.([]any); ok {
+			out = appendSlice(out, arr, s.slice)
+		}
+
+	case selFilter:
+		for _, child := range children(node) {
+			if filterTruthy(s.filter.eval(child, root)) {
+				out = append(out, child)
+			}
+		}
+	}
+	return out
+}
+
+// children returns array elements, or object values in key order.
+func children(node any) []any {
+	switch t := node.(type) {
+	case []any:
+		return t
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]any, len(keys))
+		for i, k := range keys {
+			values[i] = t[k]
+		}
+		return values
+	}
+	return nil
+}
+
+// walk visits node and all its descendants, parents first.
+func walk(node any, visit func(any)) {
+	visit(node)
+	for _, child := range children(node) {
+		walk(child, visit)
+	}
+}
+
+// appendSlice appends arr[start:end:step] with Python-style negative
+// indexes and steps.
+func appendSlice(out, arr []any, bounds [3]*int) []any {
+	n := len(arr)
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return out
+	}
+
+	// clamp resolves a negative index and limits it to [lo, hi]
+	clamp := func(b *int, def, lo, hi int) int {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		return min(max(i, lo), hi)
+	}
+
+	if step > 0 {
+		start, end := clamp(bounds[0], 0, 0, n), clamp(bounds[1], n, 0, n)
+		for i := start; i < end; i += step {
+			out = append(out, arr[i])
+		}
+		return out
+	}
+	start, end := clamp(bounds[0], n-1, -1, n-1), clamp(bounds[1], -1, -1, n-1)
+	for i := start; i > end; i += step {
+		out = append(out, arr[i])
+	}
+	return out
+}
+
+// decodeJSON decodes a JSON body, reporting whether it was valid.
+func decodeJSON(body []byte) (any, bool) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// Functions
+
+func jpLength(v any) (any, bool) {
+	switch t := v.(type) {
+	case string:
+		return float64(utf8.RuneCountInString(t)), true
+	case []any:
+		return float64(len(t)), true
+	case map[string]any:
+		return float64(len(t)), true
+	}
+	return nil, false
+}
+
+func jpEnd(v any, first bool) (any, bool) {
+	arr, ok := v.([]any)
+	if !ok || len(arr) == 0 {
+		return nil, false
+	}
+	if first {
+		return arr[0], true
+	}
+	return arr[len(arr)-1], true
+}
+
+func jpAggregate(v any, op string) (any, bool) {
+	arr, ok := v.([]any)
+	if !ok || (len(arr) == 0 && op != "sum") {
+		return nil, false
+	}
+	var sum float64
+	result := math.NaN()
+	for _, item := range arr {
+		f, ok := item.(float64)
+		if !ok {
+			return nil, false
+		}
+		sum += f
+		switch {
+		case math.IsNaN(result):
+			result = f
+		case op == "min":
+			result = math.Min(result, f)
+		case op == "max":
+			result = math.Max(result, f)
+		}
+	}
+	switch op {
+	case "sum":
+		return sum, true
+	case "avg":
+		return sum / float64(len(arr)), true
+	}
+	return result, true
+}
+
+// Filter expressions
+
+// filterNode is a node in a filter expression. Paths evaluate to a
+// nodeList so that a bare path can test for existence.
+type filterNode interface {
+	eval(cur, root any) any
+}
+
+// nodeList is the result of a path inside a filter.
+type nodeList []any
+
+type filterLiteral struct{ value any }
+
+type filterPath struct {
+	path     *JSONPath
+	relative bool // @ rather than $
+}
+
+type filterCall struct {
+	name string
+	arg  filterNode
+}
+
+type filterNot struct{ operand filterNode }
+
+type filterLogic struct {
+	op          string // && or ||
+	left, right filterNode
+}
+
+type filterCompare struct {
+	op          string
+	left, right filterNode
+	re          *regexp.Regexp // for =~
+}
+
+func (n *filterLiteral) eval(any, any) any { return n.value }
+
+func (n *filterPath) eval(cur, root any) any {
+	start := root
+	if n.relative {
+		start = cur
+	}
+	if n.path.fn != "" {
+		if v, ok := n.path.get(start, root); ok {
+			return nodeList{v}
+		}
+		return nodeList{}
+	}
+	return nodeList(n.path.eval(start, root))
+}
+
+func (n *filterCall) eval(cur, root any) any {
+	arg := n.arg.eval(cur, root)
+	if list, ok := arg.(nodeList); ok {
+		if p, ok := n.arg.(*filterPath); ok && !p.path.definite {
+			arg = []any(list)
+		} else if len(list) == 1 {
+			arg = list[0]
+		} else {
+			return nodeList{}
+		}
+	}
+	if v, ok := jsonPathFuncs[n.name](arg); ok {
+		return v
+	}
+	return nodeList{}
+}
+
+func (n *filterNot) eval(cur, root any) any {
+	return !filterTruthy(n.operand.eval(cur, root))
+}
+
+func (n *filterLogic) eval(cur, root any) any {
+	left := filterTruthy(n.left.eval(cur, root))
+	if n.op == "&&" && !left {
+		return false
+	}
+	if n.op == "||" && left {
+		return true
+	}
+	return filterTruthy(n.right.eval(cur, root))
+}
+
+func (n *filterCompare) eval(cur, root any) any {
+	left, lok := singleValue(n.left.eval(cur, root))
+	if n.re != nil {
+		s, ok := left.(string)
+		return lok && ok && n.re.MatchString(s)
+	}
+	right, rok := singleValue(n.right.eval(cur, root))
+
+	switch n.op {
+	case "==":
+		return lok == rok && (!lok || jpEqual(left, right))
+	case "!=":
+		return lok != rok || (lok && !jpEqual(left, right))
+	}
+	if !lok || !rok {
+		return false
+	}
+	_, ls := left.(string)
+	_, rs := right.(string)
+	if ls != rs {
+		// Strings and numbers have no common order
+		return false
+	}
+	ok, err := compare(n.op, left, right)
+	return err == nil && ok
+}
+
+// jpEqual compares JSON values; unlike expressions, a string never
+// equals a number.
+func jpEqual(a, b any) bool {
+	_, as := a.(string)
+	_, bs := b.(string)
+	return as == bs && equal(a, b)
+}
+
+// singleValue unwraps a path result that selected exactly one node.
+// Anything else compares as missing.
+func singleValue(v any) (any, bool) {
+	list, ok := v.(nodeList)
+	if !ok {
+		return v, true
+	}
+	if len(list) == 1 {
+		return list[0], true
+	}
+	return nil, false
+}
+
+// filterTruthy treats paths as existence tests and other values as booleans.
+func filterTruthy(v any) bool {
+	if list, ok := v.(nodeList); ok {
+		return len(list) > 0
+	}
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// Parsing
+
+type jpParser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+func (p *jpParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *jpParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *jpParser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *jpParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *jpParser) enter() error {
+	p.depth++
+	if p.depth > maxExprDepth {
+		return p.errorf("expression nested too deeply")
+	}
+	return nil
+}
+
+func (p *jpParser) leave() { p.depth-- }
+
+// parsePath parses a path starting at $ or @. At the top level the $ may
+// be omitted; inside filters parsing stops at the first character that
+// cannot continue the path.
+func (p *jpParser) parsePath(top bool) (*JSONPath, error) {
+	path := &JSONPath{definite: true}
+	switch c := p.peek(); {
+	case c == '$' || (c == '@' && !top):
+		p.pos++
+		if top && p.pos < len(p.src) && p.peek() != '.' && p.peek() != '[' {
+			// $foo.bar is read as $.foo.bar
+			if err := p.parseMember(path, false); err != nil {
+				return nil, err
+			}
+		}
+	case top && c != '[':
+		if err := p.parseMember(path, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for p.pos < len(p.src) && path.fn == "" {
+		switch {
+		case p.hasPrefix(".."):
+			p.pos += 2
+			if p.peek() == '[' {
+				if err := p.parseBracket(path, true); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := p.parseMember(path, true); err != nil {
+				return nil, err
+			}
+		case p.peek() == '.':
+			p.pos++
+			if err := p.parseMember(path, false); err != nil {
+				return nil, err
+			}
+		case p.peek() == '[':
+			if err := p.parseBracket(path, false); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+	if path.fn != "" && top && p.pos < len(p.src) {
+		return nil, p.errorf("%s() must end the path", path.fn)
+	}
+	return path, nil
+}
+
+// memberStop are the characters that end a dot-notation member name.
+const memberStop = ".[]()=!<>&|,~ \t'\""
+
+// parseMember parses a dot-notation name, * or trailing function call.
+func (p *jpParser) parseMember(path *JSONPath, descendant bool) error {
+	if p.peek() == '*' {
+		p.pos++
+		path.add(jpSegment{descendant: descendant, selectors: []jpSelector{{kind: selWildcard}}})
+		return nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(memberStop, rune(p.src[p.pos])) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		return p.errorf("expected member name")
+	}
+
+	if p.peek() == '(' {
+		if _, ok := jsonPathFuncs[name]; !ok {
+			p.pos = start
+			return p.errorf("unsupported function %s()", name)
+		}
+		if !p.hasPrefix("()") {
+			return p.errorf("%s() takes no arguments", name)
+		}
+		if descendant {
+			return p.errorf("%s() cannot follow ..", name)
+		}
+		p.pos += 2
+		path.fn = name
+		return nil
+	}
+
+	path.add(jpSegment{descendant: descendant, selectors: []jpSelector{{kind: selName, name: name}}})
+	return nil
+}
+
+// parseBracket parses [selector, ...].
+func (p *jpParser) parseBracket(path *JSONPath, descendant bool) error {
+	p.pos++ // [
+	seg := jpSegment{descendant: descendant}
+	for {
+		p.skipSpace()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return err
+		}
+		seg.selectors = append(seg.selectors, sel)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			path.add(seg)
+			return nil
+		case 0:
+			return p.errorf("missing ]")
+		default:
+			return p.errorf("unexpected %q in brackets", p.peek())
+		}
+	}
+}
+
+func (p *jpParser) parseSelector() (jpSelector, error) {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return jpSelector{kind: selWildcard}, nil
+
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		return jpSelector{kind: selName, name: s}, err
+
+	case c == '?':
+		p.pos++
+		filter, err := p.parseOr()
+		return jpSelector{kind: selFilter, filter: filter}, err
+
+	case c == '(':
+		return jpSelector{}, p.errorf("script expressions [(...)] are not supported")
+
+	case c == '-' || c == ':' || isDigit(c):
+		return p.parseIndexOrSlice()
+	}
+	return jpSelector{}, p.errorf("unsupported selector")
+}
+
+// parseIndexOrSlice parses [n] or [start:end:step].
+func (p *jpParser) parseIndexOrSlice() (jpSelector, error) {
+	var bounds [3]*int
+	for part := 0; ; part++ {
+		p.skipSpace()
+		if c := p.peek(); c == '-' || isDigit(c) {
+			n, err := p.parseInt()
+			if err != nil {
+				return jpSelector{}, err
+			}
+			bounds[part] = &n
+		}
+		p.skipSpace()
+		if p.peek() != ':' {
+			if part > 0 {
+				return jpSelector{kind: selSlice, slice: bounds}, nil
+			}
+			if bounds[0] == nil {
+				return jpSelector{}, p.errorf("expected index")
+			}
+			return jpSelector{kind: selIndex, index: *bounds[0]}, nil
+		}
+		if part == 2 {
+			return jpSelector{}, p.errorf("too many : in slice")
+		}
+		p.pos++
+	}
+}
+
+func (p *jpParser) parseInt() (int, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		p.pos = start
+		return 0, p.errorf("invalid number %q", text)
+	}
+	return n, nil
+}
+
+func (p *jpParser) parseString() (string, error) {
+	quote := p.src[p.pos]
+	start := p.pos
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			sb.WriteByte(p.src[p.pos])
+		default:
+			sb.WriteByte(c)
+		}
+		p.pos++
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jpParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpace(); p.hasPrefix("||"); p.skipSpace() {
+		p.pos += 2
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterLogic{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jpParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpace(); p.hasPrefix("&&"); p.skipSpace() {
+		p.pos += 2
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterLogic{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jpParser) parseNot() (filterNode, error) {
+	p.skipSpace()
+	if p.peek() != '!' || p.hasPrefix("!=") {
+		return p.parseComparison()
+	}
+	p.pos++
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &filterNot{operand: operand}, nil
+}
+
+// filterOps are the comparison operators, longest first.
+var filterOps = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+func (p *jpParser) parseComparison() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range filterOps {
+		if !p.hasPrefix(op) {
+			continue
+		}
+		p.pos += len(op)
+		p.skipSpace()
+		if op == "=~" {
+			re, err := p.parseRegex()
+			if err != nil {
+				return nil, err
+			}
+			return &filterCompare{op: op, left: left, re: re}, nil
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &filterCompare{op: op, left: left, right: right}, nil
+	}
+	if p.peek() == '=' {
+		return nil, p.errorf("use == to compare")
+	}
+	return left, nil
+}
+
+// parseRegex parses /pattern/ with an optional i flag, or a quoted pattern.
+func (p *jpParser) parseRegex() (*regexp.Regexp, error) {
+	var pattern string
+	switch p.peek() {
+	case '\'', '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		pattern = s
+	case '/':
+		start := p.pos
+		p.pos++
+		var sb strings.Builder
+		for p.pos < len(p.src) && p.src[p.pos] != '/' {
+			if p.hasPrefix(`\/`) {
+				p.pos++
+			}
+			sb.WriteByte(p.src[p.pos])
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			p.pos = start
+			return nil, p.errorf("unterminated regex")
+		}
+		p.pos++
+		pattern = sb.String()
+		if p.peek() == 'i' {
+			p.pos++
+			pattern = "(?i)" + pattern
+		}
+	default:
+		return nil, p.errorf("=~ expects /regex/")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, p.errorf("invalid regex: %v", err)
+	}
+	return re, nil
+}
+
+func (p *jpParser) parseOperand() (filterNode, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+
+	case c == '@' || c == '$':
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		path, err := p.parsePath(false)
+		if err != nil {
+			return nil, err
+		}
+		return &filterPath{path: path, relative: c == '@'}, nil
+
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		return &filterLiteral{value: s}, err
+
+	case c == '-' || isDigit(c):
+		return p.parseNumber()
+
+	case isIdentStart(c):
+		return p.parseWord()
+
+	case c == 0:
+		return nil, p.errorf("unexpected end of filter")
+	}
+	return nil, p.errorf("unexpected %q in filter", p.peek())
+}
+
+func (p *jpParser) parseNumber() (filterNode, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		exponentSign := (c == '+' || c == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')
+		if !isDigit(c) && c != '.' && c != 'e' && c != 'E' && !exponentSign {
+			break
+		}
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid number")
+	}
+	return &filterLiteral{value: f}, nil
+}
+
+// parseWord parses true, false, null or a function call like length(@.tags).
+func (p *jpParser) parseWord() (filterNode, error) {
+	start := p.pos
+	for isIdentChar(p.peek()) {
+		p.pos++
+	}
+	word := p.src[start:p.pos]
+	switch word {
+	case "true":
+		return &filterLiteral{value: true}, nil
+	case "false":
+		return &filterLiteral{value: false}, nil
+	case "null":
+		return &filterLiteral{value: nil}, nil
+	}
+
+	if p.peek() != '(' {
+		p.pos = start
+		return nil, p.errorf("unexpected %q (filter paths start with @ or $)", word)
+	}
+	if _, ok := jsonPathFuncs[word]; !ok {
+		p.pos = start
+		return nil, p.errorf("unsupported function %s()", word)
+	}
+	p.pos++
+	arg, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return nil, p.errorf("%s() takes one argument", word)
+	}
+	p.pos++
+	return &filterCall{name: word, arg: arg}, nil
+}
@@ -0,0 +1,168 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const storeJSON = `{
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95, "status": "active"},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99, "status": "active", "tags": ["war", "classic", "uk"]},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99, "status": "retired"},
+			{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99, "status": "active"}
+		],
+		"bicycle": {"color": "red", "price": 19.95}
+	},
+	"limit": 10,
+	"counts": [1, 2, 3, 6],
+	"items": [{"id": "a", "status": "active"}, {"id": "b", "status": "closed"}, {"id": "c", "status": "active"}]
+}`
+
+func TestJSONPath_Get(t *testing.T) {
+	doc, ok := decodeJSON([]byte(storeJSON))
+	if !ok {
+		t.Fatal("invalid test document")
+	}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		// Definite paths
+		{"$.store.bicycle.color", "red"},
+		{"$.store.book[0].author", "Nigel Rees"},
+		{"$.store.book[-1].title", "The Lord of the Rings"},
+		{"$['store']['bicycle']['price']", 19.95},
+		{`$.store["bicycle"].color`, "red"},
+
+		// Filters
+		{"$.items[?(@.status=='active')].id", []any{"a", "c"}},
+		{"$.items[?(@.status != 'active')].id", []any{"b"}},
+		{"$.store.book[?(@.price < 10)].title", []any{"Sayings of the Century", "Moby Dick"}},
+		{"$.store.book[?(@.isbn)].author", []any{"Herman Melville", "J. R. R. Tolkien"}},
+		{"$.store.book[?(!@.isbn)].price", []any{8.95, 12.99}},
+		{"$.store.book[?(@.price > 10 && @.category == 'fiction')].title", []any{"Sword of Honour", "The Lord of the Rings"}},
+		{"$.store.book[?(@.price < 9 || @.status == 'retired')].price", []any{8.95, 8.99}},
+		{"$.store.book[?(@.author =~ /tolkien/i)].price", []any{22.99}},
+		{"$.store.book[?(@.price <= $.limit)].price", []any{8.95, 8.99}},
+		{"$.store.book[?(@.tags.length() > 2)].title", []any{"Sword of Honour"}},
+		{"$.store.book[?(length(@.title) < 10)].title", []any{"Moby Dick"}},
+		{"$.items[?(@.status == 'active')].id.first()", "a"},
+
+		// Slices and unions
+		{"$.items[0:2].id", []any{"a", "b"}},
+		{"$.items[1:].id", []any{"b", "c"}},
+		{"$.items[-2:].id", []any{"b", "c"}},
+		{"$.items[::2].id", []any{"a", "c"}},
+		{"$.items[::-1].id", []any{"c", "b", "a"}},
+		{"$.items[0,2].id", []any{"a", "c"}},
+		{"$.store.bicycle['color','price']", []any{"red", 19.95}},
+
+		// Wildcards and recursive descent
+		{"$.items[*].id", []any{"a", "b", "c"}},
+		{"$.items.*.id", []any{"a", "b", "c"}},
+		{"$..id", []any{"a", "b", "c"}},
+		{"$..isbn", []any{"0-553-21311-3", "0-395-19395-8"}},
+		{"$.store..price", []any{19.95, 8.95, 12.99, 8.99, 22.99}},
+		{"$..book[2].title", []any{"Moby Dick"}},
+
+		// Functions
+		{"$.items.length()", float64(3)},
+		{"$.store.bicycle.color.length()", float64(3)},
+		{"$.store.bicycle.length()", float64(2)},
+		{"$..id.length()", float64(3)},
+		{"$.items[?(@.status == 'gone')].length()", float64(0)},
+		{"$.store.book[*].price.min()", 8.95},
+		{"$.store.book[*].price.max()", 22.99},
+		{"$.counts.sum()", float64(12)},
+		{"$.counts[1:].avg()", float64(11) / 3},
+		{"$.items[*].id.last()", "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := ParseJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, ok := p.Get(doc)
+			if !ok {
+				t.Fatal("expected a value")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONPath_NotFound(t *testing.T) {
+	doc, _ := decodeJSON([]byte(storeJSON))
+	for _, path := range []string{
+		"$.missing",
+		"$.store.book[10]",
+		"$.items[?(@.status == 'gone')].id",
+		"$.items[?(@.status == 'gone')].id.first()",
+		"$..nothing",
+		"$.store.bicycle.color.first()",
+		"$.items[*].id.sum()", // not numbers
+	} {
+		p, err := ParseJSONPath(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+		if got, ok := p.Get(doc); ok {
+			t.Errorf("%s: expected no value, got %#v", path, got)
+		}
+	}
+}
+
+func TestParseJSONPath_Errors(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "empty JSONPath"},
+		{"$.items[0", "missing ]"},
+		{"$.items[(@.length-1)]", "script expressions"},
+		{"$.items[?(@.id = 'a')]", "use == to compare"},
+		{"$.items[?(@.id == 'a']", "missing )"},
+		{"$.items[?(status == 'a')]", "filter paths start with @ or $"},
+		{"$.items.count()", "unsupported function count()"},
+		{"$.items.length(1)", "takes no arguments"},
+		{"$.items.length().id", "must end the path"},
+		{"$..", "expected member name"},
+		{"$.items[1:2:3:4]", "too many :"},
+		{"$.items[?(@.id =~ /[/)]", "invalid regex"},
+		{"$.a b", "unexpected ' '"},
+		{"$.items[foo]", "unsupported selector"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := ParseJSONPath(tt.path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func BenchmarkJSONPath_Filter(b *testing.B) {
+	doc, _ := decodeJSON([]byte(storeJSON))
+	p, err := ParseJSONPath("$.store.book[?(@.price < 10 && @.status == 'active')].title")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = p.Get(doc)
+	}
+}
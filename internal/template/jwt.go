@@ -0,0 +1,323 @@
+package template
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwtTimeClaims are the claims holding NumericDate values. They accept a
+// Unix time, "now", or a duration relative to now such as +1h or -5m.
+var jwtTimeClaims = map[string]bool{"exp": true, "nbf": true, "iat": true}
+
+// jwtKeys caches parsed key files by path and algorithm family, so
+// signing in a hot loop does not re-read and re-parse PEM files.
+var jwtKeys sync.Map
+
+type jwtKeyID struct{ alg, path string }
+
+// fnJWTSign creates a signed JWT. Claims are name=value arguments or
+// objects (map variables); later claims win. iat defaults to now.
+// Usage: jwt_sign(alg, keyfile, sub=user_id, exp=+1h, claims)
+//
+// alg is HS256, RS256 or ES256. For HS256 the key file holds the shared
+// secret (a trailing newline is ignored); for RS256 and ES256 it holds a
+// PEM private key in PKCS#1, SEC 1 or PKCS#8 form.
+func fnJWTSign(args []string) (string, error) {
+	if len(args) < 2 {
+		return "", errors.New("jwt_sign(alg, keyfile, claims...) requires at least 2 arguments")
+	}
+	alg, path := strings.ToUpper(args[0]), args[1]
+	key, err := loadJWTKey(alg, path)
+	if err != nil {
+		return "", err
+	}
+
+	now := clock.Now()
+	claims := map[string]any{"iat": now.Unix()}
+	for _, arg := range args[2:] {
+		if err := addClaims(claims, arg, now); err != nil {
+			return "", err
+		}
+	}
+
+	header, _ := marshalJSON(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := marshalJSON(claims)
+	if err != nil {
+		return "", fmt.Errorf("encoding claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	sig, err := signJWT(alg, key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// addClaims adds a name=value claim or the members of a JSON object.
+func addClaims(claims map[string]any, arg string, now time.Time) error {
+	if strings.HasPrefix(strings.TrimSpace(arg), "{") {
+		var obj map[string]any
+		dec := json.NewDecoder(strings.NewReader(arg))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return fmt.Errorf("invalid claims object: %w", err)
+		}
+		for name, value := range obj {
+			if err := setClaim(claims, name, value, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok {
+		return fmt.Errorf("claim %q must be name=value or an object", arg)
+	}
+	var v any = value
+	if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+		// Arrays and objects from variables arrive as JSON
+		var decoded any
+		if json.Unmarshal([]byte(value), &decoded) == nil {
+			v = decoded
+		}
+	}
+	return setClaim(claims, name, v, now)
+}
+
+func setClaim(claims map[string]any, name string, value any, now time.Time) error {
+	if !jwtTimeClaims[name] {
+		claims[name] = value
+		return nil
+	}
+	s := strings.TrimSpace(fmt.Sprint(value))
+	switch {
+	case s == "now":
+		claims[name] = now.Unix()
+	case strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-"):
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("claim %s: invalid relative time %q", name, s)
+		}
+		claims[name] = now.Add(d).Unix()
+	default:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("claim %s: want a Unix time, now or a duration such as +1h, got %q", name, s)
+		}
+		claims[name] = n
+	}
+	return nil
+}
+
+// keyFileArg is the key file argument of a jwt_sign call in a template.
+type keyFileArg struct {
+	start, end int // the argument as written, without surrounding spaces
+	path       string
+}
+
+// keyFileArgs finds the key file arguments of the jwt_sign calls in text.
+func keyFileArgs(text string) []keyFileArg {
+	const call = "jwt_sign("
+	var found []keyFileArg
+	for from := 0; ; {
+		i := strings.Index(text[from:], call)
+		if i < 0 {
+			return found
+		}
+		i += from
+		from = i + len(call)
+		if i > 0 && isIdentChar(text[i-1]) {
+			continue
+		}
+		open := i + len(call) - 1
+		end := closingParen(text, open)
+		if end < 0 {
+			continue
+		}
+		parts := splitArgs(text[open+1 : end])
+		if len(parts) < 2 {
+			continue
+		}
+		start := open + 1 + len(parts[0]) + 1
+		arg := strings.TrimSpace(parts[1])
+		start += strings.Index(parts[1], arg)
+		found = append(found, keyFileArg{start: start, end: start + len(arg), path: keyFilePath(arg)})
+	}
+}
+
+// keyFilePath returns the path a key file argument names. It is always a
+// literal path, quoted or not, never a variable or expression.
+func keyFilePath(arg string) string {
+	if len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0] {
+		p := &jpParser{src: arg}
+		if s, err := p.parseString(); err == nil && p.pos == len(arg) {
+			return s
+		}
+	}
+	return arg
+}
+
+// KeyFiles returns the key files read by the jwt_sign calls in text.
+func KeyFiles(text string) []string {
+	var paths []string
+	for _, arg := range keyFileArgs(text) {
+		paths = append(paths, arg.path)
+	}
+	return paths
+}
+
+// pathQuoter quotes a path as a single-quoted template string.
+var pathQuoter = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// ResolveKeyFiles returns text with the relative key files of its jwt_sign
+// calls resolved against dir, the directory of the file text came from.
+func ResolveKeyFiles(text, dir string) string {
+	args := keyFileArgs(text)
+	for i := len(args) - 1; i >= 0; i-- {
+		arg := args[i]
+		if arg.path == "" || filepath.IsAbs(arg.path) {
+			continue
+		}
+		path := filepath.Join(dir, arg.path)
+		text = text[:arg.start] + "'" + pathQuoter.Replace(path) + "'" + text[arg.end:]
+	}
+	return text
+}
+
+// loadJWTKey reads and parses a key file once per path and algorithm.
+func loadJWTKey(alg, path string) (any, error) {
+	id := jwtKeyID{alg, path}
+	if key, ok := jwtKeys.Load(id); ok {
+		return key, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	var key any
+	switch alg {
+	case "HS256":
+		key = bytes.TrimRight(data, "\r\n")
+	case "RS256", "ES256":
+		if key, err = parsePrivateKey(alg, data); err != nil {
+			return nil, fmt.Errorf("key %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q (want HS256, RS256 or ES256)", alg)
+	}
+	jwtKeys.Store(id, key)
+	return key, nil
+}
+
+func parsePrivateKey(alg string, data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if alg == "RS256" {
+			return k, nil
+		}
+	case *ecdsa.PrivateKey:
+		if alg == "ES256" {
+			if k.Curve != elliptic.P256() {
+				return nil, errors.New("ES256 requires a P-256 key")
+			}
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%T cannot sign %s", key, alg)
+}
+
+func signJWT(alg string, key any, input []byte) ([]byte, error) {
+	if alg == "HS256" {
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	}
+
+	digest := sha256.Sum256(input)
+	if alg == "RS256" {
+		return rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	}
+	// ES256 signatures are r and s as fixed-size big-endian integers
+	r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig, nil
+}
+
+// fnJWTClaim reads a claim from a JWT without verifying its signature.
+// The claim is a name or a JSONPath into the payload (realm.roles[0]).
+// Usage: jwt_claim(token, "sub")
+func fnJWTClaim(args []string) (string, error) {
+	if len(args) != 2 {
+		return "", errors.New("jwt_claim(token, claim) requires exactly 2 arguments")
+	}
+	parts := strings.Split(strings.TrimSpace(strings.TrimPrefix(args[0], "Bearer ")), ".")
+	if len(parts) != 3 {
+		return "", errors.New("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT payload: %w", err)
+	}
+	doc, ok := decodeJSON(payload)
+	if !ok {
+		return "", errors.New("invalid JWT payload: not JSON")
+	}
+
+	path, err := ParseJSONPath(args[1])
+	if err != nil {
+		return "", err
+	}
+	value, ok := path.Get(doc)
+	if !ok {
+		return "", fmt.Errorf("claim %q not found", args[1])
+	}
+	return formatValue(value), nil
+}
@@ -0,0 +1,259 @@
+package template
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"maestro/internal/core"
+)
+
+// writeKey writes a PEM block to a temporary file.
+func writeKey(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// decodeJWT splits a token and decodes its header and payload.
+func decodeJWT(t *testing.T, token string) (header, claims map[string]any, signingInput string, sig []byte) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %q", token)
+	}
+	for i, dst := range []*map[string]any{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if err := json.Unmarshal(data, dst); err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("signature: %v", err)
+	}
+	return header, claims, parts[0] + "." + parts[1], sig
+}
+
+func TestJWTSign_HS256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.key")
+	os.WriteFile(path, []byte("s3cr3t\n"), 0o600)
+
+	vars := core.NewVariables()
+	vars.Set("user", map[string]any{"id": "u-7", "roles": []any{"admin", "ops"}})
+	vars.Set("extra", map[string]any{"tenant": "acme", "sub": "overridden"})
+
+	before := time.Now().Unix()
+	token, err := Substitute("${jwt_sign(HS256, '"+path+"', extra, sub=user.id, roles=user.roles, exp=+1h, nbf=-30s, scope='read write')}", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	header, claims, input, sig := decodeJWT(t, token)
+	if header["alg"] != "HS256" || header["typ"] != "JWT" {
+		t.Errorf("unexpected header %v", header)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(input))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		t.Error("invalid HS256 signature")
+	}
+
+	if claims["sub"] != "u-7" || claims["tenant"] != "acme" || claims["scope"] != "read write" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if roles, _ := json.Marshal(claims["roles"]); string(roles) != `["admin","ops"]` {
+		t.Errorf("expected roles array, got %s", roles)
+	}
+	iat := int64(claims["iat"].(float64))
+	if iat < before || iat > time.Now().Unix() {
+		t.Errorf("unexpected iat %d", iat)
+	}
+	if exp := int64(claims["exp"].(float64)); exp != iat+3600 {
+		t.Errorf("expected exp one hour after iat, got %d (iat %d)", exp, iat)
+	}
+	if nbf := int64(claims["nbf"].(float64)); nbf != iat-30 {
+		t.Errorf("expected nbf 30s before iat, got %d", nbf)
+	}
+}
+
+func TestJWTSign_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	for name, path := range map[string]string{
+		"pkcs1": writeKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		"pkcs8": writeKey(t, "PRIVATE KEY", pkcs8),
+	} {
+		token, err := fnJWTSign([]string{"RS256", path, "sub=alice", "iat=1700000000"})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		header, claims, input, sig := decodeJWT(t, token)
+		digest := sha256.Sum256([]byte(input))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+			t.Errorf("%s: invalid RS256 signature: %v", name, err)
+		}
+		if header["alg"] != "RS256" || claims["sub"] != "alice" || claims["iat"] != float64(1700000000) {
+			t.Errorf("%s: unexpected token %v %v", name, header, claims)
+		}
+	}
+}
+
+func TestJWTSign_ES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalECPrivateKey(key)
+	path := writeKey(t, "EC PRIVATE KEY", der)
+
+	token, err := fnJWTSign([]string{"es256", path, "sub=bob"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	header, _, input, sig := decodeJWT(t, token)
+	if header["alg"] != "ES256" || len(sig) != 64 {
+		t.Fatalf("unexpected header %v or signature length %d", header, len(sig))
+	}
+	digest := sha256.Sum256([]byte(input))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("invalid ES256 signature")
+	}
+}
+
+func TestJWTSign_RelativeKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "keys"), 0o700)
+	os.WriteFile(filepath.Join(dir, "keys", "hs.key"), []byte("s3cr3t"), 0o600)
+
+	text := ResolveKeyFiles("Bearer ${jwt_sign(HS256, keys/hs.key, sub='u-1')}", dir)
+	want := filepath.Join(dir, "keys", "hs.key")
+	if files := KeyFiles(text); len(files) != 1 || files[0] != want {
+		t.Fatalf("expected key file %s, got %v in %s", want, files, text)
+	}
+
+	// Rendering from another directory still finds the key
+	token, err := Substitute(strings.TrimPrefix(text, "Bearer "), core.NewVariables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, claims, input, sig := decodeJWT(t, token)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(input))
+	if !hmac.Equal(sig, mac.Sum(nil)) || claims["sub"] != "u-1" {
+		t.Errorf("unexpected token %s", token)
+	}
+
+	// Absolute and quoted paths
+	abs := "${jwt_sign(RS256, '/etc/keys/rs.pem')} ${jwt_sign(ES256, \"k 1.pem\", sub=id)}"
+	got := KeyFiles(ResolveKeyFiles(abs, dir))
+	if len(got) != 2 || got[0] != "/etc/keys/rs.pem" || got[1] != filepath.Join(dir, "k 1.pem") {
+		t.Errorf("unexpected key files %v", got)
+	}
+}
+
+func TestJWTSign_Errors(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPath := writeKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(p384)
+	p384Path := writeKey(t, "EC PRIVATE KEY", der)
+	notPEM := filepath.Join(t.TempDir(), "plain.txt")
+	os.WriteFile(notPEM, []byte("secret"), 0o600)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"HS256"}, "requires at least 2 arguments"},
+		{[]string{"PS256", rsaPath}, "unsupported algorithm"},
+		{[]string{"HS256", "/does/not/exist"}, "reading key"},
+		{[]string{"RS256", notPEM}, "no PEM block found"},
+		{[]string{"ES256", rsaPath}, "cannot sign ES256"},
+		{[]string{"ES256", p384Path}, "requires a P-256 key"},
+		{[]string{"RS256", rsaPath, "sub"}, "must be name=value"},
+		{[]string{"RS256", rsaPath, "exp=tomorrow"}, "want a Unix time"},
+		{[]string{"RS256", rsaPath, "exp=+1 day"}, "invalid relative time"},
+		{[]string{"RS256", rsaPath, "{bad"}, "invalid claims object"},
+	}
+	for _, tt := range tests {
+		_, err := fnJWTSign(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestJWTClaim(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.key")
+	os.WriteFile(path, []byte("k"), 0o600)
+	token, err := fnJWTSign([]string{"HS256", path, "sub=carol", `realm={"roles":["admin"]}`, "exp=1700003600"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := core.NewVariables()
+	vars.Set("token", token)
+	vars.Set("auth_header", "Bearer "+token)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`${jwt_claim(token, "sub")}`, "carol"},
+		{`${jwt_claim(auth_header, 'sub')}`, "carol"},
+		{`${jwt_claim(token, "realm.roles[0]")}`, "admin"},
+		{`${jwt_claim(token, "exp")}`, "1700003600"},
+		{`${jwt_claim(token, "realm")}`, `{"roles":["admin"]}`},
+	}
+	for _, tt := range tests {
+		got, err := Substitute(tt.input, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{token, "email"}, `claim "email" not found`},
+		{[]string{"not-a-token", "sub"}, "not a JWT"},
+		{[]string{"a.!!!.c", "sub"}, "invalid JWT payload"},
+		{[]string{token}, "requires exactly 2 arguments"},
+	} {
+		_, err := fnJWTClaim(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
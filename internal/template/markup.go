@@ -0,0 +1,376 @@
+package template
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a parsed XML or HTML body that XPath expressions and CSS
+// selectors are evaluated against.
+type Document struct {
+	root *node
+	html bool // element and attribute names are case-insensitive
+}
+
+type nodeKind int
+
+const (
+	documentNode nodeKind = iota
+	elementNode
+	textNode
+	attrNode
+)
+
+type node struct {
+	kind     nodeKind
+	name     string // local name of elements and attributes
+	value    string // text and attribute values
+	attrs    []*node
+	children []*node
+	parent   *node
+	order    int // position in document order
+}
+
+// stringValue is the XPath string value: the text of elements and the
+// document including their descendants, the value of anything else.
+func (n *node) stringValue() string {
+	if n.kind != elementNode && n.kind != documentNode {
+		return n.value
+	}
+	var b strings.Builder
+	var collect func(*node)
+	collect = func(n *node) {
+		for _, c := range n.children {
+			if c.kind == textNode {
+				b.WriteString(c.value)
+			} else {
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+	return b.String()
+}
+
+func (n *node) appendText(s string) {
+	if s == "" {
+		return
+	}
+	if last := len(n.children) - 1; last >= 0 && n.children[last].kind == textNode {
+		n.children[last].value += s
+		return
+	}
+	n.children = append(n.children, &node{kind: textNode, value: s, parent: n})
+}
+
+// attr returns the value of the named attribute.
+func (d *Document) attr(n *node, name string) (string, bool) {
+	for _, a := range n.attrs {
+		if d.nameMatches(a.name, name) {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// nameMatches compares a node name with a name from an expression.
+func (d *Document) nameMatches(name, want string) bool {
+	if d.html {
+		return strings.EqualFold(name, want)
+	}
+	return name == want
+}
+
+// number assigns document order, attributes following their element.
+func (d *Document) number() {
+	order := 0
+	var visit func(*node)
+	visit = func(n *node) {
+		n.order = order
+		order++
+		for _, a := range n.attrs {
+			a.order = order
+			order++
+		}
+		for _, c := range n.children {
+			visit(c)
+		}
+	}
+	visit(d.root)
+}
+
+// elements returns all elements in document order.
+func (d *Document) elements() []*node {
+	var out []*node
+	var visit func(*node)
+	visit = func(n *node) {
+		for _, c := range n.children {
+			if c.kind == elementNode {
+				out = append(out, c)
+				visit(c)
+			}
+		}
+	}
+	visit(d.root)
+	return out
+}
+
+// ParseXML parses a well-formed XML document. Names keep only their local
+// part, so expressions match elements regardless of namespace prefixes.
+func ParseXML(body []byte) (*Document, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = charsetReader
+
+	doc := &Document{root: &node{kind: documentNode}}
+	cur := doc.root
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &node{kind: elementNode, name: t.Name.Local, parent: cur}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				el.attrs = append(el.attrs, &node{kind: attrNode, name: a.Name.Local, value: a.Value, parent: el})
+			}
+			cur.children = append(cur.children, el)
+			cur = el
+		case xml.EndElement:
+			cur = cur.parent
+		case xml.CharData:
+			if cur != doc.root {
+				cur.appendText(string(t))
+			}
+		}
+	}
+	if len(doc.elements()) == 0 {
+		return nil, errors.New("invalid XML: no root element")
+	}
+	doc.number()
+	return doc, nil
+}
+
+// charsetReader decodes the single-byte encodings legacy services still
+// declare; encoding/xml only reads UTF-8 itself.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, 0, len(data))
+		for _, c := range data {
+			out = utf8.AppendRune(out, rune(c))
+		}
+		return bytes.NewReader(out), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// htmlVoid are the elements that never have content or an end tag.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawText are the elements whose content is not markup.
+var htmlRawText = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// htmlImpliedEnd lists, for a start tag, the open elements it closes.
+var htmlImpliedEnd = map[string][]string{
+	"li":     {"li"},
+	"option": {"option"},
+	"p":      {"p"},
+	"tr":     {"tr", "td", "th"},
+	"td":     {"td", "th"},
+	"th":     {"td", "th"},
+	"dt":     {"dt", "dd"},
+	"dd":     {"dt", "dd"},
+}
+
+// ParseHTML parses an HTML document. Like browsers it never fails: stray
+// end tags are ignored, unclosed elements end with their parent, and
+// element and attribute names are lowercased.
+func ParseHTML(body []byte) *Document {
+	doc := &Document{root: &node{kind: documentNode}, html: true}
+	p := &htmlParser{src: string(body), cur: doc.root}
+	p.parse()
+	doc.number()
+	return doc
+}
+
+type htmlParser struct {
+	src string
+	pos int
+	cur *node
+}
+
+func (p *htmlParser) parse() {
+	for p.pos < len(p.src) {
+		lt := strings.IndexByte(p.src[p.pos:], '<')
+		if lt < 0 {
+			p.text(p.src[p.pos:])
+			return
+		}
+		p.text(p.src[p.pos : p.pos+lt])
+		p.pos += lt
+
+		rest := p.src[p.pos:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			p.skipPast("-->", 4)
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			p.skipPast(">", 2)
+		case strings.HasPrefix(rest, "</"):
+			p.pos += 2
+			name := strings.ToLower(p.name())
+			p.skipPast(">", 0)
+			p.end(name)
+		case len(rest) > 1 && isIdentStart(rest[1]):
+			p.pos++
+			p.startTag()
+		default:
+			p.text("<")
+			p.pos++
+		}
+	}
+}
+
+func (p *htmlParser) skipPast(marker string, offset int) {
+	i := strings.Index(p.src[p.pos+offset:], marker)
+	if i < 0 {
+		p.pos = len(p.src)
+		return
+	}
+	p.pos += offset + i + len(marker)
+}
+
+func (p *htmlParser) text(s string) {
+	if s != "" && p.cur.kind == elementNode {
+		p.cur.appendText(html.UnescapeString(s))
+	}
+}
+
+// name reads a tag or attribute name.
+func (p *htmlParser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n/>=", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *htmlParser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *htmlParser) startTag() {
+	el := &node{kind: elementNode, name: strings.ToLower(p.name())}
+	selfClosing := false
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			break
+		}
+		if p.src[p.pos] == '>' {
+			p.pos++
+			break
+		}
+		if p.src[p.pos] == '/' {
+			p.pos++
+			selfClosing = p.pos < len(p.src) && p.src[p.pos] == '>'
+			continue
+		}
+		attr := &node{kind: attrNode, name: strings.ToLower(p.name()), parent: el}
+		if attr.name == "" {
+			p.pos++ // a stray '=' or quote
+			continue
+		}
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '=' {
+			p.pos++
+			p.skipSpace()
+			attr.value = html.UnescapeString(p.attrValue())
+		}
+		el.attrs = append(el.attrs, attr)
+	}
+
+	for p.cur.kind == elementNode && containsString(htmlImpliedEnd[el.name], p.cur.name) {
+		p.cur = p.cur.parent
+	}
+	el.parent = p.cur
+	p.cur.children = append(p.cur.children, el)
+	if selfClosing || htmlVoid[el.name] {
+		return
+	}
+	if htmlRawText[el.name] {
+		p.rawText(el)
+		return
+	}
+	p.cur = el
+}
+
+func (p *htmlParser) attrValue() string {
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		quote := p.src[p.pos]
+		p.pos++
+		end := strings.IndexByte(p.src[p.pos:], quote)
+		if end < 0 {
+			end = len(p.src) - p.pos
+		}
+		value := p.src[p.pos : p.pos+end]
+		p.pos = min(p.pos+end+1, len(p.src))
+		return value
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n>", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// rawText reads the content of script, style, textarea and title up to
+// their end tag.
+func (p *htmlParser) rawText(el *node) {
+	end := strings.Index(strings.ToLower(p.src[p.pos:]), "</"+el.name)
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	content := p.src[p.pos : p.pos+end]
+	if el.name == "textarea" || el.name == "title" {
+		content = html.UnescapeString(content)
+	}
+	el.appendText(content)
+	p.pos += end
+	p.skipPast(">", 0)
+}
+
+// end closes the nearest open element with the given name. End tags that
+// match nothing open are ignored.
+func (p *htmlParser) end(name string) {
+	for n := p.cur; n.kind == elementNode; n = n.parent {
+		if n.name == name {
+			p.cur = n.parent
+			return
+		}
+	}
+}
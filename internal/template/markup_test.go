@@ -0,0 +1,59 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseXML_Errors(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`<a><b></a>`, "invalid XML"},
+		{`{"id": 1}`, "no root element"},
+		{``, "no root element"},
+		{`<?xml version="1.0" encoding="Shift_JIS"?><a/>`, "unsupported charset"},
+	}
+	for _, tt := range tests {
+		_, err := ParseXML([]byte(tt.body))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.body, tt.want, err)
+		}
+	}
+}
+
+func TestParseXML_Charset(t *testing.T) {
+	body := []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><city>M\xfcnchen &amp; more</city>")
+	doc, err := ParseXML(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := doc.root.stringValue(); got != "München & more" {
+		t.Errorf("unexpected text %q", got)
+	}
+}
+
+func TestParseHTML_Tolerant(t *testing.T) {
+	doc := ParseHTML([]byte(`<div>one</span><ul><li>a<li>b</ul>x < y<br><IMG SRC=logo.png alt=""/>done`))
+
+	var names []string
+	for _, el := range doc.elements() {
+		names = append(names, el.name)
+	}
+	if got := strings.Join(names, " "); got != "div ul li li br img" {
+		t.Errorf("unexpected elements %q", got)
+	}
+
+	div := doc.root.children[0]
+	if got := div.stringValue(); got != "oneabx < ydone" {
+		t.Errorf("unexpected text %q", got)
+	}
+	img := doc.elements()[5]
+	if src, ok := doc.attr(img, "src"); !ok || src != "logo.png" {
+		t.Errorf("expected src attribute, got %q", src)
+	}
+	if alt, ok := doc.attr(img, "alt"); !ok || alt != "" {
+		t.Errorf("expected empty alt attribute, got %q", alt)
+	}
+}
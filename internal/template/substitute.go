@@ -5,9 +5,10 @@ package template
 import (
 	"errors"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"maestro/internal/core"
 )
@@ -15,10 +16,16 @@ import (
 // varPattern matches ${var} and ${env:VAR} placeholders.
 var varPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
 
-// Substitute replaces placeholders in text:
+// Substitute replaces placeholders in text. It caches the parsed text; use
+// Compile to parse once up front and report syntax errors:
 //   - ${var} - workflow variables
 //   - ${env:VAR} - environment variables
 //   - ${func(args)} - built-in functions (uuid, timestamp, random, etc.)
+//   - ${expr} - expressions such as ${price * qty} or ${items[0].id} (see Evaluate)
+//   - ${x | filter} - any of the above through filters: ${token | base64},
+//     ${name | urlencode}, ${obj | json}, ${s | upper}, ${s | trim}, ${n | default: 0}
+//
+// Maps and arrays are inserted as JSON.
 //
 // Returns all errors joined if multiple substitutions fail.
 // If text contains no placeholders, it is returned unchanged (fast path).
@@ -27,42 +34,31 @@ func Substitute(text string, vars core.Variables) (string, error) {
 	if !strings.Contains(text, "${") {
 		return text, nil
 	}
+	return cachedTemplate(text).Execute(vars)
+}
 
-	var errs []error
-	result := varPattern.ReplaceAllStringFunc(text, func(match string) string {
-		expr := match[2 : len(match)-1] // Extract content between ${ and }
-
-		// Handle environment variables
-		if strings.HasPrefix(expr, "env:") {
-			envName := expr[4:]
-			if val, ok := os.LookupEnv(envName); ok {
-				return val
-			}
-			errs = append(errs, fmt.Errorf("env var %q not set", envName))
-			return match
-		}
+// maxCachedTemplates bounds the templates Substitute keeps, in case it is
+// called with ever-changing text.
+const maxCachedTemplates = 1024
 
-		// Handle built-in functions (contains parentheses)
-		if result, isFunc, err := evalFunction(expr); isFunc {
-			if err != nil {
-				errs = append(errs, err)
-				return match
-			}
-			return result
-		}
+var (
+	templateCache sync.Map // text -> *Template
+	cachedCount   atomic.Int32
+)
 
-		// Handle workflow variables
-		if val, ok := vars.Get(expr); ok {
-			return fmt.Sprintf("%v", val)
+// cachedTemplate compiles text once for Substitute. Syntax errors surface
+// when the affected placeholders render.
+func cachedTemplate(text string) *Template {
+	if t, ok := templateCache.Load(text); ok {
+		return t.(*Template)
+	}
+	t, _ := compile(text)
+	if cachedCount.Load() < maxCachedTemplates {
+		if _, loaded := templateCache.LoadOrStore(text, t); !loaded {
+			cachedCount.Add(1)
 		}
-		errs = append(errs, fmt.Errorf("variable %q not found", expr))
-		return match
-	})
-
-	if len(errs) > 0 {
-		return "", errors.Join(errs...)
 	}
-	return result, nil
+	return t
 }
 
 // SubstituteMap applies substitution to all values in a map.
@@ -222,6 +222,79 @@ func BenchmarkSubstitute(b *testing.B) {
 	}
 }
 
+func TestSubstitute_Filters(t *testing.T) {
+	os.Setenv("TEST_FILTER_REGION", "eu-west")
+	defer os.Unsetenv("TEST_FILTER_REGION")
+
+	vars := core.NewVariables()
+	vars.Set("token", "user:pass")
+	vars.Set("name", "Jane Doe & co")
+	vars.Set("padded", "  x  ")
+	vars.Set("empty", "")
+	vars.Set("order", map[string]any{"id": float64(7), "tags": []any{"a", "<b>"}})
+	vars.Set("quote", `say "hi"`)
+	vars.Set("count", float64(3))
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"${token | base64}", "dXNlcjpwYXNz"},
+		{"${token|base64}", "dXNlcjpwYXNz"},
+		{"?q=${name | urlencode}", "?q=Jane+Doe+%26+co"},
+		{"${order | json}", `{"id":7,"tags":["a","<b>"]}`},
+		{"${order}", `{"id":7,"tags":["a","<b>"]}`},
+		{"${order.tags}", `["a","<b>"]`},
+		{`{"msg": ${quote | json}}`, `{"msg": "say \"hi\""}`},
+		{"${count | json}", "3"},
+		{"${name | upper}", "JANE DOE & CO"},
+		{"${name | lower}", "jane doe & co"},
+		{"[${padded | trim}]", "[x]"},
+		{"${padded | trim | upper | base64}", "WA=="},
+		{"${missing | default: 0}", "0"},
+		{"${empty | default: 'none'}", "none"},
+		{"${missing | default: 'a|b' | upper}", "A|B"},
+		{"${missing | upper | default: x}", "x"},
+		{"${count | default: 0}", "3"},
+		{"${count > 2 || missing | json}", "true"},
+		{"${env:TEST_FILTER_REGION | upper}", "EU-WEST"},
+		{"${env:TEST_FILTER_UNSET | default: local}", "local"},
+		{"${random(5,5) | json}", `"5"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := Substitute(tt.text, vars)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSubstitute_FilterErrors(t *testing.T) {
+	vars := core.NewVariables()
+	vars.Set("name", "x")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"${name | reverse}", `unknown filter "reverse"`},
+		{"${name | upper: 1}", "filter upper takes no argument"},
+		{"${name | default}", "filter default requires a value"},
+		{"${missing | upper}", `variable "missing" not found`},
+	}
+	for _, tt := range tests {
+		_, err := Substitute(tt.text, vars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.text, tt.want, err)
+		}
+	}
+}
+
 func BenchmarkSubstitute_NoVars(b *testing.B) {
 	vars := core.NewVariables()
 	text := "Bearer static-token"
@@ -245,6 +318,63 @@ func BenchmarkSubstitute_MultipleVars(b *testing.B) {
 	}
 }
 
+func BenchmarkTemplate(b *testing.B) {
+	vars := core.NewVariables()
+	vars.Set("token", "abc123")
+	tmpl, _ := Compile("Bearer ${token}")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = tmpl.Execute(vars)
+	}
+}
+
+func BenchmarkTemplate_MultipleVars(b *testing.B) {
+	vars := core.NewVariables()
+	vars.Set("base", "https://api.example.com")
+	vars.Set("user_id", "12345")
+	vars.Set("token", "abcdef123456")
+	tmpl, _ := Compile("${base}/users/${user_id}?token=${token}")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = tmpl.Execute(vars)
+	}
+}
+
+func BenchmarkTemplate_WithFunction(b *testing.B) {
+	vars := core.NewVariables()
+	tmpl, _ := Compile("id=${uuid()}&ts=${timestamp()}")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = tmpl.Execute(vars)
+	}
+}
+
+func BenchmarkTemplate_Body(b *testing.B) {
+	vars := core.NewVariables()
+	vars.Set("user", map[string]any{"id": "u-7", "name": "Jane Doe"})
+	vars.Set("price", 2.5)
+	vars.Set("qty", 4)
+	text := `{"user": "${user.id}", "name": ${user.name | json}, "total": ${price * qty}, "sig": "${sha256(user.id)}"}`
+	tmpl, _ := Compile(text)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = tmpl.Execute(vars)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	text := `{"user": "${user.id}", "name": ${user.name | json}, "total": ${price * qty}, "sig": "${sha256(user.id)}"}`
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = Compile(text)
+	}
+}
+
 func BenchmarkSubstituteMap(b *testing.B) {
 	vars := core.NewVariables()
 	vars.Set("token", "abc123")
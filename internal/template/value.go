@@ -0,0 +1,89 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+
+	"maestro/internal/core"
+)
+
+// ValueTemplate is a structured value, such as a YAML mapping decoded into
+// maps, slices and scalars, whose strings are templates. It is safe for
+// concurrent use.
+type ValueTemplate struct {
+	root any // maps, slices and scalars, with *Template for strings
+}
+
+// CompileValue compiles every string in v. Mapping keys must be strings
+// and are not templated.
+func CompileValue(v any) (*ValueTemplate, error) {
+	var errs []error
+	root := compileValueNode(v, "$", &errs)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &ValueTemplate{root: root}, nil
+}
+
+func compileValueNode(v any, path string, errs *[]error) any {
+	switch t := v.(type) {
+	case string:
+		tmpl, err := Compile(t)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", path, err))
+		}
+		return tmpl
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = compileValueNode(item, path+"."+k, errs)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = compileValueNode(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+		return out
+	case map[any]any:
+		*errs = append(*errs, fmt.Errorf("%s: mapping keys must be strings", path))
+		return nil
+	}
+	return v
+}
+
+// Render evaluates the templates in the value. A string that is a single
+// placeholder keeps its value's type, so "${qty}" renders a number and
+// "${user.tags}" an array; other strings render as text.
+func (t *ValueTemplate) Render(vars core.Variables) (any, error) {
+	var errs []error
+	v := renderValueNode(t.root, vars, &errs)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return v, nil
+}
+
+func renderValueNode(v any, vars core.Variables, errs *[]error) any {
+	switch t := v.(type) {
+	case *Template:
+		val, err := t.Value(vars)
+		if err != nil {
+			*errs = append(*errs, err)
+		}
+		return val
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = renderValueNode(item, vars, errs)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = renderValueNode(item, vars, errs)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,66 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"maestro/internal/core"
+)
+
+func TestValueTemplate_Render(t *testing.T) {
+	vt, err := CompileValue(map[string]any{
+		"name":  "${name}",
+		"greet": "hi ${name}",
+		"qty":   "${qty}",
+		"price": 2.5,
+		"ok":    true,
+		"tags":  "${tags}",
+		"items": []any{"${qty * 2}", map[string]any{"id": "${name | upper}"}, nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := core.NewVariables()
+	vars.Set("name", "ada")
+	vars.Set("qty", 3)
+	vars.Set("tags", []any{"a", "b"})
+
+	got, err := vt.Render(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":  "ada",
+		"greet": "hi ada",
+		"qty":   3,
+		"price": 2.5,
+		"ok":    true,
+		"tags":  []any{"a", "b"},
+		"items": []any{6.0, map[string]any{"id": "ADA"}, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+}
+
+func TestValueTemplate_Errors(t *testing.T) {
+	_, err := CompileValue(map[string]any{
+		"a": []any{"ok", "${x | bogus}"},
+		"b": map[any]any{1: "one"},
+	})
+	for _, want := range []string{`$.a[1]: ${x | bogus}: unknown filter "bogus"`, "$.b: mapping keys must be strings"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+
+	vt, err := CompileValue([]any{"${missing}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vt.Render(core.NewVariables()); err == nil || !strings.Contains(err.Error(), `variable "missing" not found`) {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+}
@@ -0,0 +1,840 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// XPath is a compiled XPath 1.0 expression.
+//
+// Supported syntax: absolute and relative location paths (/a/b, //b, and
+// a/b, which starts at the document), the child, descendant (//), parent
+// (..), self (.) and attribute (@name, @*) steps, the axes child::,
+// descendant::, descendant-or-self::, parent::, ancestor::, self::,
+// attribute::, following-sibling:: and preceding-sibling::, name tests
+// with * and the text() and node() tests, predicates by position ([1],
+// [last()]) or condition ([@type='hidden']), grouping ((//a)[2]), unions
+// (|), comparisons (= != < <= > >=) combined with and/or, and the
+// functions listed in xpathFuncs.
+//
+// Element and attribute names match on their local part, so namespace
+// prefixes in the path are ignored: soap:Body matches <s:Body>. A path
+// yields the text of the first node it selects, trimmed of surrounding
+// whitespace; count() and comparisons yield a number or a bool.
+type XPath struct {
+	src  string
+	expr xpNode
+}
+
+// ParseXPath compiles an XPath expression, rejecting syntax it does not
+// support.
+func ParseXPath(src string) (*XPath, error) {
+	s := strings.TrimSpace(src)
+	if s == "" {
+		return nil, errors.New("empty XPath")
+	}
+	p := &xpParser{jpParser{src: s}}
+	expr, err := p.parseOr()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.src) {
+			err = p.errorf("unexpected %q", p.src[p.pos])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath %q: %w", src, err)
+	}
+	return &XPath{src: src, expr: expr}, nil
+}
+
+func (x *XPath) String() string { return x.src }
+
+// Select evaluates the expression against a document and reports whether
+// anything was selected.
+func (x *XPath) Select(doc *Document) (any, bool) {
+	v := x.expr.eval(xpContext{doc: doc, node: doc.root, pos: 1, size: 1})
+	switch t := v.(type) {
+	case []*node:
+		if len(t) == 0 {
+			return nil, false
+		}
+		return strings.TrimSpace(t[0].stringValue()), true
+	case float64:
+		if math.IsNaN(t) {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Evaluation
+//
+// Expressions evaluate to a node set ([]*node in document order), a
+// string, a float64 or a bool.
+
+type xpContext struct {
+	doc       *Document
+	node      *node
+	pos, size int
+}
+
+type xpNode interface {
+	eval(ctx xpContext) any
+}
+
+type xpAxis int
+
+const (
+	axisChild xpAxis = iota
+	axisDescendant
+	axisDescendantOrSelf
+	axisParent
+	axisAncestor
+	axisSelf
+	axisAttribute
+	axisFollowingSibling
+	axisPrecedingSibling
+)
+
+var xpAxes = map[string]xpAxis{
+	"child":              axisChild,
+	"descendant":         axisDescendant,
+	"descendant-or-self": axisDescendantOrSelf,
+	"parent":             axisParent,
+	"ancestor":           axisAncestor,
+	"self":               axisSelf,
+	"attribute":          axisAttribute,
+	"following-sibling":  axisFollowingSibling,
+	"preceding-sibling":  axisPrecedingSibling,
+}
+
+type xpStep struct {
+	axis  xpAxis
+	test  string // a name, "*", "text()" or "node()"
+	preds []xpNode
+}
+
+type xpPath struct {
+	start    xpNode // grouped expression the path continues from, if any
+	absolute bool
+	steps    []xpStep
+}
+
+// xpFilter applies predicates to a grouped expression: (//a)[2] is the
+// second a in the document.
+type xpFilter struct {
+	expr  xpNode
+	preds []xpNode
+}
+
+type xpLiteral struct{ value any }
+
+type xpUnion struct{ left, right xpNode }
+
+type xpBinary struct {
+	op          string
+	left, right xpNode
+}
+
+type xpCall struct {
+	name string
+	args []xpNode
+}
+
+func (n *xpLiteral) eval(xpContext) any { return n.value }
+
+func (n *xpPath) eval(ctx xpContext) any {
+	var nodes []*node
+	switch {
+	case n.start != nil:
+		nodes, _ = n.start.eval(ctx).([]*node)
+	case n.absolute:
+		nodes = []*node{ctx.doc.root}
+	default:
+		nodes = []*node{ctx.node}
+	}
+	for _, step := range n.steps {
+		nodes = step.apply(ctx.doc, nodes)
+	}
+	return nodes
+}
+
+func (n *xpFilter) eval(ctx xpContext) any {
+	nodes, _ := n.expr.eval(ctx).([]*node)
+	return filterNodes(ctx.doc, nodes, n.preds)
+}
+
+func (n *xpUnion) eval(ctx xpContext) any {
+	left, _ := n.left.eval(ctx).([]*node)
+	right, _ := n.right.eval(ctx).([]*node)
+	return documentOrder(append(append([]*node{}, left...), right...))
+}
+
+func (n *xpBinary) eval(ctx xpContext) any {
+	switch n.op {
+	case "or":
+		return xpBool(n.left.eval(ctx)) || xpBool(n.right.eval(ctx))
+	case "and":
+		return xpBool(n.left.eval(ctx)) && xpBool(n.right.eval(ctx))
+	}
+	return xpCompare(n.op, n.left.eval(ctx), n.right.eval(ctx))
+}
+
+func (n *xpCall) eval(ctx xpContext) any {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(ctx)
+	}
+	return xpathFuncs[n.name].fn(ctx, args)
+}
+
+// apply selects the step from every context node. Predicates see
+// positions in axis order, so ancestor::div[1] is the nearest ancestor.
+func (s *xpStep) apply(doc *Document, contexts []*node) []*node {
+	var out []*node
+	for _, c := range contexts {
+		var candidates []*node
+		for _, n := range axisNodes(s.axis, c) {
+			if s.matches(doc, n) {
+				candidates = append(candidates, n)
+			}
+		}
+		out = append(out, filterNodes(doc, candidates, s.preds)...)
+	}
+	if len(contexts) > 1 || s.axis == axisAncestor || s.axis == axisPrecedingSibling {
+		out = documentOrder(out)
+	}
+	return out
+}
+
+// filterNodes keeps the nodes every predicate accepts. A number selects by
+// position.
+func filterNodes(doc *Document, nodes []*node, preds []xpNode) []*node {
+	for _, pred := range preds {
+		kept := nodes[:0:0]
+		for i, n := range nodes {
+			v := pred.eval(xpContext{doc: doc, node: n, pos: i + 1, size: len(nodes)})
+			if f, ok := v.(float64); ok {
+				if f == float64(i+1) {
+					kept = append(kept, n)
+				}
+			} else if xpBool(v) {
+				kept = append(kept, n)
+			}
+		}
+		nodes = kept
+	}
+	return nodes
+}
+
+func (s *xpStep) matches(doc *Document, n *node) bool {
+	switch s.test {
+	case "node()":
+		return true
+	case "text()":
+		return n.kind == textNode
+	}
+	want := elementNode
+	if s.axis == axisAttribute {
+		want = attrNode
+	}
+	return n.kind == want && (s.test == "*" || doc.nameMatches(n.name, s.test))
+}
+
+// axisNodes lists the nodes on an axis, nearest first.
+func axisNodes(axis xpAxis, n *node) []*node {
+	switch axis {
+	case axisChild:
+		return n.children
+	case axisAttribute:
+		return n.attrs
+	case axisSelf:
+		return []*node{n}
+	case axisParent:
+		if n.parent == nil {
+			return nil
+		}
+		return []*node{n.parent}
+	case axisAncestor:
+		var out []*node
+		for p := n.parent; p != nil; p = p.parent {
+			out = append(out, p)
+		}
+		return out
+	case axisFollowingSibling, axisPrecedingSibling:
+		if n.parent == nil || n.kind == attrNode {
+			return nil
+		}
+		siblings := n.parent.children
+		i := 0
+		for siblings[i] != n {
+			i++
+		}
+		if axis == axisFollowingSibling {
+			return siblings[i+1:]
+		}
+		out := make([]*node, 0, i)
+		for j := i - 1; j >= 0; j-- {
+			out = append(out, siblings[j])
+		}
+		return out
+	}
+
+	var out []*node
+	if axis == axisDescendantOrSelf {
+		out = append(out, n)
+	}
+	var visit func(*node)
+	visit = func(n *node) {
+		for _, c := range n.children {
+			out = append(out, c)
+			visit(c)
+		}
+	}
+	visit(n)
+	return out
+}
+
+// documentOrder sorts nodes and drops duplicates.
+func documentOrder(nodes []*node) []*node {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+	out := nodes[:0]
+	for i, n := range nodes {
+		if i == 0 || n != nodes[i-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Conversions follow XPath 1.0: a node set converts through its first
+// node, and comparing a node set tests each of its nodes.
+
+func xpString(v any) string {
+	switch t := v.(type) {
+	case []*node:
+		if len(t) == 0 {
+			return ""
+		}
+		return t[0].stringValue()
+	case float64:
+		if math.IsNaN(t) {
+			return "NaN"
+		}
+		return formatValue(t)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	return v.(string)
+}
+
+func xpNumber(v any) float64 {
+	switch t := v.(type) {
+	case float64:
+		return t
+	case bool:
+		if t {
+			return 1
+		}
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(xpString(v)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+func xpBool(v any) bool {
+	switch t := v.(type) {
+	case []*node:
+		return len(t) > 0
+	case float64:
+		return t != 0 && !math.IsNaN(t)
+	case bool:
+		return t
+	}
+	return v.(string) != ""
+}
+
+func xpCompare(op string, a, b any) bool {
+	if nodes, ok := a.([]*node); ok {
+		if _, isBool := b.(bool); isBool {
+			return xpCompareValues(op, len(nodes) > 0, b)
+		}
+		for _, n := range nodes {
+			if xpCompare(op, n.stringValue(), b) {
+				return true
+			}
+		}
+		return false
+	}
+	if nodes, ok := b.([]*node); ok {
+		if _, isBool := a.(bool); isBool {
+			return xpCompareValues(op, a, len(nodes) > 0)
+		}
+		for _, n := range nodes {
+			if xpCompare(op, a, n.stringValue()) {
+				return true
+			}
+		}
+		return false
+	}
+	return xpCompareValues(op, a, b)
+}
+
+func xpCompareValues(op string, a, b any) bool {
+	if op == "=" || op == "!=" {
+		_, aBool := a.(bool)
+		_, bBool := b.(bool)
+		_, aNum := a.(float64)
+		_, bNum := b.(float64)
+		var eq bool
+		switch {
+		case aBool || bBool:
+			eq = xpBool(a) == xpBool(b)
+		case aNum || bNum:
+			eq = xpNumber(a) == xpNumber(b)
+		default:
+			eq = xpString(a) == xpString(b)
+		}
+		return eq == (op == "=")
+	}
+	x, y := xpNumber(a), xpNumber(b)
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	}
+	return x >= y
+}
+
+// Functions
+
+type xpFunc struct {
+	min, max int // argument counts; max -1 for any
+	fn       func(ctx xpContext, args []any) any
+}
+
+// xpathFuncs are the callable functions. Those taking an optional
+// argument default to the context node.
+var xpathFuncs = map[string]xpFunc{
+	"last":     {0, 0, func(ctx xpContext, _ []any) any { return float64(ctx.size) }},
+	"position": {0, 0, func(ctx xpContext, _ []any) any { return float64(ctx.pos) }},
+	"count": {1, 1, func(_ xpContext, args []any) any {
+		nodes, _ := args[0].([]*node)
+		return float64(len(nodes))
+	}},
+	"string":  {0, 1, func(ctx xpContext, args []any) any { return xpString(xpArg(ctx, args)) }},
+	"number":  {0, 1, func(ctx xpContext, args []any) any { return xpNumber(xpArg(ctx, args)) }},
+	"boolean": {1, 1, func(_ xpContext, args []any) any { return xpBool(args[0]) }},
+	"not":     {1, 1, func(_ xpContext, args []any) any { return !xpBool(args[0]) }},
+	"true":    {0, 0, func(xpContext, []any) any { return true }},
+	"false":   {0, 0, func(xpContext, []any) any { return false }},
+	"normalize-space": {0, 1, func(ctx xpContext, args []any) any {
+		return strings.Join(strings.Fields(xpString(xpArg(ctx, args))), " ")
+	}},
+	"string-length": {0, 1, func(ctx xpContext, args []any) any {
+		return float64(utf8.RuneCountInString(xpString(xpArg(ctx, args))))
+	}},
+	"contains": {2, 2, func(_ xpContext, args []any) any {
+		return strings.Contains(xpString(args[0]), xpString(args[1]))
+	}},
+	"starts-with": {2, 2, func(_ xpContext, args []any) any {
+		return strings.HasPrefix(xpString(args[0]), xpString(args[1]))
+	}},
+	"ends-with": {2, 2, func(_ xpContext, args []any) any {
+		return strings.HasSuffix(xpString(args[0]), xpString(args[1]))
+	}},
+	"substring-before": {2, 2, func(_ xpContext, args []any) any {
+		before, _, _ := strings.Cut(xpString(args[0]), xpString(args[1]))
+		return before
+	}},
+	"substring-after": {2, 2, func(_ xpContext, args []any) any {
+		_, after, _ := strings.Cut(xpString(args[0]), xpString(args[1]))
+		return after
+	}},
+	"concat": {2, -1, func(_ xpContext, args []any) any {
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(xpString(arg))
+		}
+		return b.String()
+	}},
+	"name":       {0, 1, xpName},
+	"local-name": {0, 1, xpName},
+}
+
+func xpArg(ctx xpContext, args []any) any {
+	if len(args) == 0 {
+		return []*node{ctx.node}
+	}
+	return args[0]
+}
+
+func xpName(ctx xpContext, args []any) any {
+	nodes, _ := xpArg(ctx, args).([]*node)
+	if len(nodes) == 0 {
+		return ""
+	}
+	return nodes[0].name
+}
+
+// Parsing
+
+type xpParser struct {
+	jpParser
+}
+
+// keyword reports whether the input continues with word as a whole name.
+func (p *xpParser) keyword(word string) bool {
+	if !p.hasPrefix(word) {
+		return false
+	}
+	end := p.pos + len(word)
+	return end >= len(p.src) || !isNameChar(p.src[end])
+}
+
+func (p *xpParser) parseOr() (xpNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	return p.parseLogic("or", p.parseAnd)
+}
+
+func (p *xpParser) parseAnd() (xpNode, error) {
+	return p.parseLogic("and", p.parseComparison)
+}
+
+func (p *xpParser) parseLogic(op string, operand func() (xpNode, error)) (xpNode, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.keyword(op) {
+			return left, nil
+		}
+		p.pos += len(op)
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpBinary{op: op, left: left, right: right}
+	}
+}
+
+var xpathOps = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+func (p *xpParser) parseComparison() (xpNode, error) {
+	left, err := p.parseUnion()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		op := ""
+		for _, candidate := range xpathOps {
+			if p.hasPrefix(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		if op == "=" && p.hasPrefix("==") {
+			return nil, p.errorf("use = to compare")
+		}
+		p.pos += len(op)
+		right, err := p.parseUnion()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *xpParser) parseUnion() (xpNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.peek() != '|' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpUnion{left: left, right: right}
+	}
+}
+
+func (p *xpParser) parsePrimary() (xpNode, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, p.errorf("unexpected end of expression")
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &xpLiteral{value: s}, nil
+	case isDigit(c) || (c == '-' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1])) ||
+		(c == '.' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1])):
+		return p.parseNumber()
+	case c == '(':
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		var group xpStep
+		if err := p.parsePredicates(&group); err != nil {
+			return nil, err
+		}
+		if len(group.preds) > 0 {
+			inner = &xpFilter{expr: inner, preds: group.preds}
+		}
+		path := &xpPath{start: inner}
+		if err := p.parseSteps(path, false); err != nil {
+			return nil, err
+		}
+		if len(path.steps) == 0 {
+			return inner, nil
+		}
+		return path, nil
+	case c == '/':
+		path := &xpPath{absolute: true}
+		if err := p.parseSteps(path, true); err != nil {
+			return nil, err
+		}
+		return path, nil
+	case isIdentStart(c):
+		start := p.pos
+		name := p.name()
+		p.skipSpace()
+		if p.peek() == '(' && name != "text" && name != "node" {
+			return p.parseCall(name)
+		}
+		p.pos = start
+	}
+	path := &xpPath{}
+	if err := p.parseStep(path); err != nil {
+		return nil, err
+	}
+	if err := p.parseSteps(path, false); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// parseSteps parses steps separated by / and //. An absolute path may be
+// the document alone (/).
+func (p *xpParser) parseSteps(path *xpPath, absolute bool) error {
+	for {
+		switch {
+		case p.hasPrefix("//"):
+			p.pos += 2
+			path.steps = append(path.steps, xpStep{axis: axisDescendantOrSelf, test: "node()"})
+		case p.peek() == '/':
+			p.pos++
+			if absolute && len(path.steps) == 0 && !p.startsStep() {
+				return nil
+			}
+		default:
+			return nil
+		}
+		p.skipSpace()
+		if err := p.parseStep(path); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *xpParser) startsStep() bool {
+	p.skipSpace()
+	c := p.peek()
+	return c == '.' || c == '@' || c == '*' || isIdentStart(c)
+}
+
+func (p *xpParser) parseStep(path *xpPath) error {
+	step := xpStep{axis: axisChild}
+	switch {
+	case p.hasPrefix(".."):
+		p.pos += 2
+		path.steps = append(path.steps, xpStep{axis: axisParent, test: "node()"})
+		return nil
+	case p.peek() == '.':
+		p.pos++
+		path.steps = append(path.steps, xpStep{axis: axisSelf, test: "node()"})
+		return nil
+	case p.peek() == '@':
+		p.pos++
+		step.axis = axisAttribute
+	}
+
+	test, err := p.parseNodeTest()
+	if err != nil {
+		return err
+	}
+	if p.hasPrefix("::") {
+		if step.axis == axisAttribute {
+			return p.errorf("unexpected ::")
+		}
+		axis, ok := xpAxes[test]
+		if !ok {
+			return p.errorf("unsupported axis %q", test)
+		}
+		p.pos += 2
+		step.axis = axis
+		if test, err = p.parseNodeTest(); err != nil {
+			return err
+		}
+	}
+	step.test = test
+	if err := p.parsePredicates(&step); err != nil {
+		return err
+	}
+	path.steps = append(path.steps, step)
+	return nil
+}
+
+// parseNodeTest reads a name, *, text() or node(). A namespace prefix is
+// dropped; an axis name is returned as is for the caller to check.
+func (p *xpParser) parseNodeTest() (string, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return "*", nil
+	}
+	if !isIdentStart(p.peek()) {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("missing step")
+		}
+		return "", p.errorf("unexpected %q", p.src[p.pos])
+	}
+	name := p.name()
+	if p.hasPrefix("::") {
+		return name, nil
+	}
+	if p.peek() == ':' {
+		p.pos++
+		if p.peek() == '*' {
+			p.pos++
+			return "*", nil
+		}
+		if !isIdentStart(p.peek()) {
+			return "", p.errorf("missing name after prefix %q", name)
+		}
+		name = p.name()
+	}
+	if name == "text" || name == "node" {
+		p.skipSpace()
+		if p.hasPrefix("()") {
+			p.pos += 2
+			return name + "()", nil
+		}
+	}
+	return name, nil
+}
+
+func (p *xpParser) parsePredicates(step *xpStep) error {
+	for {
+		p.skipSpace()
+		if p.peek() != '[' {
+			return nil
+		}
+		p.pos++
+		pred, err := p.parseOr()
+		if err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.peek() != ']' {
+			return p.errorf("missing ]")
+		}
+		p.pos++
+		step.preds = append(step.preds, pred)
+	}
+}
+
+func (p *xpParser) parseCall(name string) (xpNode, error) {
+	fn, ok := xpathFuncs[name]
+	if !ok {
+		return nil, p.errorf("unsupported function %s()", name)
+	}
+	p.pos++ // (
+	call := &xpCall{name: name}
+	p.skipSpace()
+	if p.peek() == ')' {
+		p.pos++
+	} else {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			p.skipSpace()
+			if p.peek() == ',' {
+				p.pos++
+				continue
+			}
+			if p.peek() != ')' {
+				return nil, p.errorf("missing )")
+			}
+			p.pos++
+			break
+		}
+	}
+	if len(call.args) < fn.min || (fn.max >= 0 && len(call.args) > fn.max) {
+		return nil, p.errorf("wrong number of arguments for %s()", name)
+	}
+	return call, nil
+}
+
+func (p *xpParser) parseNumber() (xpNode, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return nil, p.errorf("invalid number %q", p.src[start:p.pos])
+	}
+	return &xpLiteral{value: f}, nil
+}
+
+// name reads an XML name without a namespace prefix.
+func (p *xpParser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isNameChar(c byte) bool { return isIdentChar(c) || c == '-' || c == '.' }
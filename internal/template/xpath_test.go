@@ -0,0 +1,166 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+const soapXML = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="urn:orders">
+  <soap:Header>
+    <m:Session id="s-1" expires="300">abc123</m:Session>
+  </soap:Header>
+  <soap:Body>
+    <m:GetOrdersResponse>
+      <m:Order id="o-1" status="open"><m:Total currency="EUR">19.90</m:Total><m:Item>pen</m:Item></m:Order>
+      <m:Order id="o-2" status="closed"><m:Total currency="EUR">5.00</m:Total><m:Item>ink</m:Item><m:Item>paper</m:Item></m:Order>
+      <m:Order id="o-3" status="open"><m:Total currency="USD">42.50</m:Total></m:Order>
+    </m:GetOrdersResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestXPath_Select(t *testing.T) {
+	doc, err := ParseXML([]byte(soapXML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		// Paths and namespaces
+		{"/Envelope/Header/Session", "abc123"},
+		{"/soap:Envelope/soap:Header/m:Session/@id", "s-1"},
+		{"//Session", "abc123"},
+		{"Envelope/Body//Order/@id", "o-1"},
+		{"//Order[2]/@id", "o-2"},
+		{"//Order[last()]/@id", "o-3"},
+		{"(//Item)[2]", "ink"},
+		{"//Total/text()", "19.90"},
+		{"//Order/*[1]/@currency", "EUR"},
+		{"//Item/../@id", "o-1"},
+		{"//Item[. = 'paper']/parent::Order/@status", "closed"},
+		{"//Total[@currency='USD']/ancestor::Order/@id", "o-3"},
+		{"//Total[.='5.00']/following-sibling::Item", "ink"},
+		{"//Item[.='paper']/preceding-sibling::*[1]", "ink"},
+		{"//Session/@* ", "s-1"},
+
+		// Predicates
+		{"//Order[@status='open'][2]/@id", "o-3"},
+		{"//Order[@status!='open']/@id", "o-2"},
+		{"//Order[Total > 10 and Total < 20]/@id", "o-1"},
+		{"//Order[Total < 10 or Total/@currency = 'USD']/@id", "o-2"},
+		{"//Order[count(Item) = 2]/@id", "o-2"},
+		{"//Order[not(Item)]/@id", "o-3"},
+		{"//Order[contains(@id, '3')]/Total", "42.50"},
+		{"//Order[starts-with(Item, 'pe')]/@id", "o-1"},
+		{"//Order[position() = 2]/@id", "o-2"},
+
+		// Functions and comparisons at the top level
+		{"count(//Order)", float64(3)},
+		{"count(//Order[@status='open'])", float64(2)},
+		{"string(//Session/@expires)", "300"},
+		{"number(//Session/@expires)", float64(300)},
+		{"//Session/@expires > 100", true},
+		{"concat(//Order[1]/@id, '-', //Order[1]/@status)", "o-1-open"},
+		{"substring-after(//Order[2]/@id, '-')", "2"},
+		{"name(//Body/*)", "GetOrdersResponse"},
+		{"string-length(//Session)", float64(6)},
+		{"//Item | //Session", "abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			x, err := ParseXPath(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, ok := x.Select(doc)
+			if !ok || got != tt.want {
+				t.Errorf("expected %#v, got %#v (ok=%v)", tt.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestXPath_NotFound(t *testing.T) {
+	doc, _ := ParseXML([]byte(soapXML))
+	for _, path := range []string{
+		"//Missing",
+		"/Body",
+		"//Order[4]",
+		"//Order[@status='void']/@id",
+		"//Session/@missing",
+		"number(//Item)",
+	} {
+		x, err := ParseXPath(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+		if got, ok := x.Select(doc); ok {
+			t.Errorf("%s: expected no match, got %#v", path, got)
+		}
+	}
+}
+
+func TestXPath_HTML(t *testing.T) {
+	doc := ParseHTML([]byte(`<HTML><body><form id="login">
+		<INPUT type="hidden" name="csrf" value="tok-42">
+		<table><tr><td>Total</td><td>12</td></table>
+	</form></body></html>`))
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"//input[@name='csrf']/@value", "tok-42"},
+		{"//INPUT/@VALUE", "tok-42"},
+		{"//td[.='Total']/following-sibling::td", "12"},
+		{"//form/@id", "login"},
+	}
+	for _, tt := range tests {
+		x, err := ParseXPath(tt.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.path, err)
+		}
+		if got, ok := x.Select(doc); !ok || got != tt.want {
+			t.Errorf("%s: expected %#v, got %#v", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestParseXPath_Errors(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "empty XPath"},
+		{"//", "missing step"},
+		{"//a[", "unexpected end"},
+		{"//a[@b='c'", "missing ]"},
+		{"//a[@b=='c']", "use = to compare"},
+		{"//a[@b='c]", "unterminated string"},
+		{"sum(//a)", "unsupported function sum()"},
+		{"count()", "wrong number of arguments"},
+		{"following::a", "unsupported axis"},
+		{"//a]", "unexpected ']'"},
+		{"(//a", "missing )"},
+	}
+	for _, tt := range tests {
+		_, err := ParseXPath(tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.path, tt.want, err)
+		}
+	}
+}
+
+func BenchmarkXPath_Select(b *testing.B) {
+	doc, _ := ParseXML([]byte(soapXML))
+	x, _ := ParseXPath("//Order[@status='open' and Total > 20]/@id")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Select(doc)
+	}
+}